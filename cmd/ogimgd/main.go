@@ -4,9 +4,11 @@ import (
 	"log"
 	"os"
 	"strconv"
+	"strings"
 
 	"github.com/davidbyttow/govips/v2/vips"
 	"github.com/nDmitry/ogimgd/internal/preview"
+	"github.com/nDmitry/ogimgd/internal/remote"
 	"github.com/nDmitry/ogimgd/internal/server"
 )
 
@@ -23,10 +25,51 @@ func main() {
 
 	vips.LoggingSettings(nil, vips.LogLevelError)
 
-	vips.Startup(nil)
-	defer vips.Shutdown()
+	var config preview.Config
 
-	p := preview.New()
+	for _, env := range []struct {
+		name  string
+		value *int
+	}{{"VIPS_CONCURRENCY", &config.VipsConcurrency}, {"VIPS_CACHE_MAX", &config.VipsCacheMax}} {
+		if os.Getenv(env.name) == "" {
+			continue
+		}
+
+		var err error
+
+		if *env.value, err = strconv.Atoi(os.Getenv(env.name)); err != nil {
+			log.Fatalf("could not parse %s: %s\n", env.name, os.Getenv(env.name))
+		}
+	}
+
+	preview.Startup(config)
+	defer preview.Shutdown()
+
+	// the images can't be fetched from the internal network unless their hosts are allowed explicitly
+	remoteOptions := []remote.Option{remote.WithPrivateNetworksDenied()}
+
+	if hosts := os.Getenv("ALLOWED_HOSTS"); hosts != "" {
+		remoteOptions = append(remoteOptions, remote.WithAllowedHosts(strings.Split(hosts, ",")...))
+	}
+
+	if redirects := os.Getenv("MAX_REDIRECTS"); redirects != "" {
+		n, err := strconv.Atoi(redirects)
+
+		if err != nil {
+			log.Fatalf("could not parse MAX_REDIRECTS: %s\n", redirects)
+		}
+
+		remoteOptions = append(remoteOptions, remote.WithMaxRedirects(n))
+	}
+
+	p := preview.New(preview.WithLogger(log.Default()), preview.WithGetter(remote.New(remoteOptions...)))
+
+	var signer *server.Signer
+
+	// the previews are served to anyone without a secret
+	if secret := os.Getenv("SIGNING_SECRET"); secret != "" {
+		signer = server.NewSigner([]byte(secret))
+	}
 
-	server.Run(port, p)
+	server.Run(port, p, signer)
 }
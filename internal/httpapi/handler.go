@@ -0,0 +1,17 @@
+// Package httpapi exposes the preview rendering over HTTP for the services embedding the previews
+// into their own HTTP servers instead of running the ogimgd server.
+package httpapi
+
+import (
+	"net/http"
+
+	"github.com/nDmitry/ogimgd/internal/preview"
+	"github.com/nDmitry/ogimgd/internal/server"
+)
+
+// Handler returns an http.Handler mapping the query parameters (title, author, bg, ava, logo, op, w, h and q)
+// to the Options, drawing the preview and writing it with the Content-Type, Cache-Control and ETag headers.
+// Unknown or invalid parameters are answered with 400 and a JSON error message (see the README).
+func Handler(p *preview.Preview) http.Handler {
+	return server.PreviewHandler(p)
+}
@@ -0,0 +1,76 @@
+package httpapi
+
+import (
+	"image/jpeg"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/davidbyttow/govips/v2/vips"
+	"github.com/nDmitry/ogimgd/internal/preview"
+)
+
+func TestMain(m *testing.M) {
+	vips.LoggingSettings(nil, vips.LogLevelError)
+	preview.Startup(preview.Config{})
+
+	code := m.Run()
+
+	preview.Shutdown()
+	os.Exit(code)
+}
+
+func TestHandler(t *testing.T) {
+	ts := httptest.NewServer(Handler(preview.New()))
+
+	defer ts.Close()
+
+	res, err := http.Get(ts.URL + "/?title=Test&logo=logo.png&w=600&h=315")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", res.StatusCode)
+	}
+
+	if ct := res.Header.Get("Content-Type"); ct != "image/jpeg" {
+		t.Errorf("expected image/jpeg, got %s", ct)
+	}
+
+	if res.Header.Get("Cache-Control") == "" {
+		t.Error("expected the Cache-Control header")
+	}
+
+	img, err := jpeg.Decode(res.Body)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if size := img.Bounds().Size(); size.X != 600 || size.Y != 315 {
+		t.Errorf("expected a 600x315 image, got %v", size)
+	}
+}
+
+func TestHandler_BadOpacity(t *testing.T) {
+	ts := httptest.NewServer(Handler(preview.New()))
+
+	defer ts.Close()
+
+	res, err := http.Get(ts.URL + "/?title=Test&logo=logo.png&op=dark")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusBadRequest {
+		t.Errorf("expected status 400, got %d", res.StatusCode)
+	}
+}
@@ -0,0 +1,97 @@
+package preview
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"net/url"
+	"strings"
+)
+
+// WithAssetFS makes the Preview read the local avatar, logo and background paths from the filesystem
+// instead of the images embedded into the remote package, the URLs are still fetched by the getter.
+// The paths are relative to the filesystem root, the ones escaping it (e.g. with ..) are rejected.
+func WithAssetFS(fsys fs.FS) Option {
+	return func(p *Preview) {
+		p.assetFS = fsys
+	}
+}
+
+// assetGetter reads the local paths from the filesystem and passes the URLs to the inner getter.
+type assetGetter struct {
+	fsys  fs.FS
+	inner getter
+}
+
+// getter returns the getter of the resources reading the local paths from the asset filesystem if there is one.
+func (p *Preview) getter() getter {
+	if p.assetFS == nil {
+		return p.remote
+	}
+
+	return &assetGetter{fsys: p.assetFS, inner: p.remote}
+}
+
+// GetAll reads the local paths and fetches the rest concurrently, failing on any error.
+func (g *assetGetter) GetAll(ctx context.Context, urlsOrPaths map[string]string) (map[string][]byte, error) {
+	urls := make(map[string]string, len(urlsOrPaths))
+	bufs := make(map[string][]byte, len(urlsOrPaths))
+
+	for key, urlOrPath := range urlsOrPaths {
+		if !isLocalPath(urlOrPath) {
+			urls[key] = urlOrPath
+			continue
+		}
+
+		buf, err := g.read(urlOrPath)
+
+		if err != nil {
+			return nil, err
+		}
+
+		bufs[key] = buf
+	}
+
+	if len(urls) == 0 {
+		return bufs, nil
+	}
+
+	got, err := g.inner.GetAll(ctx, urls)
+
+	if err != nil {
+		return nil, err
+	}
+
+	for key, buf := range got {
+		bufs[key] = buf
+	}
+
+	return bufs, nil
+}
+
+// read reads the file of the path rejecting the paths escaping the filesystem root.
+func (g *assetGetter) read(path string) ([]byte, error) {
+	if !fs.ValidPath(path) {
+		return nil, fmt.Errorf("invalid asset path, it must be relative and not contain . or .. elements: %s", path)
+	}
+
+	buf, err := fs.ReadFile(g.fsys, path)
+
+	if err != nil {
+		return nil, fmt.Errorf("could not read the asset: %w", err)
+	}
+
+	return buf, nil
+}
+
+// isLocalPath reports whether the resource is a local path rather than a data URL or an URL.
+func isLocalPath(urlOrPath string) bool {
+	if strings.HasPrefix(urlOrPath, "data:") {
+		return false
+	}
+
+	// an absolute path parses as a request URI too
+	u, err := url.ParseRequestURI(urlOrPath)
+
+	return err != nil || u.Scheme == ""
+}
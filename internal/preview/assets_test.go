@@ -0,0 +1,50 @@
+package preview
+
+import (
+	"context"
+	"encoding/base64"
+	"image/color"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestWithAssetFS(t *testing.T) {
+	dataURL := pngDataURL(t, 1200, 630, color.RGBA{R: 255, A: 255})
+	buf, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(dataURL, "data:image/png;base64,"))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	g := &recordingGetter{}
+	p := New(WithGetter(g), WithAssetFS(fstest.MapFS{"images/bg.png": {Data: buf}}))
+
+	opts := testOptions()
+	opts.Bg = "images/bg.png"
+	opts.RequireBg = true
+	opts.LogoURL = "https://example.com/logo.png"
+
+	img, err := p.Draw(context.Background(), opts)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if r, g, b, _ := img.At(4, 4).RGBA(); r>>8 < 200 || g>>8 > 50 || b>>8 > 50 {
+		t.Errorf("expected the red background from the filesystem, got %v, %v, %v", r>>8, g>>8, b>>8)
+	}
+
+	// only the URLs reach the getter
+	if len(g.urls) != 1 || g.urls[0] != opts.LogoURL {
+		t.Errorf("expected only the logo URL fetched, got %v", g.urls)
+	}
+
+	for _, path := range []string{"../images/bg.png", "images/../../bg.png", "/images/bg.png"} {
+		opts.Bg = path
+
+		if _, err := p.Draw(context.Background(), opts); err == nil || !strings.Contains(err.Error(), "invalid asset path") {
+			t.Errorf("expected %s to be rejected, got %v", path, err)
+		}
+	}
+}
@@ -0,0 +1,176 @@
+package preview
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"strconv"
+	"strings"
+)
+
+const (
+	dominantPrefix = "dominant:"
+	dominantAva    = "ava"
+	dominantLogo   = "logo"
+	// the dominant color is picked from a thumbnail of the height
+	dominantSampleH = 64
+	// the channel bits kept when the colors are grouped to pick the dominant one
+	dominantBits = 4
+)
+
+// Background is a typed alternative to the Options.Bg string, see the implementations.
+type Background interface {
+	// bg returns the Options.Bg string of the background
+	bg() string
+}
+
+// HexBackground fills the canvas with a HEX-color.
+type HexBackground string
+
+func (b HexBackground) bg() string {
+	return string(b)
+}
+
+// ImageBackground draws an image by its URL, or filename of the local image, or a data URL.
+type ImageBackground string
+
+func (b ImageBackground) bg() string {
+	return string(b)
+}
+
+// ColorFromImage fills the canvas with the dominant color of an image by its URL, or filename of the local image,
+// or a data URL.
+type ColorFromImage string
+
+func (b ColorFromImage) bg() string {
+	return dominantPrefix + string(b)
+}
+
+// GradientBackground fills the canvas with a linear or a radial gradient.
+type GradientBackground struct {
+	// HEX-color stops distributed evenly, at least two
+	Colors []string
+	// Linear gradient angle in degrees following the CSS convention: 0 goes to the top, 90 to the right
+	Angle float64
+	// Draw a radial gradient reaching the farthest corner instead of a linear one
+	Radial bool
+	// Radial gradient center, the canvas center if nil
+	Center *Focus
+}
+
+func (b GradientBackground) bg() string {
+	if !b.Radial {
+		return linearGradientPrefix + strings.Join(append([]string{strconv.FormatFloat(b.Angle, 'g', -1, 64)}, b.Colors...), ",")
+	}
+
+	parts := b.Colors
+
+	if b.Center != nil {
+		center := []string{strconv.FormatFloat(b.Center.X, 'g', -1, 64), strconv.FormatFloat(b.Center.Y, 'g', -1, 64)}
+		parts = append(center, parts...)
+	}
+
+	return radialGradientPrefix + strings.Join(parts, ",")
+}
+
+// isDominant reports whether the background is the dominant color of an image.
+func isDominant(bg string) bool {
+	return strings.HasPrefix(bg, dominantPrefix)
+}
+
+// dominantImageKey returns the key of the fetched avatar or logo image the dominant color background is picked from.
+func dominantImageKey(bg string) (string, bool) {
+	switch bg {
+	case dominantPrefix + dominantAva:
+		return avaKey + "0", true
+	case dominantPrefix + dominantLogo:
+		return logoKey, true
+	}
+
+	return "", false
+}
+
+// bgImageURL returns the URL or the path of the image the background is drawn from,
+// empty if it's a HEX-color, a gradient, or the dominant color of the avatar or the logo.
+func bgImageURL(bg string) string {
+	if _, exists := dominantImageKey(bg); exists || bg == "" || hexRe.MatchString(bg) || isGradient(bg) {
+		return ""
+	}
+
+	return strings.TrimPrefix(bg, dominantPrefix)
+}
+
+// dominantColor returns the HEX-color of the most common colors of an image ignoring its transparent parts.
+// The colors are grouped by their high bits, the dominant one is the average of the largest group.
+func (p *drawing) dominantColor(buf []byte) (string, error) {
+	if err := p.checkPixels(buf); err != nil {
+		return "", fmt.Errorf("could not load the image: %w", err)
+	}
+
+	buf, err := p.decodable(buf)
+
+	if err != nil {
+		return "", fmt.Errorf("could not convert the image to PNG: %w", err)
+	}
+
+	buf, err = p.scale(buf, dominantSampleH)
+
+	if err != nil {
+		return "", fmt.Errorf("could not resize the image: %w", err)
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(buf))
+
+	if err != nil {
+		return "", fmt.Errorf("could not decode the image: %w", err)
+	}
+
+	type group struct {
+		n       int
+		r, g, b uint32
+	}
+
+	groups := map[uint32]*group{}
+	var largest *group
+
+	bounds := img.Bounds()
+
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			r, g, b, a := img.At(x, y).RGBA()
+
+			if a < 0x8000 {
+				continue
+			}
+
+			// the alpha premultiplied channels are restored to the color
+			r, g, b = r*0xFFFF/a>>8, g*0xFFFF/a>>8, b*0xFFFF/a>>8
+			shift := 8 - dominantBits
+			key := r>>shift<<(2*dominantBits) | g>>shift<<dominantBits | b>>shift
+
+			grp, exists := groups[key]
+
+			if !exists {
+				grp = &group{}
+				groups[key] = grp
+			}
+
+			grp.n++
+			grp.r += r
+			grp.g += g
+			grp.b += b
+
+			if largest == nil || grp.n > largest.n {
+				largest = grp
+			}
+		}
+	}
+
+	if largest == nil {
+		return "", fmt.Errorf("the image is fully transparent")
+	}
+
+	n := uint32(largest.n)
+
+	return fmt.Sprintf("#%02X%02X%02X", largest.r/n, largest.g/n, largest.b/n), nil
+}
@@ -0,0 +1,161 @@
+package preview
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"testing"
+)
+
+// pngDataURL returns a data URL of a w*h PNG filled with the color.
+func pngDataURL(t *testing.T, w, h int, c color.Color) string {
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	draw.Draw(img, img.Bounds(), image.NewUniform(c), image.Point{}, draw.Src)
+
+	buf := new(bytes.Buffer)
+
+	if err := png.Encode(buf, img); err != nil {
+		t.Fatal(err)
+	}
+
+	return "data:image/png;base64," + base64.StdEncoding.EncodeToString(buf.Bytes())
+}
+
+// mostlyRed returns a data URL of a PNG filled with #E61414 but for a blue stripe.
+func mostlyRed(t *testing.T) string {
+	img := image.NewRGBA(image.Rect(0, 0, 64, 64))
+	draw.Draw(img, img.Bounds(), image.NewUniform(color.RGBA{R: 230, G: 20, B: 20, A: 255}), image.Point{}, draw.Src)
+	draw.Draw(img, image.Rect(0, 0, 64, 16), image.NewUniform(color.RGBA{B: 255, A: 255}), image.Point{}, draw.Src)
+
+	buf := new(bytes.Buffer)
+
+	if err := png.Encode(buf, img); err != nil {
+		t.Fatal(err)
+	}
+
+	return "data:image/png;base64," + base64.StdEncoding.EncodeToString(buf.Bytes())
+}
+
+func TestDrawBackgroundSource(t *testing.T) {
+	testCases := []struct {
+		name   string
+		source Background
+		// the expected colors at the points
+		points map[image.Point]color.RGBA
+	}{{
+		name:   "HEX",
+		source: HexBackground("#FF0000"),
+		points: map[image.Point]color.RGBA{{600, 315}: {R: 255}},
+	}, {
+		name:   "image",
+		source: ImageBackground(pngDataURL(t, 1200, 630, color.RGBA{B: 255, A: 255})),
+		// the margin is outside of the overlay
+		points: map[image.Point]color.RGBA{{2, 2}: {B: 255}},
+	}, {
+		name:   "linear gradient",
+		source: GradientBackground{Colors: []string{"#FFFFFF", "#000000"}, Angle: 90},
+		points: map[image.Point]color.RGBA{{0, 315}: {R: 255, G: 255, B: 255}, {1199, 315}: {}},
+	}, {
+		name:   "radial gradient",
+		source: GradientBackground{Colors: []string{"#FFFFFF", "#000000"}, Radial: true, Center: &Focus{X: 0.75, Y: 0.25}},
+		points: map[image.Point]color.RGBA{{900, 157}: {R: 255, G: 255, B: 255}, {0, 629}: {}},
+	}, {
+		name:   "dominant color",
+		source: ColorFromImage(mostlyRed(t)),
+		points: map[image.Point]color.RGBA{{600, 315}: {R: 230, G: 20, B: 20}},
+	}}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := testOptions()
+			opts.BackgroundSource = tt.source
+			opts.RequireBg = true
+			opts.Title = " "
+
+			img, err := New().Draw(context.Background(), opts)
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			for pt, want := range tt.points {
+				r, g, b, _ := img.At(pt.X, pt.Y).RGBA()
+
+				if !near(r>>8, want.R) || !near(g>>8, want.G) || !near(b>>8, want.B) {
+					t.Errorf("expected %d,%d,%d at %v, got %d,%d,%d", want.R, want.G, want.B, pt, r>>8, g>>8, b>>8)
+				}
+			}
+		})
+	}
+}
+
+func TestDrawDominantBg(t *testing.T) {
+	testCases := []struct {
+		name      string
+		bg        string
+		ava, logo string
+		require   bool
+		want      color.RGBA
+		wantErr   bool
+	}{{
+		name: "avatar",
+		bg:   "dominant:ava",
+		ava:  mostlyRed(t),
+		want: color.RGBA{R: 230, G: 20, B: 20},
+	}, {
+		name: "logo",
+		bg:   "dominant:logo",
+		logo: mostlyRed(t),
+		want: color.RGBA{R: 230, G: 20, B: 20},
+	}, {
+		name: "no logo",
+		bg:   "dominant:logo",
+		ava:  mostlyRed(t),
+		want: color.RGBA{R: 255, G: 255, B: 255},
+	}, {
+		name:    "required without an avatar",
+		bg:      "dominant:ava",
+		require: true,
+		wantErr: true,
+	}}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := testOptions()
+			opts.Bg = tt.bg
+			opts.AvaURL = tt.ava
+			opts.LogoURL = tt.logo
+			opts.RequireBg = tt.require
+			opts.Title = " "
+
+			img, err := New().Draw(context.Background(), opts)
+
+			if tt.wantErr {
+				if err == nil {
+					t.Error("expected an error")
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if r, g, b, _ := img.At(600, 315).RGBA(); !near(r>>8, tt.want.R) || !near(g>>8, tt.want.G) || !near(b>>8, tt.want.B) {
+				t.Errorf("expected %d,%d,%d, got %d,%d,%d", tt.want.R, tt.want.G, tt.want.B, r>>8, g>>8, b>>8)
+			}
+		})
+	}
+}
+
+// near reports whether the channel is within a few levels of the expected value.
+func near(got uint32, want uint8) bool {
+	d := int(got) - int(want)
+
+	return d >= -8 && d <= 8
+}
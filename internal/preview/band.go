@@ -0,0 +1,57 @@
+package preview
+
+import (
+	"fmt"
+	"image/color"
+	"math"
+
+	"github.com/fogleman/gg"
+)
+
+// defaultBandPadding is the space in px between the title and the edges of its band.
+const defaultBandPadding = 16.0
+
+// defaultBandColor is a semi-transparent black
+var defaultBandColor = color.RGBA{A: 153}
+
+// drawTitleBand draws a rounded rectangle in the band color behind the wrapped title lines
+// as wide as the longest of them.
+func (p *drawing) drawTitleBand(align gg.Align, style textStyle) error {
+	if err := p.setColor(p.opts.TitleBandColor, defaultBandColor); err != nil {
+		return fmt.Errorf("invalid title band color: %w", err)
+	}
+
+	box := p.layout.Title
+	w := 0.0
+
+	for _, line := range p.layout.TitleLines {
+		w = math.Max(w, p.measureString(line, style))
+	}
+
+	if w == 0 {
+		return nil
+	}
+
+	x := box.X
+
+	switch align {
+	case gg.AlignCenter:
+		x += (box.W - w) / 2
+	case gg.AlignRight:
+		x += box.W - w
+	}
+
+	pad := p.opts.TitleBandPadding
+
+	if pad == 0 {
+		pad = defaultBandPadding
+	}
+
+	// the descent of the last line hangs below the box
+	descent := float64(style.face.Metrics().Descent) / 64
+
+	p.ctx.DrawRoundedRectangle(x-pad, box.Y-pad, w+pad*2, box.H+descent+pad*2, pad/2)
+	p.ctx.Fill()
+
+	return nil
+}
@@ -0,0 +1,57 @@
+package preview
+
+import (
+	"context"
+	"image"
+	"testing"
+)
+
+func TestDrawTitleBand(t *testing.T) {
+	opts := testOptions()
+	opts.Title = "Test"
+	opts.TitleBand = true
+	opts.TitleBandColor = "#FF0000"
+
+	p := New()
+	layout, err := p.Layout(opts)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	img, err := p.Draw(context.Background(), opts)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	box := layout.Title
+	red := func(x, y int) bool {
+		r, g, b, _ := img.At(x, y).RGBA()
+
+		return r>>8 == 0xFF && g>>8 == 0 && b>>8 == 0
+	}
+
+	// the band pads the title on the left and reaches just past the short title on the right
+	if !red(int(box.X)-4, int(box.Y+box.H/2)) {
+		t.Error("expected the band to the left of the title")
+	}
+
+	if red(int(box.X+box.W)-10, int(box.Y+box.H/2)) {
+		t.Error("expected no band past the title")
+	}
+
+	if red(int(box.X)-4, int(box.Y)-int(defaultBandPadding)-4) {
+		t.Error("expected no band above the title")
+	}
+
+	if _, g, _ := maxRGB(img, image.Rect(int(box.X), int(box.Y), int(box.X+box.W), int(box.Y+box.H))); g < 250 {
+		t.Error("expected the white title drawn over the band")
+	}
+
+	opts.TitleBandColor = "red"
+
+	if _, err := p.Draw(context.Background(), opts); err == nil {
+		t.Error("expected an invalid band color error")
+	}
+}
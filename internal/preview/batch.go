@@ -0,0 +1,57 @@
+package preview
+
+import (
+	"context"
+	"image"
+	"runtime"
+	"sync"
+
+	"github.com/nDmitry/ogimgd/internal/remote"
+)
+
+// WithBatchWorkers sets the number of previews DrawBatch draws at a time, the number of CPUs by default.
+func WithBatchWorkers(n int) Option {
+	return func(p *Preview) {
+		p.batchWorkers = n
+	}
+}
+
+// DrawBatch draws a preview for each of the Options. The images referenced by several previews are fetched once.
+// The images and the errors are returned at the indexes of their Options, so a failed preview doesn't fail the rest.
+func (p *Preview) DrawBatch(ctx context.Context, opts []Options) ([]image.Image, []error) {
+	imgs := make([]image.Image, len(opts))
+	errs := make([]error, len(opts))
+
+	batch := *p
+	batch.remote = remote.NewShared(p.remote)
+
+	workers := p.batchWorkers
+
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+
+	wg.Add(workers)
+
+	for i := 0; i < workers; i++ {
+		go func() {
+			defer wg.Done()
+
+			for i := range indexes {
+				imgs[i], errs[i] = batch.Draw(ctx, opts[i])
+			}
+		}()
+	}
+
+	for i := range opts {
+		indexes <- i
+	}
+
+	close(indexes)
+	wg.Wait()
+
+	return imgs, errs
+}
@@ -0,0 +1,46 @@
+package preview
+
+import (
+	"context"
+	"testing"
+)
+
+func TestDrawBatch(t *testing.T) {
+	g := &recordingGetter{}
+	opts := make([]Options, 6)
+
+	for i := range opts {
+		opts[i] = testOptions()
+		opts[i].LogoURL = "https://example.com/logo.png"
+		opts[i].RequireLogo = true
+	}
+
+	opts[2].LogoURL = "https://example.com/missing.png"
+	opts[4].CanvasW = 0
+
+	imgs, errs := New(WithGetter(g), WithBatchWorkers(2)).DrawBatch(context.Background(), opts)
+
+	for i := range opts {
+		failed := i == 2 || i == 4
+
+		if failed && (errs[i] == nil || imgs[i] != nil) {
+			t.Errorf("expected preview %d to fail, got %v", i, errs[i])
+		}
+
+		if !failed && (errs[i] != nil || imgs[i] == nil) {
+			t.Errorf("expected preview %d to be drawn, got %v", i, errs[i])
+		}
+	}
+
+	fetches := 0
+
+	for _, url := range g.urls {
+		if url == opts[0].LogoURL {
+			fetches++
+		}
+	}
+
+	if fetches != 1 {
+		t.Errorf("expected the shared logo to be fetched once, got %d fetches", fetches)
+	}
+}
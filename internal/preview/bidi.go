@@ -0,0 +1,132 @@
+package preview
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Title directions
+const (
+	DirectionAuto = "auto"
+	DirectionLTR  = "ltr"
+	DirectionRTL  = "rtl"
+)
+
+// mirrored are the paired punctuation drawn mirrored in the right-to-left text.
+var mirrored = map[rune]rune{'(': ')', ')': '(', '[': ']', ']': '[', '{': '}', '}': '{', '<': '>', '>': '<', '«': '»', '»': '«'}
+
+// isRTL reports whether the rune is a letter of a right-to-left script.
+func isRTL(r rune) bool {
+	return unicode.In(r, unicode.Hebrew, unicode.Arabic, unicode.Syriac, unicode.Thaana, unicode.Nko)
+}
+
+// isLTR reports whether the rune is a left-to-right letter or a digit.
+func isLTR(r rune) bool {
+	return (unicode.IsLetter(r) || unicode.IsDigit(r)) && !isRTL(r)
+}
+
+// hasRTL reports whether the string has any right-to-left letters.
+func hasRTL(s string) bool {
+	for _, r := range s {
+		if isRTL(r) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// isRTLText reports whether most of the letters of the string are of right-to-left scripts.
+func isRTLText(s string) bool {
+	rtl, ltr := 0, 0
+
+	for _, r := range s {
+		switch {
+		case isRTL(r):
+			rtl++
+		case unicode.IsLetter(r):
+			ltr++
+		}
+	}
+
+	return rtl > ltr
+}
+
+// visualOrder reorders a line of the paragraph of the direction to be drawn left to right.
+// A right-to-left line is reversed except for the runs of left-to-right letters and digits,
+// a left-to-right line has only its runs of right-to-left letters reversed.
+//
+// It's a simplification of the Unicode bidirectional algorithm without the explicit embeddings,
+// and there is no shaping, so Arabic letters are drawn in their isolated forms without ligatures.
+func visualOrder(line string, rtl bool) string {
+	// the emoji sequences are kept as the whole units
+	var units []string
+
+	for _, run := range splitEmoji(line) {
+		if run.emoji {
+			units = append(units, run.text)
+			continue
+		}
+
+		for _, r := range run.text {
+			units = append(units, string(r))
+		}
+	}
+
+	first := func(unit string) rune {
+		for _, r := range unit {
+			return r
+		}
+
+		return 0
+	}
+
+	strong, opposite := isRTL, isLTR
+
+	if rtl {
+		strong, opposite = isLTR, isRTL
+		reverseUnits(units)
+
+		for i, unit := range units {
+			if m, exists := mirrored[first(unit)]; exists {
+				units[i] = string(m)
+			}
+		}
+	}
+
+	// the runs of the strong units of the other direction with the neutrals between them
+	for i := 0; i < len(units); i++ {
+		if !strong(first(units[i])) {
+			continue
+		}
+
+		end := i
+
+		for j := i + 1; j < len(units) && !opposite(first(units[j])); j++ {
+			if strong(first(units[j])) {
+				end = j
+			}
+		}
+
+		run := units[i : end+1]
+		reverseUnits(run)
+
+		if rtl {
+			for k, unit := range run {
+				if m, exists := mirrored[first(unit)]; exists {
+					run[k] = string(m)
+				}
+			}
+		}
+
+		i = end
+	}
+
+	return strings.Join(units, "")
+}
+
+func reverseUnits(units []string) {
+	for i, j := 0, len(units)-1; i < j; i, j = i+1, j-1 {
+		units[i], units[j] = units[j], units[i]
+	}
+}
@@ -0,0 +1,73 @@
+package preview
+
+import (
+	"context"
+	"image"
+	"testing"
+)
+
+func TestVisualOrder(t *testing.T) {
+	tests := []struct {
+		line string
+		rtl  bool
+		want string
+	}{
+		{"Hello, world", false, "Hello, world"},
+		{"שלום עולם", true, "םלוע םולש"},
+		{"שלום Go 1.16 עולם", true, "םלוע Go 1.16 םולש"},
+		{"Hello שלום עולם!", false, "Hello םלוע םולש!"},
+		{"(שלום)", true, "(םולש)"},
+		{"🇮🇱 שלום", true, "םולש 🇮🇱"},
+	}
+
+	for _, tt := range tests {
+		if got := visualOrder(tt.line, tt.rtl); got != tt.want {
+			t.Errorf("visualOrder(%q, %v) = %q, want %q", tt.line, tt.rtl, got, tt.want)
+		}
+	}
+}
+
+func TestIsRTLText(t *testing.T) {
+	if !isRTLText("שלום עולם, Go") {
+		t.Error("expected a mostly Hebrew text to be right-to-left")
+	}
+
+	if isRTLText("Hello, שלום") {
+		t.Error("expected a mostly Latin text to be left-to-right")
+	}
+}
+
+func TestDrawRTLTitle(t *testing.T) {
+	p := New(WithFontDir(redEmojiDir(t)))
+
+	// redColumn returns the first column with the red emoji
+	redColumn := func(direction string) int {
+		opts := testOptions()
+		opts.Title = "🎉 שלום עולם"
+		opts.TitleDirection = direction
+		opts.ColorEmoji = true
+
+		img, err := p.Draw(context.Background(), opts)
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for x := 0; x < opts.CanvasW; x++ {
+			if hasColor(img.(*image.RGBA).SubImage(image.Rect(x, 0, x+1, opts.CanvasH))) {
+				return x
+			}
+		}
+
+		return -1
+	}
+
+	// the first glyph of a right-to-left title lands on the right of the right aligned text
+	if x := redColumn(""); x < 600 {
+		t.Errorf("expected the first glyph on the right side, got x %d", x)
+	}
+
+	if x := redColumn(DirectionLTR); x < 0 || x > 600 {
+		t.Errorf("expected the first glyph on the left side for ltr, got x %d", x)
+	}
+}
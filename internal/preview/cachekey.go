@@ -0,0 +1,108 @@
+package preview
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"reflect"
+	"sort"
+	"strconv"
+)
+
+// CacheKey returns a stable hash of the Options with the defaults filled in, so the Options drawing the same preview
+// get the same key however they were set, e.g. to be used as an ETag. The fields are hashed by their names,
+// so reordering them in the struct doesn't change the key. The fetched images are not covered, see CacheKeyWithImages.
+// A FontSet only keeps the key within the process as it's hashed by its identity.
+func (o Options) CacheKey() string {
+	return o.CacheKeyWithImages(nil)
+}
+
+// CacheKeyWithImages returns CacheKey that also covers the contents of the images by their URLs,
+// so the key changes when a remote image changes.
+func (o Options) CacheKeyWithImages(images map[string][]byte) string {
+	h := sha256.New()
+
+	o = o.withDefaults()
+	// the typed background is already resolved to Bg
+	o.BackgroundSource = nil
+	// the font sets are told apart by their identity as their fonts are read lazily
+	fontSet := o.FontSet
+	o.FontSet = nil
+
+	hashValue(h, reflect.ValueOf(o))
+	fmt.Fprintf(h, "fontset=%p;", fontSet)
+
+	urls := make([]string, 0, len(images))
+
+	for url := range images {
+		urls = append(urls, url)
+	}
+
+	sort.Strings(urls)
+
+	for _, url := range urls {
+		sum := sha256.Sum256(images[url])
+		fmt.Fprintf(h, "image%q=%x;", url, sum)
+	}
+
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// hashValue writes an unambiguous encoding of the value to the hash, the struct fields are sorted by their names.
+func hashValue(h hash.Hash, v reflect.Value) {
+	switch v.Kind() {
+	case reflect.Struct:
+		t := v.Type()
+		fields := make([]int, t.NumField())
+
+		for i := range fields {
+			fields[i] = i
+		}
+
+		sort.Slice(fields, func(i, j int) bool {
+			return t.Field(fields[i]).Name < t.Field(fields[j]).Name
+		})
+
+		h.Write([]byte("{"))
+
+		for _, i := range fields {
+			fmt.Fprintf(h, "%s=", t.Field(i).Name)
+			hashValue(h, v.Field(i))
+			h.Write([]byte(";"))
+		}
+
+		h.Write([]byte("}"))
+	case reflect.Ptr, reflect.Interface:
+		if v.IsNil() {
+			h.Write([]byte("nil"))
+			return
+		}
+
+		hashValue(h, v.Elem())
+	case reflect.Slice:
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			fmt.Fprintf(h, "%x", sha256.Sum256(v.Bytes()))
+			return
+		}
+
+		fmt.Fprintf(h, "[%d:", v.Len())
+
+		for i := 0; i < v.Len(); i++ {
+			hashValue(h, v.Index(i))
+			h.Write([]byte(","))
+		}
+
+		h.Write([]byte("]"))
+	case reflect.String:
+		h.Write([]byte(strconv.Quote(v.String())))
+	case reflect.Bool:
+		h.Write([]byte(strconv.FormatBool(v.Bool())))
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		h.Write([]byte(strconv.FormatInt(v.Int(), 10)))
+	case reflect.Float32, reflect.Float64:
+		h.Write([]byte(strconv.FormatFloat(v.Float(), 'g', -1, 64)))
+	default:
+		fmt.Fprintf(h, "%v", v.Interface())
+	}
+}
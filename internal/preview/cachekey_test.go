@@ -0,0 +1,55 @@
+package preview
+
+import (
+	"testing"
+	"testing/fstest"
+)
+
+func TestCacheKey(t *testing.T) {
+	opts := testOptions()
+	opts.Tags = []string{"Go"}
+	opts.BgFocus = &Focus{X: 0.5, Y: 0.25}
+
+	same := testOptions()
+	same.Tags = []string{"Go"}
+	same.BgFocus = &Focus{X: 0.5, Y: 0.25}
+
+	if opts.CacheKey() != same.CacheKey() {
+		t.Error("expected identical options to have the same key")
+	}
+
+	// the zero values resolve to the defaults
+	same.Margin = DefaultMargin
+	same.BackgroundSource = HexBackground(opts.Bg)
+
+	if opts.CacheKey() != same.CacheKey() {
+		t.Error("expected the defaults and the typed background to keep the key")
+	}
+
+	changed := opts
+	changed.Title = "Changed"
+
+	if opts.CacheKey() == changed.CacheKey() {
+		t.Error("expected a changed title to change the key")
+	}
+
+	changed = opts
+	changed.Tags = []string{"G", "o"}
+
+	if opts.CacheKey() == changed.CacheKey() {
+		t.Error("expected changed tags to change the key")
+	}
+
+	changed = opts
+	changed.FontSet = NewFontSet(fstest.MapFS{})
+
+	if opts.CacheKey() == changed.CacheKey() {
+		t.Error("expected a font set to change the key")
+	}
+
+	images := map[string][]byte{"logo.png": {1, 2, 3}}
+
+	if opts.CacheKeyWithImages(images) == opts.CacheKeyWithImages(map[string][]byte{"logo.png": {1, 2, 4}}) {
+		t.Error("expected a changed image to change the key")
+	}
+}
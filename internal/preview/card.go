@@ -0,0 +1,50 @@
+package preview
+
+import (
+	"fmt"
+	"image"
+	"math"
+)
+
+// defaultCardBorderColor is the card border color if none is set.
+const defaultCardBorderColor = "#000000"
+
+// drawCardBorder draws a frame of CardBorderW inside the canvas edges following the rounded corners of CardRadius.
+func (p *drawing) drawCardBorder() error {
+	if p.opts.CardBorderW <= 0 {
+		return nil
+	}
+
+	borderColor := p.opts.CardBorderColor
+
+	if borderColor == "" {
+		borderColor = defaultCardBorderColor
+	}
+
+	if err := p.setColor(borderColor, nil); err != nil {
+		return fmt.Errorf("invalid card border color: %w", err)
+	}
+
+	// the frame is the ring between the canvas edges and the inset rectangle, filled to keep the corners sharp
+	w := float64(p.opts.CardBorderW)
+	cw, ch := float64(p.opts.CanvasW), float64(p.opts.CanvasH)
+	radius := float64(p.opts.CardRadius)
+
+	p.ctx.SetFillRuleEvenOdd()
+	p.ctx.DrawRoundedRectangle(0, 0, cw, ch, radius)
+	p.ctx.NewSubPath()
+	p.ctx.DrawRoundedRectangle(w, w, cw-2*w, ch-2*w, math.Max(0, radius-w))
+	p.ctx.Fill()
+	p.ctx.SetFillRuleWinding()
+
+	return nil
+}
+
+// clipCard returns the canvas with its corners rounded by CardRadius and left transparent.
+func (p *drawing) clipCard() image.Image {
+	if p.opts.CardRadius <= 0 {
+		return p.ctx.Image()
+	}
+
+	return p.maskShape(p.ctx.Image(), ShapeRounded, float64(p.opts.CardRadius))
+}
@@ -0,0 +1,86 @@
+package preview
+
+import (
+	"context"
+	"testing"
+)
+
+func TestDrawCardRadius(t *testing.T) {
+	opts := testOptions()
+	opts.Bg = "#336699"
+	opts.CardRadius = 32
+
+	img, err := New().Draw(context.Background(), opts)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, corner := range [][2]int{{0, 0}, {1199, 0}, {0, 629}, {1199, 629}} {
+		if _, _, _, a := img.At(corner[0], corner[1]).RGBA(); a != 0 {
+			t.Errorf("expected a transparent corner at %v, got alpha %d", corner, a>>8)
+		}
+	}
+
+	if _, _, _, a := img.At(600, 0).RGBA(); a>>8 != 0xFF {
+		t.Errorf("expected an opaque edge between the corners, got alpha %d", a>>8)
+	}
+
+	opts.CardRadius = 0
+
+	if img, err = New().Draw(context.Background(), opts); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, _, _, a := img.At(0, 0).RGBA(); a>>8 != 0xFF {
+		t.Errorf("expected a square corner without the radius, got alpha %d", a>>8)
+	}
+}
+
+func TestDrawCardBorder(t *testing.T) {
+	opts := testOptions()
+	opts.Bg = "#FFFFFF"
+	opts.Title = " "
+	opts.CardBorderW = 4
+	opts.CardBorderColor = "#FF0000"
+
+	img, err := New().Draw(context.Background(), opts)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	isRed := func(x, y int) bool {
+		r, g, b, _ := img.At(x, y).RGBA()
+
+		return r>>8 > 0xF0 && g>>8 < 0x10 && b>>8 < 0x10
+	}
+
+	// the frame rings the perimeter
+	for x := 0; x < 1200; x += 50 {
+		if !isRed(x, 0) || !isRed(x, 3) || !isRed(x, 629) {
+			t.Fatalf("expected the border along the top and bottom edges at x=%d", x)
+		}
+	}
+
+	for y := 0; y < 630; y += 30 {
+		if !isRed(0, y) || !isRed(1196, y) || !isRed(1199, y) {
+			t.Fatalf("expected the border along the left and right edges at y=%d", y)
+		}
+	}
+
+	if isRed(4, 315) || isRed(600, 315) {
+		t.Error("expected the border to be as wide as set")
+	}
+
+	// the frame follows the rounded corners
+	opts.CardRadius = 32
+
+	if img, err = New().Draw(context.Background(), opts); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, _, _, a := img.At(0, 0).RGBA(); a != 0 || !isRed(600, 0) || !isRed(11, 11) {
+		t.Error("expected the border inside the rounded corners")
+	}
+}
@@ -0,0 +1,59 @@
+package preview
+
+import (
+	"bytes"
+	"image"
+
+	"github.com/davidbyttow/govips/v2/vips"
+)
+
+// imageConfig reads the size of an image from its header. The formats the standard library has no decoder for,
+// e.g. WebP or HEIC, are read by vips which loads the pixels lazily, so only the header is read as well.
+func imageConfig(buf []byte) (image.Config, error) {
+	config, _, err := image.DecodeConfig(bytes.NewReader(buf))
+
+	if err == nil {
+		return config, nil
+	}
+
+	vipsImg, vipsErr := vips.NewImageFromBuffer(buf)
+
+	if vipsErr != nil {
+		// the standard library error tells an unknown format from a corrupted one
+		return image.Config{}, err
+	}
+
+	defer vipsImg.Close()
+
+	return image.Config{Width: vipsImg.Width(), Height: vipsImg.Height()}, nil
+}
+
+// decodable converts an image the standard library can't decode to PNG via vips, so any format vips reads
+// can be resized, filtered and drawn. The images image.Decode can read and SVGs are returned as they are.
+func (p *Preview) decodable(buf []byte) ([]byte, error) {
+	if isSVG(buf) {
+		return buf, nil
+	}
+
+	if _, _, err := image.DecodeConfig(bytes.NewReader(buf)); err == nil {
+		return buf, nil
+	}
+
+	vipsImg, err := loadImage(buf)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer vipsImg.Close()
+
+	p.logger.Printf("Converting an image to PNG")
+
+	buf, _, err = vipsImg.ExportPng(vips.NewPngExportParams())
+
+	if err != nil {
+		return nil, err
+	}
+
+	return buf, nil
+}
@@ -0,0 +1,81 @@
+package preview
+
+import (
+	"context"
+	"encoding/base64"
+	"image"
+	"image/color"
+	"testing"
+
+	"github.com/davidbyttow/govips/v2/vips"
+)
+
+// webpGray is a lossy 1x1 WebP of the middle gray.
+const webpGray = "UklGRiIAAABXRUJQVlA4IBYAAAAwAQCdASoBAAEADsD+JaQAA3AAAAAA"
+
+func TestDrawWebPAvatar(t *testing.T) {
+	opts := testOptions()
+	opts.AvaURL = "data:image/webp;base64," + webpGray
+	opts.AvaD = 100
+
+	layout, err := New().Layout(opts)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	img, err := New().Draw(context.Background(), opts)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// a failed avatar leaves the black background as there is no author for the initials
+	ava := layout.Avatars[0]
+	x, y := int(ava.X+ava.W/2), int(ava.Y+ava.H/2)
+
+	if r, g, b, _ := img.At(x, y).RGBA(); r>>8 < 100 || r>>8 > 160 || r != g || g != b {
+		t.Errorf("expected the gray WebP avatar at %d,%d, got %v", x, y, img.At(x, y))
+	}
+}
+
+func TestDrawHEICBackground(t *testing.T) {
+	if !vips.IsTypeSupported(vips.ImageTypeHEIF) {
+		t.Skip("libvips has no HEIC support")
+	}
+
+	red := image.NewNRGBA(image.Rect(0, 0, 64, 64))
+
+	for i := 0; i < len(red.Pix); i += 4 {
+		red.Pix[i], red.Pix[i+3] = 0xFF, 0xFF
+	}
+
+	vipsImg, err := toVips(red)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer vipsImg.Close()
+
+	heic, _, err := vipsImg.ExportHeif(vips.NewHeifExportParams())
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	opts := testOptions()
+	opts.Bg = "data:image/heic;base64," + base64.StdEncoding.EncodeToString(heic)
+	opts.RequireBg = true
+
+	img, err := New().Draw(context.Background(), opts)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the corner is outside of the foreground overlay
+	if c := color.NRGBAModel.Convert(img.At(0, 0)).(color.NRGBA); c.R < 200 || c.G > 60 || c.B > 60 {
+		t.Errorf("expected the red background, got %v", c)
+	}
+}
@@ -0,0 +1,243 @@
+package preview
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/png"
+	"io/fs"
+	"strings"
+	"unicode/utf8"
+)
+
+// DefaultEmojiURL is the base URL the color emoji images missing in the font dir are fetched from,
+// it serves the 72x72 Twemoji PNGs.
+const DefaultEmojiURL = "https://cdn.jsdelivr.net/gh/jdecked/twemoji@15.1.0/assets/72x72/"
+
+const (
+	// emojiDir is the dir of the font dir with the color emoji images named by the codepoints, e.g. 1f389.png.
+	emojiDir = "emoji"
+	// emojiKey prefixes the emoji sequences to make the keys of their fetched images
+	emojiKey = "emoji:"
+
+	zeroWidthJoiner   = '\u200d'
+	variationSelector = '\ufe0f'
+)
+
+// textRun is a part of a string that is either plain text or a single emoji sequence.
+type textRun struct {
+	text  string
+	emoji bool
+}
+
+// isEmoji reports whether the rune starts an emoji sequence.
+func isEmoji(r rune) bool {
+	return (r >= 0x1F000 && r <= 0x1FAFF) || (r >= 0x2600 && r <= 0x27BF) || (r >= 0x2B00 && r <= 0x2BFF)
+}
+
+// isRegionalIndicator reports whether the rune is one of the two letters of a flag.
+func isRegionalIndicator(r rune) bool {
+	return r >= 0x1F1E6 && r <= 0x1F1FF
+}
+
+// isEmojiModifier reports whether the rune modifies the preceding emoji: a variation selector, a skin tone or a tag.
+func isEmojiModifier(r rune) bool {
+	return r == variationSelector || (r >= 0x1F3FB && r <= 0x1F3FF) || (r >= 0xE0020 && r <= 0xE007F)
+}
+
+// splitEmoji splits the string into the runs of plain text and the emoji sequences
+// including the modifiers, the ZWJ sequences and the flags.
+func splitEmoji(s string) []textRun {
+	var runs []textRun
+
+	start := 0
+
+	for i := 0; i < len(s); {
+		r, size := utf8.DecodeRuneInString(s[i:])
+
+		if !isEmoji(r) {
+			i += size
+			continue
+		}
+
+		if start < i {
+			runs = append(runs, textRun{text: s[start:i]})
+		}
+
+		end := i + size
+		flag := isRegionalIndicator(r)
+
+	sequence:
+		for end < len(s) {
+			next, nextSize := utf8.DecodeRuneInString(s[end:])
+
+			switch {
+			case isEmojiModifier(next):
+				end += nextSize
+			case flag && isRegionalIndicator(next):
+				end += nextSize
+				flag = false
+			case next == zeroWidthJoiner:
+				joined, joinedSize := utf8.DecodeRuneInString(s[end+nextSize:])
+
+				if !isEmoji(joined) {
+					break sequence
+				}
+
+				end += nextSize + joinedSize
+			default:
+				break sequence
+			}
+		}
+
+		runs = append(runs, textRun{text: s[i:end], emoji: true})
+		i, start = end, end
+	}
+
+	if start < len(s) {
+		runs = append(runs, textRun{text: s[start:]})
+	}
+
+	return runs
+}
+
+// emojiFileNames returns the possible image names of the emoji sequence:
+// the lowercase hex codepoints joined by dashes, with and without the variation selectors.
+func emojiFileNames(seq string) []string {
+	var all, bare []string
+
+	for _, r := range seq {
+		all = append(all, fmt.Sprintf("%x", r))
+
+		if r != variationSelector {
+			bare = append(bare, fmt.Sprintf("%x", r))
+		}
+	}
+
+	names := []string{strings.Join(all, "-") + ".png"}
+
+	if len(bare) != len(all) {
+		names = append(names, strings.Join(bare, "-")+".png")
+	}
+
+	return names
+}
+
+// emojiImage returns the color image of the emoji sequence from the emoji dir of the font dir,
+// or nil if there is no image for it.
+func (s *fontSet) emojiImage(seq string) (image.Image, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if img, exists := s.emoji[seq]; exists {
+		return img, nil
+	}
+
+	var img image.Image
+
+	if s.dir != nil {
+		for _, name := range emojiFileNames(seq) {
+			f, err := s.dir.Open(emojiDir + "/" + name)
+
+			if errors.Is(err, fs.ErrNotExist) {
+				continue
+			}
+
+			if err != nil {
+				return nil, fmt.Errorf("could not open an emoji image %s: %w", name, err)
+			}
+
+			img, err = png.Decode(f)
+			f.Close()
+
+			if err != nil {
+				return nil, fmt.Errorf("could not decode an emoji image %s: %w", name, err)
+			}
+
+			break
+		}
+	}
+
+	if s.emoji == nil {
+		s.emoji = make(map[string]image.Image)
+	}
+
+	// the missing images are remembered too
+	s.emoji[seq] = img
+
+	return img, nil
+}
+
+// WithEmojiURL makes the Preview fetch the color emoji images missing in the font dir from the base URL
+// instead of DefaultEmojiURL, the images are named like the Twemoji ones (e.g. 1f389.png, see twemojiName).
+// An empty URL leaves the font dir the only source, so the emoji without an image there are drawn as the glyphs.
+func WithEmojiURL(base string) Option {
+	return func(p *Preview) {
+		p.emojiURL = base
+	}
+}
+
+// twemojiName returns the Twemoji image name of the emoji sequence: the lowercase hex codepoints joined by dashes
+// without the variation selectors, which are kept in the ZWJ sequences only.
+func twemojiName(seq string) string {
+	var codepoints []string
+
+	zwj := strings.ContainsRune(seq, zeroWidthJoiner)
+
+	for _, r := range seq {
+		if r != variationSelector || zwj {
+			codepoints = append(codepoints, fmt.Sprintf("%x", r))
+		}
+	}
+
+	return strings.Join(codepoints, "-") + ".png"
+}
+
+// emojiURLs returns the URLs of the color emoji images of the title and the subtitle by their keys,
+// leaving out the emoji the font dir has an image for.
+func (p *drawing) emojiURLs() map[string]string {
+	urls := map[string]string{}
+
+	if !p.opts.ColorEmoji || p.emojiURL == "" {
+		return urls
+	}
+
+	for _, s := range []string{p.normalizedTitle(), p.opts.Subtitle} {
+		for _, run := range splitEmoji(s) {
+			if !run.emoji {
+				continue
+			}
+
+			// the errors are returned when the emoji is drawn
+			if img, err := p.fonts.emojiImage(run.text); err == nil && img == nil {
+				urls[emojiKey+run.text] = p.emojiURL + twemojiName(run.text)
+			}
+		}
+	}
+
+	return urls
+}
+
+// decodeEmoji decodes the fetched color emoji images by their sequences,
+// the emoji whose image can't be decoded are drawn as the glyphs.
+func (p *drawing) decodeEmoji(bufs map[string][]byte) map[string]image.Image {
+	images := map[string]image.Image{}
+
+	for key, buf := range bufs {
+		if !strings.HasPrefix(key, emojiKey) {
+			continue
+		}
+
+		img, err := png.Decode(bytes.NewReader(buf))
+
+		if err != nil {
+			p.logger.Printf("could not decode an emoji image: %s", err)
+			continue
+		}
+
+		images[strings.TrimPrefix(key, emojiKey)] = img
+	}
+
+	return images
+}
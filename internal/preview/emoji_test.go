@@ -0,0 +1,190 @@
+package preview
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"image"
+	"image/png"
+	"reflect"
+	"testing"
+	"testing/fstest"
+)
+
+func TestSplitEmoji(t *testing.T) {
+	tests := []struct {
+		s    string
+		want []textRun
+	}{
+		{"Hello", []textRun{{text: "Hello"}}},
+		{"Party 🎉!", []textRun{{text: "Party "}, {text: "🎉", emoji: true}, {text: "!"}}},
+		{"❤️👍🏽", []textRun{{text: "❤️", emoji: true}, {text: "👍🏽", emoji: true}}},
+		{"👩‍💻 code", []textRun{{text: "👩‍💻", emoji: true}, {text: " code"}}},
+		{"🇷🇺🇬🇧", []textRun{{text: "🇷🇺", emoji: true}, {text: "🇬🇧", emoji: true}}},
+	}
+
+	for _, tt := range tests {
+		if got := splitEmoji(tt.s); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("splitEmoji(%q) = %v, want %v", tt.s, got, tt.want)
+		}
+	}
+}
+
+func TestEmojiFileNames(t *testing.T) {
+	if got, want := emojiFileNames("🎉"), []string{"1f389.png"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	if got, want := emojiFileNames("❤️"), []string{"2764-fe0f.png", "2764.png"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+// hasColor reports whether any pixel of the image is noticeably not gray.
+func hasColor(img image.Image) bool {
+	b := img.Bounds()
+
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			r, g, b, _ := img.At(x, y).RGBA()
+
+			if r>>8 > g>>8+64 || r>>8 > b>>8+64 {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// redEmojiDir returns a font dir with a red square image for 🎉.
+func redEmojiDir(t *testing.T) fstest.MapFS {
+	red := image.NewRGBA(image.Rect(0, 0, 72, 72))
+
+	for i := 0; i < len(red.Pix); i += 4 {
+		copy(red.Pix[i:], []byte{0xE0, 0x20, 0x20, 0xFF})
+	}
+
+	var buf bytes.Buffer
+
+	if err := png.Encode(&buf, red); err != nil {
+		t.Fatal(err)
+	}
+
+	return fstest.MapFS{"emoji/1f389.png": {Data: buf.Bytes()}}
+}
+
+func TestDrawColorEmoji(t *testing.T) {
+	p := New(WithFontDir(redEmojiDir(t)), WithEmojiURL(""))
+
+	opts := testOptions()
+	opts.Title = "Release 🎉"
+
+	img, err := p.Draw(context.Background(), opts)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if hasColor(img) {
+		t.Error("expected a grayscale emoji glyph without ColorEmoji")
+	}
+
+	opts.ColorEmoji = true
+
+	if img, err = p.Draw(context.Background(), opts); err != nil {
+		t.Fatal(err)
+	}
+
+	if !hasColor(img) {
+		t.Error("expected the color emoji image to be drawn")
+	}
+
+	// an emoji without an image falls back to the glyph
+	opts.Title = "Release 🚀"
+
+	if img, err = p.Draw(context.Background(), opts); err != nil {
+		t.Fatal(err)
+	}
+
+	if hasColor(img) {
+		t.Error("expected a grayscale glyph for an emoji without an image")
+	}
+}
+
+func TestTwemojiName(t *testing.T) {
+	tests := []struct {
+		seq, want string
+	}{
+		{"🎉", "1f389.png"},
+		{"❤️", "2764.png"},
+		{"👍🏽", "1f44d-1f3fd.png"},
+		{"👁️‍🗨️", "1f441-fe0f-200d-1f5e8-fe0f.png"},
+	}
+
+	for _, tt := range tests {
+		if got := twemojiName(tt.seq); got != tt.want {
+			t.Errorf("twemojiName(%q) = %q, want %q", tt.seq, got, tt.want)
+		}
+	}
+}
+
+func TestDrawFetchedColorEmoji(t *testing.T) {
+	red := redEmojiDir(t)["emoji/1f389.png"].Data
+	getter := &emojiGetter{buf: red}
+
+	opts := testOptions()
+	opts.Title = "Release 🎉"
+	opts.ColorEmoji = true
+
+	img, err := New(WithGetter(getter)).Draw(context.Background(), opts)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !hasColor(img) {
+		t.Error("expected the fetched color emoji image to be drawn")
+	}
+
+	if want := []string{DefaultEmojiURL + "1f389.png"}; !reflect.DeepEqual(getter.urls, want) {
+		t.Errorf("expected %v to be fetched, got %v", want, getter.urls)
+	}
+
+	// the image of the font dir takes precedence
+	getter.urls = nil
+
+	if _, err = New(WithGetter(getter), WithFontDir(redEmojiDir(t))).Draw(context.Background(), opts); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(getter.urls) != 0 {
+		t.Errorf("expected nothing to be fetched, got %v", getter.urls)
+	}
+
+	// a failed image falls back to the glyph
+	if img, err = New(WithGetter(fakeGetter{err: errors.New("not found")})).Draw(context.Background(), opts); err != nil {
+		t.Fatal(err)
+	}
+
+	if hasColor(img) {
+		t.Error("expected a grayscale glyph for an emoji failed to fetch")
+	}
+}
+
+// emojiGetter returns the same buffer for every resource and records the requested URLs.
+type emojiGetter struct {
+	buf  []byte
+	urls []string
+}
+
+func (g *emojiGetter) GetAll(_ context.Context, urlsOrPaths map[string]string) (map[string][]byte, error) {
+	bufs := make(map[string][]byte, len(urlsOrPaths))
+
+	for key, urlOrPath := range urlsOrPaths {
+		g.urls = append(g.urls, urlOrPath)
+		bufs[key] = g.buf
+	}
+
+	return bufs, nil
+}
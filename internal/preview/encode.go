@@ -0,0 +1,227 @@
+package preview
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"io"
+
+	"github.com/davidbyttow/govips/v2/vips"
+)
+
+// Format is an output image format.
+type Format string
+
+// Supported output formats.
+const (
+	FormatJPEG Format = "jpeg"
+	FormatPNG  Format = "png"
+	FormatWebP Format = "webp"
+	FormatAVIF Format = "avif"
+)
+
+// maxAvifSpeed is the fastest AVIF encoder speed.
+const maxAvifSpeed = 8
+
+// ErrAVIFUnsupported is returned for AVIF output when the linked libvips can't encode it.
+var ErrAVIFUnsupported = errors.New("libvips has no AVIF support, it needs to be built with libheif and an AV1 encoder like aom")
+
+// Supported reports whether the linked libvips can encode the format.
+func Supported(format Format) bool {
+	switch format {
+	case FormatJPEG, FormatPNG, FormatWebP:
+		return true
+	case FormatAVIF:
+		return vips.IsTypeSupported(vips.ImageTypeAVIF)
+	}
+
+	return false
+}
+
+// Encode encodes a drawn preview to the format. Quality is applied to lossy formats only.
+func Encode(img image.Image, format Format, quality int) ([]byte, error) {
+	startVips()
+
+	return encode(img, format, Options{Quality: quality})
+}
+
+// DrawJPEG draws a preview using the provided Options and encodes it to JPEG with Options.Quality.
+// Options.Progressive switches to the progressive encoding.
+func (p *Preview) DrawJPEG(ctx context.Context, opts Options) ([]byte, error) {
+	return p.drawEncoded(ctx, FormatJPEG, opts)
+}
+
+// DrawPNG draws a preview using the provided Options and encodes it to PNG preserving the alpha channel.
+func (p *Preview) DrawPNG(ctx context.Context, opts Options) ([]byte, error) {
+	return p.drawEncoded(ctx, FormatPNG, opts)
+}
+
+// DrawWebP draws a preview using the provided Options and encodes it to WebP with Options.Quality.
+// Options.Lossless switches to the lossless compression.
+func (p *Preview) DrawWebP(ctx context.Context, opts Options) ([]byte, error) {
+	return p.drawEncoded(ctx, FormatWebP, opts)
+}
+
+// DrawAVIF draws a preview using the provided Options and encodes it to AVIF with Options.Quality
+// and Options.AvifSpeed. It fails with ErrAVIFUnsupported if the linked libvips can't encode AVIF.
+func (p *Preview) DrawAVIF(ctx context.Context, opts Options) ([]byte, error) {
+	return p.drawEncoded(ctx, FormatAVIF, opts)
+}
+
+// DrawTo draws a preview using the provided Options, encodes it to Options.Format (JPEG by default)
+// and writes it to the writer. Nothing is written if drawing or encoding fails, so an HTTP handler
+// can still respond with an error.
+func (p *Preview) DrawTo(ctx context.Context, w io.Writer, opts Options) error {
+	format := opts.Format
+
+	if format == "" {
+		format = FormatJPEG
+	}
+
+	buf, err := p.drawEncoded(ctx, format, opts)
+
+	if err != nil {
+		return err
+	}
+
+	if _, err := w.Write(buf); err != nil {
+		return fmt.Errorf("could not write the preview: %w", err)
+	}
+
+	return nil
+}
+
+func (p *Preview) drawEncoded(ctx context.Context, format Format, opts Options) ([]byte, error) {
+	// fail fast before drawing anything
+	if format == FormatAVIF && !Supported(format) {
+		return nil, withKind(ErrEncode, ErrAVIFUnsupported)
+	}
+
+	if format != FormatPNG {
+		if _, err := resolveQuality(opts.Quality); err != nil {
+			return nil, err
+		}
+	}
+
+	var buf []byte
+
+	// the image is encoded before its canvas is reused
+	err := p.drawPooled(ctx, opts, func(img image.Image) error {
+		var err error
+		buf, err = encode(img, format, opts)
+
+		return err
+	})
+
+	return buf, err
+}
+
+// encode encodes an image through vips using the encoding related fields of Options.
+// The drawn image has none of the metadata of the source images, vips is told to add none unless StripMetadata is false.
+func encode(img image.Image, format Format, opts Options) ([]byte, error) {
+	if format != FormatJPEG && format != FormatPNG && format != FormatWebP && format != FormatAVIF {
+		return nil, fmt.Errorf("unknown output format: %q", format)
+	}
+
+	if !Supported(format) {
+		return nil, withKind(ErrEncode, ErrAVIFUnsupported)
+	}
+
+	quality := 0
+
+	if format != FormatPNG {
+		var err error
+
+		if quality, err = resolveQuality(opts.Quality); err != nil {
+			return nil, err
+		}
+	}
+
+	vipsImg, err := toVips(img)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer vipsImg.Close()
+
+	return export(vipsImg, format, quality, opts)
+}
+
+// export exports a vips image to the format with the validated quality.
+func export(vipsImg *vips.ImageRef, format Format, quality int, opts Options) ([]byte, error) {
+	var buf []byte
+	var err error
+
+	strip := opts.StripMetadata == nil || *opts.StripMetadata
+
+	switch format {
+	case FormatJPEG:
+		params := vips.NewJpegExportParams()
+		params.Quality = quality
+		params.Interlace = opts.Progressive
+		params.StripMetadata = strip
+
+		buf, _, err = vipsImg.ExportJpeg(params)
+	case FormatPNG:
+		params := vips.NewPngExportParams()
+		params.StripMetadata = strip
+
+		buf, _, err = vipsImg.ExportPng(params)
+	case FormatWebP:
+		params := vips.NewWebpExportParams()
+		params.Quality = quality
+		params.Lossless = opts.Lossless
+		params.StripMetadata = strip
+
+		buf, _, err = vipsImg.ExportWebp(params)
+	case FormatAVIF:
+		params := vips.NewAvifExportParams()
+		params.Quality = quality
+		params.StripMetadata = strip
+
+		if opts.AvifSpeed > 0 {
+			params.Speed = opts.AvifSpeed
+		}
+
+		buf, _, err = vipsImg.ExportAvif(params)
+	}
+
+	if err != nil {
+		return nil, withKind(ErrEncode, fmt.Errorf("could not encode the preview to %s: %w", format, err))
+	}
+
+	return buf, nil
+}
+
+// resolveQuality returns the default quality for zero and validates the rest.
+func resolveQuality(quality int) (int, error) {
+	if quality == 0 {
+		return DefaultQuality, nil
+	}
+
+	if quality < 1 || quality > 100 {
+		return 0, &ValidationError{Problems: []string{fmt.Sprintf("quality must be between 1 and 100, got %d", quality)}}
+	}
+
+	return quality, nil
+}
+
+// toVips converts a drawn image to a vips image through an in-memory PNG, so alpha is preserved.
+func toVips(img image.Image) (*vips.ImageRef, error) {
+	buf := new(bytes.Buffer)
+
+	if err := pngEncoder.Encode(buf, img); err != nil {
+		return nil, withKind(ErrEncode, fmt.Errorf("could not encode the preview to PNG: %w", err))
+	}
+
+	vipsImg, err := vips.NewImageFromBuffer(buf.Bytes())
+
+	if err != nil {
+		return nil, withKind(ErrEncode, fmt.Errorf("could not load the preview into vips: %w", err))
+	}
+
+	return vipsImg, nil
+}
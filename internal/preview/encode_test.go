@@ -0,0 +1,312 @@
+package preview
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"image/png"
+	"testing"
+
+	"golang.org/x/image/webp"
+)
+
+func TestDrawJPEG_Quality(t *testing.T) {
+	p := New()
+	opts := testOptions()
+	opts.Title = "The quick brown fox jumps over the lazy dog"
+	opts.LogoURL = "logo.png"
+
+	opts.Quality = 20
+	low, err := p.DrawJPEG(context.Background(), opts)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	opts.Quality = 95
+	high, err := p.DrawJPEG(context.Background(), opts)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(high) <= len(low) {
+		t.Errorf("expected a larger buffer for higher quality, low: %d, high: %d", len(low), len(high))
+	}
+}
+
+func TestDrawAVIF(t *testing.T) {
+	opts := testOptions()
+	opts.AvifSpeed = 8
+
+	buf, err := New().DrawAVIF(context.Background(), opts)
+
+	if !Supported(FormatAVIF) {
+		if !errors.Is(err, ErrAVIFUnsupported) {
+			t.Errorf("expected ErrAVIFUnsupported, got %v", err)
+		}
+
+		t.Skip("libvips has no AVIF support")
+	}
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the ISO BMFF file type box of the AVIF brand
+	if len(buf) < 12 || string(buf[4:12]) != "ftypavif" {
+		t.Errorf("expected the ftyp avif box, got %q", buf[:12])
+	}
+}
+
+func TestDrawJPEG_Progressive(t *testing.T) {
+	// the start of frame markers of the baseline and the progressive DCT
+	sof0, sof2 := []byte{0xFF, 0xC0}, []byte{0xFF, 0xC2}
+
+	opts := testOptions()
+	baseline, err := New().DrawJPEG(context.Background(), opts)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Contains(baseline, sof0) || bytes.Contains(baseline, sof2) {
+		t.Error("expected a baseline JPEG by default")
+	}
+
+	opts.Progressive = true
+	progressive, err := New().DrawJPEG(context.Background(), opts)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Contains(progressive, sof2) {
+		t.Error("expected the progressive marker")
+	}
+}
+
+func TestDrawJPEG_NoMetadata(t *testing.T) {
+	bg := jpegWithOrientation(t, halves(1200, 630), binary.BigEndian, 1)
+
+	if !bytes.Contains(bg, []byte(exifHeader)) {
+		t.Fatal("expected the EXIF segment in the background")
+	}
+
+	opts := testOptions()
+	opts.Bg = "data:image/jpeg;base64," + base64.StdEncoding.EncodeToString(bg)
+	opts.RequireBg = true
+
+	buf, err := New().DrawJPEG(context.Background(), opts)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if bytes.Contains(buf, []byte(exifHeader)) {
+		t.Error("expected no EXIF segment in the preview")
+	}
+}
+
+func TestStripMetadata(t *testing.T) {
+	strip, keep := true, false
+	bg := jpegWithOrientation(t, halves(1200, 630), binary.BigEndian, 1)
+
+	for _, setting := range []*bool{nil, &strip, &keep} {
+		for _, format := range []Format{FormatJPEG, FormatPNG} {
+			opts := testOptions()
+			opts.Bg = "data:image/jpeg;base64," + base64.StdEncoding.EncodeToString(bg)
+			opts.RequireBg = true
+			opts.Format = format
+			opts.StripMetadata = setting
+
+			var buf bytes.Buffer
+
+			if err := New().DrawTo(context.Background(), &buf, opts); err != nil {
+				t.Fatalf("could not encode %s with StripMetadata %v: %v", format, setting, err)
+			}
+
+			// the canvas never carries the metadata of the source images
+			if bytes.Contains(buf.Bytes(), []byte(exifHeader)) {
+				t.Errorf("expected no EXIF of the background in %s with StripMetadata %v", format, setting)
+			}
+		}
+	}
+
+	opts := testOptions()
+	stripped, kept := opts, opts
+	stripped.StripMetadata, kept.StripMetadata = &strip, &keep
+
+	if opts.CacheKey() != stripped.CacheKey() {
+		t.Error("expected the metadata stripped by default")
+	}
+
+	if opts.CacheKey() == kept.CacheKey() {
+		t.Error("expected kept metadata to change the key")
+	}
+}
+
+func TestDrawJPEG_BadQuality(t *testing.T) {
+	p := New()
+
+	for _, q := range []int{-1, 101} {
+		opts := testOptions()
+		opts.Quality = q
+
+		if _, err := p.DrawJPEG(context.Background(), opts); err == nil {
+			t.Errorf("expected an error for quality %d", q)
+		}
+	}
+}
+
+func TestDrawPNG_Transparent(t *testing.T) {
+	testCases := []struct {
+		name        string
+		transparent bool
+		expected    uint32
+	}{{
+		name:        "transparent",
+		transparent: true,
+		expected:    0,
+	}, {
+		name:        "opaque",
+		transparent: false,
+		expected:    0xFFFF,
+	}}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := testOptions()
+			opts.Bg = ""
+			opts.Opacity = 0.6
+			opts.Transparent = tt.transparent
+
+			buf, err := New().DrawPNG(context.Background(), opts)
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			img, err := png.Decode(bytes.NewReader(buf))
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			corners := [][2]int{{0, 0}, {opts.CanvasW - 1, 0}, {0, opts.CanvasH - 1}, {opts.CanvasW - 1, opts.CanvasH - 1}}
+
+			for _, c := range corners {
+				if _, _, _, a := img.At(c[0], c[1]).RGBA(); a != tt.expected {
+					t.Errorf("unexpected alpha at %v: %d", c, a)
+				}
+			}
+
+			// the foreground is still composited over the transparent canvas
+			if _, _, _, a := img.At(opts.CanvasW/2, opts.CanvasH-int(DefaultMargin)-1).RGBA(); a == 0 {
+				t.Error("expected the foreground to be drawn")
+			}
+		})
+	}
+}
+
+func TestDrawTo(t *testing.T) {
+	opts := testOptions()
+	opts.Format = FormatPNG
+
+	var buf bytes.Buffer
+
+	if err := New().DrawTo(context.Background(), &buf, opts); err != nil {
+		t.Fatal(err)
+	}
+
+	img, err := png.Decode(&buf)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if b := img.Bounds(); b.Dx() != opts.CanvasW || b.Dy() != opts.CanvasH {
+		t.Errorf("expected a %dx%d preview, got %v", opts.CanvasW, opts.CanvasH, b)
+	}
+
+	// nothing is written when the preview can't be drawn
+	buf.Reset()
+	opts.CanvasW = -1
+
+	if err := New().DrawTo(context.Background(), &buf, opts); !errors.Is(err, ErrInvalidOptions) {
+		t.Errorf("expected ErrInvalidOptions, got %v", err)
+	}
+
+	if buf.Len() > 0 {
+		t.Errorf("expected nothing written, got %d bytes", buf.Len())
+	}
+}
+
+func TestDrawWebP(t *testing.T) {
+	for _, lossless := range []bool{false, true} {
+		opts := testOptions()
+		opts.Lossless = lossless
+
+		buf, err := New().DrawWebP(context.Background(), opts)
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(buf) < 12 || string(buf[0:4]) != "RIFF" || string(buf[8:12]) != "WEBP" {
+			t.Fatalf("not a WebP buffer (lossless: %t)", lossless)
+		}
+
+		config, err := webp.DecodeConfig(bytes.NewReader(buf))
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if config.Width != opts.CanvasW || config.Height != opts.CanvasH {
+			t.Errorf("unexpected dimensions: %dx%d", config.Width, config.Height)
+		}
+	}
+}
+
+func TestEncode(t *testing.T) {
+	img, err := New().Draw(context.Background(), testOptions())
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testCases := []struct {
+		format Format
+		magic  []byte
+	}{{
+		format: FormatJPEG,
+		magic:  []byte{0xFF, 0xD8, 0xFF},
+	}, {
+		format: FormatPNG,
+		magic:  []byte("\x89PNG"),
+	}, {
+		format: FormatWebP,
+		magic:  []byte("RIFF"),
+	}}
+
+	for _, tt := range testCases {
+		t.Run(string(tt.format), func(t *testing.T) {
+			buf, err := Encode(img, tt.format, 90)
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !bytes.HasPrefix(buf, tt.magic) {
+				t.Errorf("unexpected magic bytes: %x", buf[:4])
+			}
+		})
+	}
+
+	if _, err := Encode(img, Format("gif"), 90); err == nil {
+		t.Error("expected an error for an unknown format")
+	}
+}
@@ -0,0 +1,44 @@
+package preview
+
+import "errors"
+
+// The kinds of failures, match them with errors.Is. The errors keep their own messages and causes.
+var (
+	// ErrInvalidOptions is matched by the *ValidationError of the nonsensical Options.
+	ErrInvalidOptions = errors.New("invalid options")
+	// ErrFetch is matched by the failures to get a required image, remote or local.
+	ErrFetch = errors.New("could not get an image")
+	// ErrDecode is matched by the failures to load a required image, e.g. a corrupted or a too large one.
+	ErrDecode = errors.New("could not decode an image")
+	// ErrEncode is matched by the failures to encode the drawn preview.
+	ErrEncode = errors.New("could not encode the preview")
+)
+
+// kindError marks an error as one of the failure kinds.
+type kindError struct {
+	kind error
+	err  error
+}
+
+// withKind marks the error as the kind unless it's already marked.
+func withKind(kind, err error) error {
+	var marked *kindError
+
+	if err == nil || errors.As(err, &marked) {
+		return err
+	}
+
+	return &kindError{kind: kind, err: err}
+}
+
+func (e *kindError) Error() string {
+	return e.err.Error()
+}
+
+func (e *kindError) Unwrap() error {
+	return e.err
+}
+
+func (e *kindError) Is(target error) bool {
+	return target == e.kind
+}
@@ -0,0 +1,81 @@
+package preview
+
+import (
+	"context"
+	"errors"
+	"image"
+	"testing"
+)
+
+func TestErrorKinds(t *testing.T) {
+	kinds := []error{ErrInvalidOptions, ErrFetch, ErrDecode, ErrEncode}
+
+	testCases := []struct {
+		name   string
+		modify func(o *Options)
+		kind   error
+	}{{
+		name:   "invalid options",
+		modify: func(o *Options) { o.CanvasW = 0 },
+		kind:   ErrInvalidOptions,
+	}, {
+		name: "missing logo",
+		modify: func(o *Options) {
+			o.LogoURL = "missing.png"
+			o.RequireLogo = true
+		},
+		kind: ErrFetch,
+	}, {
+		name: "corrupted background",
+		modify: func(o *Options) {
+			o.Bg = "data:image/png;base64,AAAAAAAA"
+			o.RequireBg = true
+		},
+		kind: ErrDecode,
+	}, {
+		name: "no dominant color image",
+		modify: func(o *Options) {
+			o.Bg = "dominant:logo"
+			o.RequireBg = true
+		},
+		kind: ErrFetch,
+	}}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := testOptions()
+			tt.modify(&opts)
+
+			_, err := New().Draw(context.Background(), opts)
+
+			for _, kind := range kinds {
+				if errors.Is(err, kind) != (kind == tt.kind) {
+					t.Errorf("expected errors.Is(%v, %v) to be %v", err, kind, kind == tt.kind)
+				}
+			}
+		})
+	}
+
+	t.Run("invalid options type", func(t *testing.T) {
+		opts := testOptions()
+		opts.CanvasW = 0
+
+		var validationErr *ValidationError
+
+		if _, err := New().Draw(context.Background(), opts); !errors.As(err, &validationErr) {
+			t.Errorf("expected a *ValidationError, got %v", err)
+		}
+	})
+
+	t.Run("unsupported format", func(t *testing.T) {
+		if Supported(FormatAVIF) {
+			t.Skip("libvips supports AVIF")
+		}
+
+		_, err := Encode(image.NewRGBA(image.Rect(0, 0, 8, 8)), FormatAVIF, 0)
+
+		if !errors.Is(err, ErrEncode) || !errors.Is(err, ErrAVIFUnsupported) {
+			t.Errorf("expected an ErrEncode of ErrAVIFUnsupported, got %v", err)
+		}
+	})
+}
@@ -0,0 +1,162 @@
+package preview
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/davidbyttow/govips/v2/vips"
+)
+
+// Background image filters
+const (
+	BgFilterNone      = "none"
+	BgFilterGrayscale = "grayscale"
+	BgFilterDuotone   = "duotone"
+)
+
+// The default duotone colors of the shadows and the highlights
+const (
+	defaultDuotoneDark  = "#1E1B4B"
+	defaultDuotoneLight = "#FDE68A"
+)
+
+// maxBgAdjustment is the largest background brightness, contrast and saturation multiplier.
+const maxBgAdjustment = 3.0
+
+// bgAdjustment returns the multiplier of a background adjustment: 1 if zero, clamped to 0-maxBgAdjustment.
+func bgAdjustment(v float64) float64 {
+	if v == 0 {
+		return 1
+	}
+
+	return math.Max(math.Min(v, maxBgAdjustment), 0)
+}
+
+// filterBackground adjusts the brightness, the contrast and the saturation of the background image
+// and then applies BgFilter. The duotone maps the gray levels of the image to the gradient from DuotoneDark
+// to DuotoneLight. The filtered image is exported to PNG to keep the gray exact.
+func (p *drawing) filterBackground(buf []byte) ([]byte, error) {
+	brightness := bgAdjustment(p.opts.BgBrightness)
+	contrast := bgAdjustment(p.opts.BgContrast)
+	saturation := bgAdjustment(p.opts.BgSaturation)
+	adjust := brightness != 1 || contrast != 1 || saturation != 1
+	filter := p.opts.BgFilter != "" && p.opts.BgFilter != BgFilterNone
+
+	if !adjust && !filter {
+		return buf, nil
+	}
+
+	vipsImg, err := loadImage(buf)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer vipsImg.Close()
+
+	if adjust {
+		p.logger.Printf("Adjusting an image by %g brightness, %g contrast, %g saturation", brightness, contrast, saturation)
+
+		if err = adjustImage(vipsImg, brightness, contrast, saturation); err != nil {
+			return nil, err
+		}
+	}
+
+	if filter {
+		p.logger.Printf("Filtering an image with %s", p.opts.BgFilter)
+
+		// the round trip through the black and white space leaves the same value in all the channels
+		if err = vipsImg.ToColorSpace(vips.InterpretationBW); err != nil {
+			return nil, fmt.Errorf("could not desaturate the image: %w", err)
+		}
+
+		if err = vipsImg.ToColorSpace(vips.InterpretationSRGB); err != nil {
+			return nil, fmt.Errorf("could not convert the image to sRGB: %w", err)
+		}
+	}
+
+	if p.opts.BgFilter == BgFilterDuotone {
+		if err = p.duotone(vipsImg); err != nil {
+			return nil, err
+		}
+	}
+
+	buf, _, err = vipsImg.ExportPng(vips.NewPngExportParams())
+
+	if err != nil {
+		return nil, err
+	}
+
+	return buf, nil
+}
+
+// adjustImage scales the saturation and then the brightness and the contrast around the middle gray.
+func adjustImage(vipsImg *vips.ImageRef, brightness, contrast, saturation float64) error {
+	if saturation != 1 {
+		if err := vipsImg.Modulate(1, saturation, 0); err != nil {
+			return fmt.Errorf("could not adjust the saturation: %w", err)
+		}
+	}
+
+	if brightness == 1 && contrast == 1 {
+		return nil
+	}
+
+	// contrast * (brightness * v - 128) + 128 for each color channel
+	a := []float64{contrast * brightness, contrast * brightness, contrast * brightness}
+	b := []float64{128 * (1 - contrast), 128 * (1 - contrast), 128 * (1 - contrast)}
+
+	// the alpha is kept as it is
+	if vipsImg.HasAlpha() {
+		a, b = append(a, 1), append(b, 0)
+	}
+
+	if err := vipsImg.Linear(a, b); err != nil {
+		return fmt.Errorf("could not adjust the brightness and the contrast: %w", err)
+	}
+
+	return nil
+}
+
+// duotone maps the gray image to the gradient of the duotone colors channel by channel.
+func (p *drawing) duotone(vipsImg *vips.ImageRef) error {
+	darkHex, lightHex := p.opts.DuotoneDark, p.opts.DuotoneLight
+
+	if darkHex == "" {
+		darkHex = defaultDuotoneDark
+	}
+
+	if lightHex == "" {
+		lightHex = defaultDuotoneLight
+	}
+
+	dark, err := parseHexColor(darkHex)
+
+	if err != nil {
+		return fmt.Errorf("invalid duotone dark color: %w", err)
+	}
+
+	light, err := parseHexColor(lightHex)
+
+	if err != nil {
+		return fmt.Errorf("invalid duotone light color: %w", err)
+	}
+
+	a := []float64{
+		float64(int(light.R)-int(dark.R)) / 255,
+		float64(int(light.G)-int(dark.G)) / 255,
+		float64(int(light.B)-int(dark.B)) / 255,
+	}
+	b := []float64{float64(dark.R), float64(dark.G), float64(dark.B)}
+
+	// the alpha is kept as it is
+	if vipsImg.HasAlpha() {
+		a, b = append(a, 1), append(b, 0)
+	}
+
+	if err = vipsImg.Linear(a, b); err != nil {
+		return fmt.Errorf("could not map the image to the duotone: %w", err)
+	}
+
+	return nil
+}
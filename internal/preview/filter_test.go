@@ -0,0 +1,117 @@
+package preview
+
+import (
+	"context"
+	"image"
+	"math"
+	"testing"
+)
+
+func TestDrawBgFilter(t *testing.T) {
+	render := func(filter string) func(x, y int) (r, g, b uint32) {
+		opts := testOptions()
+		opts.Title = ""
+		opts.Bg = stripesDataURL(t, 1200, 630)
+		opts.RequireBg = true
+		opts.BgFilter = filter
+		opts.DuotoneDark = "#400000"
+		opts.DuotoneLight = "#FF8080"
+
+		img, err := New().Draw(context.Background(), opts)
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return func(x, y int) (r, g, b uint32) {
+			r, g, b, _ = img.At(x, y).RGBA()
+
+			return r >> 8, g >> 8, b >> 8
+		}
+	}
+
+	// a point of each of the stripes
+	xs := []int{200, 600, 1000}
+
+	at := render(BgFilterNone)
+
+	if r, g, b := at(xs[0], 300); r == g && g == b {
+		t.Errorf("expected the colors kept without a filter, got %d,%d,%d", r, g, b)
+	}
+
+	at = render(BgFilterGrayscale)
+
+	for y := 0; y < 630; y += 10 {
+		for x := 0; x < 1200; x += 10 {
+			if r, g, b := at(x, y); r != g || g != b {
+				t.Fatalf("expected a gray pixel at %d,%d, got %d,%d,%d", x, y, r, g, b)
+			}
+		}
+	}
+
+	at = render(BgFilterDuotone)
+
+	// the duotone colors are reddish with the same green and blue
+	for _, x := range xs {
+		if r, g, b := at(x, 300); r <= g || g != b {
+			t.Errorf("expected a pixel of the duotone at %d, got %d,%d,%d", x, r, g, b)
+		}
+	}
+}
+
+func TestDrawBgAdjustment(t *testing.T) {
+	render := func(modify func(o *Options)) image.Image {
+		opts := testOptions()
+		opts.Title = ""
+		opts.Bg = stripesDataURL(t, 1200, 630)
+		opts.RequireBg = true
+		modify(&opts)
+
+		img, err := New().Draw(context.Background(), opts)
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return img
+	}
+
+	mean := func(img image.Image) float64 {
+		var sum, n float64
+
+		for y := 0; y < 630; y += 10 {
+			for x := 0; x < 1200; x += 10 {
+				r, g, b, _ := img.At(x, y).RGBA()
+				sum += float64(r>>8+g>>8+b>>8) / 3
+				n++
+			}
+		}
+
+		return sum / n
+	}
+
+	// a negative saturation is clamped to 0
+	gray := render(func(o *Options) { o.BgSaturation = -1 })
+
+	for y := 0; y < 630; y += 10 {
+		for x := 0; x < 1200; x += 10 {
+			r, g, b, _ := gray.At(x, y).RGBA()
+			lo, hi := math.Min(float64(r>>8), math.Min(float64(g>>8), float64(b>>8))), math.Max(float64(r>>8), math.Max(float64(g>>8), float64(b>>8)))
+
+			// the LCh round trip of vips may be off by a level
+			if hi-lo > 2 {
+				t.Fatalf("expected a gray pixel at %d,%d, got %d,%d,%d", x, y, r>>8, g>>8, b>>8)
+			}
+		}
+	}
+
+	original := mean(render(func(o *Options) {}))
+
+	if same := mean(render(func(o *Options) { o.BgBrightness, o.BgContrast, o.BgSaturation = 1, 1, 1 })); same != original {
+		t.Errorf("expected the multipliers of 1 to keep the background, got the mean of %.1f vs %.1f", same, original)
+	}
+
+	if dark := mean(render(func(o *Options) { o.BgBrightness = 0.5 })); dark >= original-10 {
+		t.Errorf("expected a darker background, got the mean of %.1f vs %.1f", dark, original)
+	}
+}
@@ -1,7 +1,14 @@
 package preview
 
 import (
+	"crypto/sha1"
 	"embed"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"image"
+	"io/fs"
+	"path"
 	"sync"
 
 	"github.com/AndreKR/multiface"
@@ -9,99 +16,245 @@ import (
 	"golang.org/x/image/font"
 )
 
+// Text font weights
 const (
+	WeightRegular = "regular"
+	WeightMedium  = "medium"
+	WeightBold    = "bold"
+)
+
+const (
+	// the regular and the bold weights fall back to the medium one unless embedded or in the font dir
+	regularFont = "fonts/Ubuntu-Regular.ttf"
 	textFont    = "fonts/Ubuntu-Medium.ttf"
+	boldFont    = "fonts/Ubuntu-Bold.ttf"
 	symbolsFont = "fonts/NotoSansSymbols-Medium.ttf"
 	emoji1Font  = "fonts/NotoEmoji-Regular.ttf"
 	emoji2Font  = "fonts/Symbola.ttf"
+	// cjkFont is not embedded as it's too large, the font dir may provide it
+	cjkFont = "fonts/NotoSansCJK.ttf"
 )
 
+// maxIdleFaces bounds the number of font faces kept for reuse between draws.
+const maxIdleFaces = 32
+
 //go:embed fonts/*
 var fonts embed.FS
-var cache sync.Map
-
-// loadFont loads a multiface consisting of letters, symbols and emojis merged to one font face.
-// It caches the result in memory for each font size to avoid multiface creation on each request
-func loadFont(points float64) (font.Face, error) {
-	if cached, exists := cache.Load(points); exists {
-		if face, ok := cached.(font.Face); ok {
-			return face, nil
+
+// defaultFonts is the font set of the embedded fonts shared by the previews without a font dir.
+var defaultFonts = &fontSet{}
+
+// FontSource is a TrueType font either read by the path from the font dir (falling back to the embedded fonts)
+// or given as raw bytes. The zero value stands for the embedded text font.
+type FontSource struct {
+	Path string
+	Data []byte
+}
+
+// weightFonts maps the text font weights to the embedded fonts.
+var weightFonts = map[string]string{
+	"":            textFont,
+	WeightRegular: regularFont,
+	WeightMedium:  textFont,
+	WeightBold:    boldFont,
+}
+
+// textFontSource returns the custom font if set, or the text font of the weight.
+func textFontSource(custom FontSource, weight string) FontSource {
+	if custom.Path != "" || len(custom.Data) > 0 {
+		return custom
+	}
+
+	return FontSource{Path: weightFonts[weight]}
+}
+
+// key identifies the font among the parsed ones.
+func (f FontSource) key() string {
+	if len(f.Data) > 0 {
+		sum := sha1.Sum(f.Data)
+
+		return "sha1:" + hex.EncodeToString(sum[:])
+	}
+
+	if f.Path == "" {
+		return textFont
+	}
+
+	return f.Path
+}
+
+// faceKey identifies a face of the primary font of the size.
+type faceKey struct {
+	font   string
+	points float64
+}
+
+// FontSet is a font dir for Options.FontSet: the fonts and the emoji images are read from the dir like with WithFontDir.
+// The fonts are parsed once per FontSet, so it's meant to be reused by the draws. It's safe for concurrent use.
+type FontSet struct {
+	fonts *fontSet
+}
+
+// NewFontSet returns a FontSet reading the fonts from the dir, the fonts missing in the dir fall back to the embedded ones.
+func NewFontSet(dir fs.FS) *FontSet {
+	return &FontSet{fonts: &fontSet{dir: dir}}
+}
+
+// fontSet loads font faces from a font dir falling back to the embedded fonts.
+type fontSet struct {
+	dir fs.FS
+
+	mu     sync.Mutex
+	parsed map[string]*truetype.Font
+	idle   map[faceKey][]font.Face
+	idleN  int
+	emoji  map[string]image.Image
+}
+
+// readFile reads a font by the path from the font dir if the dir has it, or from the embedded fonts.
+// The embedded fonts can be overridden by the files of the same name in the root of the dir.
+func (s *fontSet) readFile(name string) ([]byte, error) {
+	if s.dir != nil {
+		for _, name := range []string{name, path.Base(name)} {
+			buf, err := fs.ReadFile(s.dir, name)
+
+			if !errors.Is(err, fs.ErrNotExist) {
+				return buf, err
+			}
 		}
 	}
 
-	face := new(multiface.Face)
-	textBuf, err := fonts.ReadFile(textFont)
+	return fonts.ReadFile(name)
+}
 
-	if err != nil {
-		return nil, err
+// acquireFace returns an idle face of the primary font and the size if any, or loads a new one.
+// The face is owned by the caller until it's released, as faces can't be used concurrently.
+func (s *fontSet) acquireFace(primary FontSource, points float64) (font.Face, error) {
+	key := faceKey{font: primary.key(), points: points}
+
+	s.mu.Lock()
+
+	if faces := s.idle[key]; len(faces) > 0 {
+		face := faces[len(faces)-1]
+		s.idle[key] = faces[:len(faces)-1]
+		s.idleN--
+		s.mu.Unlock()
+
+		return face, nil
 	}
 
-	textFont, err := truetype.Parse(textBuf)
+	s.mu.Unlock()
 
-	if err != nil {
-		return nil, err
+	return s.loadFont(primary, points)
+}
+
+// releaseFace makes the face reusable by the next draws unless too many faces are idle already.
+func (s *fontSet) releaseFace(key faceKey, face font.Face) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.idleN >= maxIdleFaces {
+		return
 	}
 
-	textFace := truetype.NewFace(textFont, &truetype.Options{
-		Size: points,
-	})
+	if s.idle == nil {
+		s.idle = make(map[faceKey][]font.Face)
+	}
 
-	face.AddTruetypeFace(textFace, textFont)
+	s.idle[key] = append(s.idle[key], face)
+	s.idleN++
+}
 
-	symbolsBuf, err := fonts.ReadFile(symbolsFont)
+// loadFont loads a multiface consisting of the primary font followed by symbols and emojis fallbacks merged to one font face.
+// Font faces keep glyph buffers, so a face must not be shared between concurrent draws.
+func (s *fontSet) loadFont(primary FontSource, points float64) (font.Face, error) {
+	fonts, err := s.fallbackFonts(primary)
 
 	if err != nil {
 		return nil, err
 	}
 
-	symbolsFont, err := truetype.Parse(symbolsBuf)
+	face := new(multiface.Face)
 
-	if err != nil {
-		return nil, err
+	for _, f := range fonts {
+		face.AddTruetypeFace(truetype.NewFace(f, &truetype.Options{
+			Size: points,
+		}), f)
 	}
 
-	symbolsFace := truetype.NewFace(symbolsFont, &truetype.Options{
-		Size: points,
-	})
+	return face, nil
+}
 
-	face.AddTruetypeFace(symbolsFace, symbolsFont)
+// fontSources returns the primary font followed by the CJK, symbols and emojis fallbacks.
+func fontSources(primary FontSource) []FontSource {
+	return []FontSource{primary, {Path: cjkFont}, {Path: symbolsFont}, {Path: emoji1Font}, {Path: emoji2Font}}
+}
 
-	emoji1Buf, err := fonts.ReadFile(emoji1Font)
+// fallbackFonts parses the primary font and its fallbacks in the order the glyphs are looked up,
+// skipping the CJK font if the font dir doesn't have it.
+func (s *fontSet) fallbackFonts(primary FontSource) ([]*truetype.Font, error) {
+	sources := fontSources(primary)
+	fonts := make([]*truetype.Font, 0, len(sources))
 
-	if err != nil {
-		return nil, err
+	for _, src := range sources {
+		f, err := s.parse(src)
+
+		if err != nil {
+			return nil, err
+		}
+
+		if f != nil {
+			fonts = append(fonts, f)
+		}
 	}
 
-	emoji1Font, err := truetype.Parse(emoji1Buf)
+	return fonts, nil
+}
 
-	if err != nil {
-		return nil, err
+// parse parses a font once and keeps it for the faces of any size, as parsed fonts are immutable.
+// The optional CJK font missing in the font dir is parsed as nil.
+func (s *fontSet) parse(src FontSource) (*truetype.Font, error) {
+	key := src.key()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if f, exists := s.parsed[key]; exists {
+		return f, nil
 	}
 
-	emoji1Face := truetype.NewFace(emoji1Font, &truetype.Options{
-		Size: points,
-	})
+	if s.parsed == nil {
+		s.parsed = make(map[string]*truetype.Font)
+	}
 
-	face.AddTruetypeFace(emoji1Face, emoji1Font)
+	buf := src.Data
 
-	emoji2Buf, err := fonts.ReadFile(emoji2Font)
+	if len(buf) == 0 {
+		var err error
 
-	if err != nil {
-		return nil, err
+		buf, err = s.readFile(key)
+
+		if (key == regularFont || key == boldFont) && errors.Is(err, fs.ErrNotExist) {
+			buf, err = s.readFile(textFont)
+		}
+
+		if key == cjkFont && errors.Is(err, fs.ErrNotExist) {
+			s.parsed[key] = nil
+			return nil, nil
+		}
+
+		if err != nil {
+			return nil, fmt.Errorf("could not read a font: %w", err)
+		}
 	}
 
-	emoji2Font, err := truetype.Parse(emoji2Buf)
+	f, err := truetype.Parse(buf)
 
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("could not parse a TrueType font %s: %w", key, err)
 	}
 
-	emoji2Face := truetype.NewFace(emoji2Font, &truetype.Options{
-		Size: points,
-	})
+	s.parsed[key] = f
 
-	face.AddTruetypeFace(emoji2Face, emoji2Font)
-	cache.Store(points, face)
-
-	return face, nil
+	return f, nil
 }
@@ -0,0 +1,433 @@
+package preview
+
+import (
+	"bytes"
+	"context"
+	"image"
+	"io/fs"
+	"log"
+	"os"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/golang/freetype/truetype"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/gofont/gobold"
+	"golang.org/x/image/font/gofont/goregular"
+	"golang.org/x/image/math/fixed"
+)
+
+func TestFontSetParsesOnce(t *testing.T) {
+	s := &fontSet{}
+
+	first, err := s.parse(FontSource{})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	second, err := s.parse(FontSource{})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if first != second {
+		t.Error("expected the parsed font to be reused")
+	}
+
+	small, _ := s.loadFont(FontSource{}, 20)
+	large, _ := s.loadFont(FontSource{}, 40)
+
+	if small.Metrics().Height >= large.Metrics().Height {
+		t.Errorf("expected faces to keep their sizes: %v vs %v", small.Metrics().Height, large.Metrics().Height)
+	}
+}
+
+func TestFontSetRenderUnchanged(t *testing.T) {
+	opts := testOptions()
+	opts.Author = "@Tester ✓ 😀"
+	opts.AuthorSize = 36
+	opts.LabelL = "Label"
+
+	draw := func(p *Preview) image.Image {
+		img, err := p.Draw(context.Background(), opts)
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return img
+	}
+
+	// a preview drawn with freshly parsed fonts matches the ones drawn with the fonts parsed before
+	fresh := New()
+	fresh.fonts = &fontSet{}
+
+	want := draw(fresh).(*image.RGBA)
+	p := New()
+
+	for i := 0; i < 2; i++ {
+		if got := draw(p).(*image.RGBA); string(got.Pix) != string(want.Pix) {
+			t.Fatalf("draw #%d differs from the one with freshly parsed fonts", i)
+		}
+	}
+}
+
+func BenchmarkLoadFont(b *testing.B) {
+	b.Run("parse every time", func(b *testing.B) {
+		for n := 0; n < b.N; n++ {
+			if _, err := (&fontSet{}).loadFont(FontSource{}, 40); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+
+	b.Run("parse once", func(b *testing.B) {
+		s := &fontSet{}
+
+		for n := 0; n < b.N; n++ {
+			if _, err := s.loadFont(FontSource{}, 40); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+func TestFontSetReusesFaces(t *testing.T) {
+	s := &fontSet{}
+
+	first, err := s.acquireFace(FontSource{}, 40)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// a face in use is never handed out twice
+	second, _ := s.acquireFace(FontSource{}, 40)
+
+	if first == second {
+		t.Error("expected a face in use not to be shared")
+	}
+
+	s.releaseFace(faceKey{textFont, 40}, first)
+	s.releaseFace(faceKey{textFont, 40}, second)
+
+	if again, _ := s.acquireFace(FontSource{}, 40); again != second {
+		t.Error("expected the same face to be returned for the same size")
+	}
+
+	if other, _ := s.acquireFace(FontSource{}, 20); other == first || other == second {
+		t.Error("expected a different face for a different size")
+	}
+
+	for i := 0; i < maxIdleFaces*2; i++ {
+		face, _ := s.loadFont(FontSource{}, 12)
+		s.releaseFace(faceKey{textFont, 12}, face)
+	}
+
+	if s.idleN > maxIdleFaces {
+		t.Errorf("expected at most %d idle faces, got %d", maxIdleFaces, s.idleN)
+	}
+}
+
+func BenchmarkDrawFontSizes(b *testing.B) {
+	p := New()
+	sizes := []float64{48, 64, 76}
+
+	b.ReportAllocs()
+
+	for n := 0; n < b.N; n++ {
+		opts := testOptions()
+		opts.TitleSize = sizes[n%len(sizes)]
+		opts.Author = "@Tester"
+		opts.AuthorSize = 36
+		opts.LabelL = "Label"
+
+		if _, err := p.Draw(context.Background(), opts); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func TestCustomFonts(t *testing.T) {
+	symbola, err := fonts.ReadFile(emoji2Font)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := &fontSet{}
+
+	width := func(src FontSource) fixed.Int26_6 {
+		face, err := s.loadFont(src, 40)
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return font.MeasureString(face, "Hello, world")
+	}
+
+	def := width(FontSource{})
+
+	if got := width(FontSource{Path: emoji2Font}); got == def {
+		t.Error("expected a font by the path to change the metrics")
+	}
+
+	if got := width(FontSource{Data: symbola}); got == def {
+		t.Error("expected a font by the bytes to change the metrics")
+	}
+
+	if _, err := s.loadFont(FontSource{Path: "fonts/missing.ttf"}, 40); err == nil {
+		t.Error("expected an error for a missing font")
+	}
+}
+
+func TestDrawCustomFonts(t *testing.T) {
+	symbola, err := fonts.ReadFile(emoji2Font)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	draw := func(title, author FontSource) (image.Image, error) {
+		opts := testOptions()
+		opts.Author = "@Tester"
+		opts.AuthorSize = 36
+		opts.TitleFont = title
+		opts.AuthorFont = author
+
+		return New().Draw(context.Background(), opts)
+	}
+
+	want, err := draw(FontSource{}, FontSource{})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := draw(FontSource{Path: emoji2Font}, FontSource{Data: symbola})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(got.(*image.RGBA).Pix) == string(want.(*image.RGBA).Pix) {
+		t.Error("expected custom fonts to change the rendering")
+	}
+
+	_, err = draw(FontSource{Data: []byte("not a font")}, FontSource{})
+
+	if err == nil || !strings.Contains(err.Error(), "could not parse a TrueType font") {
+		t.Errorf("expected a TrueType parsing error, got %v", err)
+	}
+}
+
+func TestFontWeights(t *testing.T) {
+	// the Go fonts stand in for the Ubuntu weights, only the selection matters here
+	s := &fontSet{dir: fstest.MapFS{
+		"Ubuntu-Regular.ttf": {Data: goregular.TTF},
+		"Ubuntu-Bold.ttf":    {Data: gobold.TTF},
+	}}
+
+	width := func(weight string) fixed.Int26_6 {
+		face, err := s.loadFont(textFontSource(FontSource{}, weight), 40)
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return font.MeasureString(face, "Hello, world")
+	}
+
+	medium := width(WeightMedium)
+
+	if got := width(""); got != medium {
+		t.Errorf("expected medium by default, got width %v, want %v", got, medium)
+	}
+
+	regular, bold := width(WeightRegular), width(WeightBold)
+
+	if regular == medium || bold == medium || regular >= bold {
+		t.Errorf("expected the weight to change the width, got regular %v, medium %v, bold %v", regular, medium, bold)
+	}
+
+	custom := FontSource{Path: emoji2Font}
+
+	if got := textFontSource(custom, WeightBold); got.Path != custom.Path {
+		t.Errorf("expected a custom font to take precedence over the weight, got %q", got.Path)
+	}
+}
+
+func TestValidateFontWeights(t *testing.T) {
+	opts := testOptions()
+	opts.TitleWeight = WeightBold
+	opts.AuthorWeight = WeightRegular
+
+	if err := opts.Validate(); err != nil {
+		t.Fatal(err)
+	}
+
+	// the weights not embedded fall back to the medium one
+	medium := opts
+	medium.TitleWeight, medium.AuthorWeight = WeightMedium, WeightMedium
+
+	img, err := New().Draw(context.Background(), opts)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want, err := New().Draw(context.Background(), medium)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(img.(*image.RGBA).Pix) != string(want.(*image.RGBA).Pix) {
+		t.Error("expected the medium weight drawn for the missing weights")
+	}
+
+	weights := fstest.MapFS{
+		"Ubuntu-Regular.ttf": {Data: goregular.TTF},
+		"Ubuntu-Bold.ttf":    {Data: gobold.TTF},
+	}
+
+	if img, err = New(WithFontDir(weights)).Draw(context.Background(), opts); err != nil {
+		t.Fatal(err)
+	}
+
+	if string(img.(*image.RGBA).Pix) == string(want.(*image.RGBA).Pix) {
+		t.Error("expected the weights of the font dir drawn")
+	}
+
+	opts.TitleWeight = "heavy"
+
+	if err := opts.Validate(); err == nil || !strings.Contains(err.Error(), "unknown title font weight: heavy") {
+		t.Errorf("expected an unknown weight error, got %v", err)
+	}
+}
+
+func TestCJKFallbackFont(t *testing.T) {
+	fonts, err := (&fontSet{}).fallbackFonts(FontSource{})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(fonts) != 4 {
+		t.Errorf("expected the missing CJK font skipped, got %d fonts", len(fonts))
+	}
+
+	// any TrueType font stands for the CJK one here
+	dir := fstest.MapFS{"NotoSansCJK.ttf": {Data: goregular.TTF}}
+
+	if fonts, err = (&fontSet{dir: dir}).fallbackFonts(FontSource{}); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(fonts) != 5 || fonts[1].Name(truetype.NameIDFontFamily) != "Go" {
+		t.Error("expected the CJK font to follow the text font")
+	}
+}
+
+func TestDrawCJK(t *testing.T) {
+	// no CJK font is embedded, put a TrueType NotoSansCJK.ttf to testdata/fonts to run the test
+	dir := os.DirFS("testdata/fonts")
+
+	if _, err := fs.Stat(dir, "NotoSansCJK.ttf"); err != nil {
+		t.Skip("no CJK font in testdata/fonts")
+	}
+
+	var logs bytes.Buffer
+
+	opts := testOptions()
+	opts.Title = "日本語のタイトル"
+
+	p := New(WithFontDir(dir), WithLogger(log.New(&logs, "", 0)))
+	d := p.newDrawing(opts.withDefaults())
+	img, err := d.draw(context.Background())
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	box := d.layout.Title
+
+	if !hasInk(img, image.Rect(int(box.X), int(box.Y), int(box.X+box.W), int(box.Y+box.H))) {
+		t.Error("expected the CJK glyphs drawn")
+	}
+
+	if strings.Contains(logs.String(), "No glyph for") {
+		t.Errorf("expected the CJK font to have the glyphs, got %q", logs.String())
+	}
+}
+
+func TestDrawFontSetsConcurrently(t *testing.T) {
+	// the brands replace the text font, so the title, the author and the label are drawn with it
+	themes := []*FontSet{
+		NewFontSet(fstest.MapFS{"Ubuntu-Medium.ttf": {Data: goregular.TTF}}),
+		NewFontSet(fstest.MapFS{"Ubuntu-Medium.ttf": {Data: gobold.TTF}}),
+	}
+
+	p := New()
+
+	draw := func(fonts *FontSet) ([]byte, error) {
+		opts := testOptions()
+		opts.Author = "@Tester"
+		opts.LabelL = "Label"
+		opts.FontSet = fonts
+
+		img, err := p.Draw(context.Background(), opts)
+
+		if err != nil {
+			return nil, err
+		}
+
+		return img.(*image.RGBA).Pix, nil
+	}
+
+	want := make([][]byte, len(themes))
+
+	for i, fonts := range themes {
+		var err error
+
+		if want[i], err = draw(fonts); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if string(want[0]) == string(want[1]) {
+		t.Fatal("expected the font sets to change the rendering")
+	}
+
+	const draws = 8
+
+	got := make([][]byte, draws)
+	errs := make([]error, draws)
+	done := make(chan struct{})
+
+	for i := 0; i < draws; i++ {
+		go func(i int) {
+			got[i], errs[i] = draw(themes[i%len(themes)])
+			done <- struct{}{}
+		}(i)
+	}
+
+	for i := 0; i < draws; i++ {
+		<-done
+	}
+
+	for i := range got {
+		if errs[i] != nil {
+			t.Fatal(errs[i])
+		}
+
+		if string(got[i]) != string(want[i%len(themes)]) {
+			t.Errorf("expected draw %d to use the fonts of its font set", i)
+		}
+	}
+}
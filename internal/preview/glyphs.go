@@ -0,0 +1,101 @@
+package preview
+
+import (
+	"strings"
+	"unicode"
+
+	"github.com/golang/freetype/truetype"
+)
+
+// The glyphs drawn instead of the runes missing from all the fonts, the first one any of the fonts has
+var replacementRunes = []rune{'�', '□', '?'}
+
+// hasGlyph reports whether any of the fonts has a glyph for the rune.
+func hasGlyph(fonts []*truetype.Font, r rune) bool {
+	for _, f := range fonts {
+		if f.Index(r) != 0 {
+			return true
+		}
+	}
+
+	return false
+}
+
+// replaceMissingGlyphs replaces the runes the primary font and its fallbacks have no glyph for
+// with a replacement glyph, so they are not silently dropped, and returns the replaced runes.
+// Whitespace, control and format characters and combining marks are kept as they have no visible glyph anyway.
+func (s *fontSet) replaceMissingGlyphs(primary FontSource, text string) (string, []rune, error) {
+	fonts, err := s.fallbackFonts(primary)
+
+	if err != nil {
+		return "", nil, err
+	}
+
+	replacement := replacementRunes[len(replacementRunes)-1]
+
+	for _, r := range replacementRunes {
+		if hasGlyph(fonts, r) {
+			replacement = r
+			break
+		}
+	}
+
+	var missing []rune
+
+	replaced := strings.Map(func(r rune) rune {
+		if unicode.In(r, unicode.Space, unicode.Cc, unicode.Cf, unicode.Mn) || hasGlyph(fonts, r) {
+			return r
+		}
+
+		missing = append(missing, r)
+
+		return replacement
+	}, text)
+
+	return replaced, missing, nil
+}
+
+// replaceMissingTextGlyphs replaces the runes without a glyph in all the texts of the preview
+// before they are measured and logs them.
+func (p *drawing) replaceMissingTextGlyphs() error {
+	// the direction is picked by the script of the original title
+	if p.opts.TitleDirection != DirectionLTR && p.opts.TitleDirection != DirectionRTL && isRTLText(p.opts.Title) {
+		p.opts.TitleDirection = DirectionRTL
+	}
+
+	type text struct {
+		font FontSource
+		text *string
+	}
+
+	texts := []text{
+		{textFontSource(p.opts.TitleFont, p.opts.TitleWeight), &p.opts.Title},
+		{textFontSource(p.opts.AuthorFont, p.opts.AuthorWeight), &p.opts.Author},
+		{textFontSource(p.opts.AuthorFont, p.opts.AuthorWeight), &p.opts.Meta},
+		{FontSource{}, &p.opts.Subtitle},
+		{FontSource{}, &p.opts.LabelL},
+		{FontSource{}, &p.opts.LabelR},
+	}
+
+	// the tags are copied not to change the slice of the caller
+	p.opts.Tags = append([]string(nil), p.opts.Tags...)
+
+	for i := range p.opts.Tags {
+		texts = append(texts, text{FontSource{}, &p.opts.Tags[i]})
+	}
+
+	for _, t := range texts {
+		replaced, missing, err := p.fonts.replaceMissingGlyphs(t.font, *t.text)
+
+		if err != nil {
+			return err
+		}
+
+		if len(missing) > 0 {
+			p.logger.Printf("No glyph for %q in the fonts, drawing a replacement", string(missing))
+			*t.text = replaced
+		}
+	}
+
+	return nil
+}
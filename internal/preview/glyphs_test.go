@@ -0,0 +1,48 @@
+package preview
+
+import (
+	"bytes"
+	"context"
+	"image"
+	"log"
+	"strings"
+	"testing"
+)
+
+func TestDrawMissingGlyph(t *testing.T) {
+	// a private use rune none of the fonts has
+	const missing = "\U0010FFFD"
+
+	var logs bytes.Buffer
+
+	opts := testOptions()
+	opts.Title = missing
+	opts.Tags = []string{missing}
+
+	p := New(WithLogger(log.New(&logs, "", 0)))
+	d := p.newDrawing(opts.withDefaults())
+	img, err := d.draw(context.Background())
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if strings.Contains(d.opts.Title, missing) || d.opts.Title == "" {
+		t.Errorf("expected the rune replaced, got %q", d.opts.Title)
+	}
+
+	if opts.Tags[0] != missing {
+		t.Errorf("expected the tags of the caller intact, got %q", opts.Tags)
+	}
+
+	box := d.layout.Title
+	title := image.Rect(int(box.X), int(box.Y), int(box.X+box.W), int(box.Y+box.H))
+
+	if !hasInk(img, title) {
+		t.Error("expected a replacement glyph drawn")
+	}
+
+	if !strings.Contains(logs.String(), "No glyph for") {
+		t.Errorf("expected the missing rune logged, got %q", logs.String())
+	}
+}
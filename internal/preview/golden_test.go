@@ -0,0 +1,111 @@
+package preview
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"flag"
+	"image"
+	"image/png"
+	"os"
+	"testing"
+)
+
+var updateGolden = flag.Bool("update", false, "update the golden images in testdata")
+
+// meanDiff returns the mean absolute difference of the RGB channels of the images of the same size, 0-255.
+func meanDiff(a, b image.Image) float64 {
+	var sum uint64
+
+	bounds := a.Bounds()
+
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			ar, ag, ab, _ := a.At(x, y).RGBA()
+			br, bg, bb, _ := b.At(x, y).RGBA()
+
+			for _, d := range [][2]uint32{{ar, br}, {ag, bg}, {ab, bb}} {
+				if d[0] > d[1] {
+					sum += uint64(d[0]-d[1]) >> 8
+				} else {
+					sum += uint64(d[1]-d[0]) >> 8
+				}
+			}
+		}
+	}
+
+	return float64(sum) / float64(bounds.Dx()*bounds.Dy()*3)
+}
+
+// stripesDataURL returns a PNG data URL of the image of the size with red, green and blue vertical stripes.
+func stripesDataURL(t *testing.T, w, h int) string {
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	stripes := [][]byte{{0xE0, 0x40, 0x40, 0xFF}, {0x40, 0xE0, 0x40, 0xFF}, {0x40, 0x40, 0xE0, 0xFF}}
+
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			copy(img.Pix[img.PixOffset(x, y):], stripes[x*len(stripes)/w])
+		}
+	}
+
+	var buf bytes.Buffer
+
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+
+	return "data:image/png;base64," + base64.StdEncoding.EncodeToString(buf.Bytes())
+}
+
+func TestDrawGoldenCentreCrop(t *testing.T) {
+	const golden = "testdata/centre-crop.png"
+
+	opts := testOptions()
+	opts.CanvasW = 600
+	opts.CanvasH = 315
+	opts.Bg = stripesDataURL(t, 1200, 315)
+	opts.RequireBg = true
+	opts.CropMode = CropCentre
+	opts.Title = "Golden"
+
+	img, err := New().Draw(context.Background(), opts)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if *updateGolden {
+		var buf bytes.Buffer
+
+		if err := png.Encode(&buf, img); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := os.WriteFile(golden, buf.Bytes(), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	f, err := os.Open(golden)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer f.Close()
+
+	want, err := png.Decode(f)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if want.Bounds() != img.Bounds() {
+		t.Fatalf("expected the golden image size %v, got %v", want.Bounds(), img.Bounds())
+	}
+
+	// the resampling differs slightly between the vips versions
+	if diff := meanDiff(img, want); diff > 2 {
+		t.Errorf("expected the image to match the golden one, got the mean difference of %.2f", diff)
+	}
+}
@@ -0,0 +1,131 @@
+package preview
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/fogleman/gg"
+)
+
+const (
+	linearGradientPrefix = "gradient:"
+	radialGradientPrefix = "radial:"
+)
+
+// isGradient reports whether the background is a gradient definition rather than an image or a HEX-color.
+func isGradient(bg string) bool {
+	return strings.HasPrefix(bg, linearGradientPrefix) || strings.HasPrefix(bg, radialGradientPrefix)
+}
+
+// parseGradient parses either a linear or a radial gradient definition.
+func parseGradient(bg string, w, h float64) (gg.Gradient, error) {
+	if strings.HasPrefix(bg, radialGradientPrefix) {
+		return parseRadialGradient(bg, w, h)
+	}
+
+	return parseLinearGradient(bg, w, h)
+}
+
+// parseLinearGradient parses a gradient definition like gradient:45,#FF0000,#0000FF into a gradient
+// covering a w*h canvas. The angle follows the CSS convention: 0 goes to the top, 90 to the right.
+// Color stops are distributed evenly.
+func parseLinearGradient(bg string, w, h float64) (gg.Gradient, error) {
+	parts := strings.Split(strings.TrimPrefix(bg, linearGradientPrefix), ",")
+
+	if len(parts) < 3 {
+		return nil, fmt.Errorf("malformed gradient %q: an angle and at least two color stops are expected", bg)
+	}
+
+	angle, err := strconv.ParseFloat(strings.TrimSpace(parts[0]), 64)
+
+	if err != nil {
+		return nil, fmt.Errorf("malformed gradient %q: could not parse the angle: %w", bg, err)
+	}
+
+	// the gradient line goes through the center and its length makes the corners get the edge stops exactly
+	rad := angle * math.Pi / 180
+	dx, dy := math.Sin(rad), -math.Cos(rad)
+	length := math.Abs(w*dx) + math.Abs(h*dy)
+	grad := gg.NewLinearGradient(
+		w/2-dx*length/2, h/2-dy*length/2,
+		w/2+dx*length/2, h/2+dy*length/2,
+	)
+
+	if err := addColorStops(grad, parts[1:]); err != nil {
+		return nil, fmt.Errorf("malformed gradient %q: %w", bg, err)
+	}
+
+	return grad, nil
+}
+
+// parseRadialGradient parses a gradient definition like radial:#FFFFFF,#000000 or radial:0.25,0.5,#FFFFFF,#000000
+// into a gradient covering a w*h canvas. The optional center is in normalized 0-1 coordinates, the canvas center
+// by default. The radius reaches the farthest corner, so it always gets the outer stop.
+func parseRadialGradient(bg string, w, h float64) (gg.Gradient, error) {
+	parts := strings.Split(strings.TrimPrefix(bg, radialGradientPrefix), ",")
+	cx, cy := 0.5, 0.5
+
+	if len(parts) > 0 && !strings.HasPrefix(strings.TrimSpace(parts[0]), "#") {
+		if len(parts) < 2 {
+			return nil, fmt.Errorf("malformed gradient %q: both center coordinates are expected", bg)
+		}
+
+		var err error
+
+		if cx, err = parseUnit(parts[0]); err != nil {
+			return nil, fmt.Errorf("malformed gradient %q: could not parse the center x: %w", bg, err)
+		}
+
+		if cy, err = parseUnit(parts[1]); err != nil {
+			return nil, fmt.Errorf("malformed gradient %q: could not parse the center y: %w", bg, err)
+		}
+
+		parts = parts[2:]
+	}
+
+	if len(parts) < 2 {
+		return nil, fmt.Errorf("malformed gradient %q: at least two color stops are expected", bg)
+	}
+
+	x, y := cx*w, cy*h
+	r := math.Hypot(math.Max(x, w-x), math.Max(y, h-y))
+	grad := gg.NewRadialGradient(x, y, 0, x, y, r)
+
+	if err := addColorStops(grad, parts); err != nil {
+		return nil, fmt.Errorf("malformed gradient %q: %w", bg, err)
+	}
+
+	return grad, nil
+}
+
+// parseUnit parses a float in the 0-1 range.
+func parseUnit(s string) (float64, error) {
+	v, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+
+	if err != nil {
+		return 0, err
+	}
+
+	if v < 0 || v > 1 {
+		return 0, fmt.Errorf("%v is out of the 0-1 range", v)
+	}
+
+	return v, nil
+}
+
+// addColorStops adds HEX-color stops evenly distributed along the gradient.
+func addColorStops(grad gg.Gradient, stops []string) error {
+	for i, stop := range stops {
+		c, err := parseHexColor(strings.TrimSpace(stop))
+
+		if err != nil {
+			return err
+		}
+
+		grad.AddColorStop(float64(i)/float64(len(stops)-1), c)
+	}
+
+	return nil
+}
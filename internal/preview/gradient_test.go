@@ -0,0 +1,129 @@
+package preview
+
+import (
+	"context"
+	"testing"
+)
+
+func TestDrawLinearGradient(t *testing.T) {
+	opts := testOptions()
+	opts.Bg = "gradient:135,#FF0000,#0000FF"
+
+	img, err := New().Draw(context.Background(), opts)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	near := func(actual uint32, expected uint32) bool {
+		return actual+8 >= expected && actual <= expected+8
+	}
+
+	r, g, b, _ := img.At(0, 0).RGBA()
+
+	if !near(r>>8, 0xFF) || !near(g>>8, 0) || !near(b>>8, 0) {
+		t.Errorf("expected red at the top left corner, got: %d %d %d", r>>8, g>>8, b>>8)
+	}
+
+	r, g, b, _ = img.At(opts.CanvasW-1, opts.CanvasH-1).RGBA()
+
+	if !near(r>>8, 0) || !near(g>>8, 0) || !near(b>>8, 0xFF) {
+		t.Errorf("expected blue at the bottom right corner, got: %d %d %d", r>>8, g>>8, b>>8)
+	}
+}
+
+func TestDrawLinearGradient_Stops(t *testing.T) {
+	opts := testOptions()
+	opts.Bg = "gradient:90,#FF0000,#00FF00,#0000FF"
+	opts.Title = " "
+
+	img, err := New().Draw(context.Background(), opts)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the middle stop lands on the vertical center line
+	r, g, b, _ := img.At(opts.CanvasW/2, 0).RGBA()
+
+	if r>>8 > 8 || g>>8 < 0xF7 || b>>8 > 8 {
+		t.Errorf("expected green in the middle, got: %d %d %d", r>>8, g>>8, b>>8)
+	}
+}
+
+func TestDrawLinearGradient_Malformed(t *testing.T) {
+	for _, bg := range []string{
+		"gradient:",
+		"gradient:45,#FF0000",
+		"gradient:diagonal,#FF0000,#0000FF",
+		"gradient:45,#FF0000,blue",
+	} {
+		opts := testOptions()
+		opts.Bg = bg
+
+		if _, err := New().Draw(context.Background(), opts); err == nil {
+			t.Errorf("expected an error for %q", bg)
+		}
+	}
+}
+
+func TestDrawRadialGradient(t *testing.T) {
+	testCases := []struct {
+		name    string
+		bg      string
+		center  [2]int
+		corners [][2]int
+	}{{
+		name:    "centered",
+		bg:      "radial:#FFFFFF,#000000",
+		center:  [2]int{600, 315},
+		corners: [][2]int{{0, 0}, {1199, 0}, {0, 629}, {1199, 629}},
+	}, {
+		name:    "offset",
+		bg:      "radial:0.75,0.25,#FFFFFF,#000000",
+		center:  [2]int{900, 157},
+		corners: [][2]int{{0, 629}},
+	}}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := testOptions()
+			opts.Bg = tt.bg
+			opts.Title = " "
+
+			img, err := New().Draw(context.Background(), opts)
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if r, _, _, _ := img.At(tt.center[0], tt.center[1]).RGBA(); r>>8 < 0xF7 {
+				t.Errorf("expected the inner stop at the center, got: %d", r>>8)
+			}
+
+			// the farthest corners reach the outer stop
+			for _, c := range tt.corners {
+				if r, _, _, _ := img.At(c[0], c[1]).RGBA(); r>>8 > 8 {
+					t.Errorf("expected a corner close to the outer stop at %v, got: %d", c, r>>8)
+				}
+			}
+		})
+	}
+}
+
+func TestDrawRadialGradient_Malformed(t *testing.T) {
+	for _, bg := range []string{
+		"radial:",
+		"radial:#FFFFFF",
+		"radial:0.5,#FFFFFF,#000000",
+		"radial:1.5,0.5,#FFFFFF,#000000",
+		"radial:0.5,0.5,#FFFFFF,white",
+	} {
+		opts := testOptions()
+		opts.Bg = bg
+
+		if _, err := New().Draw(context.Background(), opts); err == nil {
+			t.Errorf("expected an error for %q", bg)
+		}
+	}
+}
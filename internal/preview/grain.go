@@ -0,0 +1,47 @@
+package preview
+
+import (
+	"image"
+	"math/rand"
+	"time"
+)
+
+// maxGrain is how far in levels the full strength grain shifts a pixel either way.
+const maxGrain = 32
+
+// drawGrain shifts every pixel of the canvas by the same random amount in all the channels,
+// so the noise is monochrome. The transparent pixels are left intact.
+func (p *drawing) drawGrain() {
+	if p.opts.Grain <= 0 {
+		return
+	}
+
+	seed := p.opts.Seed
+
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+
+	rng := rand.New(rand.NewSource(seed))
+	amp := p.opts.Grain * maxGrain
+	img := p.ctx.Image().(*image.RGBA)
+
+	for i := 0; i < len(img.Pix); i += 4 {
+		a := float64(img.Pix[i+3])
+
+		// the channels are alpha premultiplied, so the shift is scaled by the alpha
+		shift := (rng.Float64()*2 - 1) * amp * a / 255
+
+		for c := 0; c < 3; c++ {
+			v := float64(img.Pix[i+c]) + shift
+
+			if v < 0 {
+				v = 0
+			} else if v > a {
+				v = a
+			}
+
+			img.Pix[i+c] = uint8(v + 0.5)
+		}
+	}
+}
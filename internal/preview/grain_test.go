@@ -0,0 +1,38 @@
+package preview
+
+import (
+	"bytes"
+	"context"
+	"testing"
+)
+
+func TestDrawGrain(t *testing.T) {
+	encode := func(grain float64, seed int64) []byte {
+		opts := testOptions()
+		opts.Bg = "gradient:90,#336699,#6699CC"
+		opts.Grain = grain
+		opts.Seed = seed
+
+		buf, err := New().DrawPNG(context.Background(), opts)
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return buf
+	}
+
+	seeded := encode(0.2, 42)
+
+	if !bytes.Equal(seeded, encode(0.2, 42)) {
+		t.Error("expected the same grain for the same seed")
+	}
+
+	if bytes.Equal(seeded, encode(0.2, 43)) {
+		t.Error("expected a different grain for a different seed")
+	}
+
+	if bytes.Equal(seeded, encode(0, 42)) {
+		t.Error("expected the grain to change the preview")
+	}
+}
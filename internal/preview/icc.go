@@ -0,0 +1,41 @@
+package preview
+
+import (
+	"fmt"
+
+	"github.com/davidbyttow/govips/v2/vips"
+)
+
+// toSRGB converts an image with an embedded ICC profile (Adobe RGB, Display P3, etc.) to sRGB,
+// so its colors are drawn as they are meant to look. Images without a profile are returned as they are.
+func (p *Preview) toSRGB(buf []byte) ([]byte, error) {
+	if isSVG(buf) {
+		return buf, nil
+	}
+
+	vipsImg, err := loadImage(buf)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer vipsImg.Close()
+
+	if !vipsImg.HasICCProfile() {
+		return buf, nil
+	}
+
+	p.logger.Printf("Converting an image to sRGB")
+
+	if err = vipsImg.TransformICCProfile(vips.SRGBIEC6196621ICCProfilePath); err != nil {
+		return nil, fmt.Errorf("could not transform the color profile: %w", err)
+	}
+
+	buf, _, err = vipsImg.Export(vips.NewDefaultExportParams())
+
+	if err != nil {
+		return nil, err
+	}
+
+	return buf, nil
+}
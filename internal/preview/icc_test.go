@@ -0,0 +1,153 @@
+package preview
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/jpeg"
+	"testing"
+)
+
+// adobeRGB are the D50 adapted colorants of Adobe RGB (1998) by rows.
+var adobeRGB = [3][3]float64{{0.6097, 0.3111, 0.0195}, {0.2053, 0.6257, 0.0609}, {0.1492, 0.0632, 0.7446}}
+
+// iccProfile returns a minimal matrix/TRC RGB display profile of the colorants and the gamma.
+func iccProfile(colorants [3][3]float64, gamma float64) []byte {
+	fixed := func(buf *bytes.Buffer, v float64) {
+		binary.Write(buf, binary.BigEndian, int32(v*65536+0.5))
+	}
+
+	xyz := func(x, y, z float64) []byte {
+		buf := bytes.NewBufferString("XYZ \x00\x00\x00\x00")
+		fixed(buf, x)
+		fixed(buf, y)
+		fixed(buf, z)
+
+		return buf.Bytes()
+	}
+
+	desc := bytes.NewBufferString("desc\x00\x00\x00\x00")
+	binary.Write(desc, binary.BigEndian, uint32(5))
+	desc.WriteString("Test\x00")
+	desc.Write(make([]byte, 4+4+2+1+67))
+
+	curve := bytes.NewBufferString("curv\x00\x00\x00\x00")
+	binary.Write(curve, binary.BigEndian, uint32(1))
+	binary.Write(curve, binary.BigEndian, uint16(gamma*256+0.5))
+
+	tags := []struct {
+		sig  string
+		data []byte
+	}{
+		{"desc", desc.Bytes()},
+		{"cprt", []byte("text\x00\x00\x00\x00None\x00")},
+		{"wtpt", xyz(0.9642, 1, 0.8249)},
+		{"rXYZ", xyz(colorants[0][0], colorants[0][1], colorants[0][2])},
+		{"gXYZ", xyz(colorants[1][0], colorants[1][1], colorants[1][2])},
+		{"bXYZ", xyz(colorants[2][0], colorants[2][1], colorants[2][2])},
+		{"rTRC", curve.Bytes()},
+		{"gTRC", curve.Bytes()},
+		{"bTRC", curve.Bytes()},
+	}
+
+	table := new(bytes.Buffer)
+	data := new(bytes.Buffer)
+	offset := 128 + 4 + 12*len(tags)
+
+	binary.Write(table, binary.BigEndian, uint32(len(tags)))
+
+	for _, tag := range tags {
+		for data.Len()%4 != 0 {
+			data.WriteByte(0)
+		}
+
+		table.WriteString(tag.sig)
+		binary.Write(table, binary.BigEndian, []uint32{uint32(offset + data.Len()), uint32(len(tag.data))})
+		data.Write(tag.data)
+	}
+
+	header := new(bytes.Buffer)
+	binary.Write(header, binary.BigEndian, []uint32{uint32(offset + data.Len()), 0, 0x02100000})
+	header.WriteString("mntrRGB XYZ ")
+	header.Write(make([]byte, 12))
+	header.WriteString("acsp")
+	header.Write(make([]byte, 4+4+4+4+8+4))
+	fixed(header, 0.9642)
+	fixed(header, 1)
+	fixed(header, 0.8249)
+	header.Write(make([]byte, 128-header.Len()))
+
+	return append(append(header.Bytes(), table.Bytes()...), data.Bytes()...)
+}
+
+// jpegWithProfile returns a JPEG of the image with the ICC profile embedded.
+func jpegWithProfile(t *testing.T, img image.Image, profile []byte) []byte {
+	buf := new(bytes.Buffer)
+
+	if err := jpeg.Encode(buf, img, &jpeg.Options{Quality: 100}); err != nil {
+		t.Fatal(err)
+	}
+
+	segment := append([]byte("ICC_PROFILE\x00\x01\x01"), profile...)
+	app2 := []byte{0xFF, 0xE2, 0, 0}
+	binary.BigEndian.PutUint16(app2[2:], uint16(len(segment)+2))
+
+	jpg := buf.Bytes()
+
+	return append(append(append([]byte{}, jpg[:2]...), append(app2, segment...)...), jpg[2:]...)
+}
+
+// saturation returns the spread between the strongest and the weakest channels of the color.
+func saturation(c color.Color) int {
+	r, g, b, _ := c.RGBA()
+	max, min := r, r
+
+	for _, v := range []uint32{g, b} {
+		if v > max {
+			max = v
+		}
+
+		if v < min {
+			min = v
+		}
+	}
+
+	return int(max>>8) - int(min>>8)
+}
+
+func TestDrawColorManage(t *testing.T) {
+	muted := image.NewRGBA(image.Rect(0, 0, 64, 64))
+	draw.Draw(muted, muted.Bounds(), image.NewUniform(color.RGBA{R: 100, G: 150, B: 100, A: 255}), image.Point{}, draw.Src)
+
+	opts := testOptions()
+	opts.Bg = "data:image/jpeg;base64," + base64.StdEncoding.EncodeToString(jpegWithProfile(t, muted, iccProfile(adobeRGB, 2.2)))
+	opts.RequireBg = true
+	opts.Title = " "
+
+	drawBg := func(colorManage bool) color.Color {
+		opts.ColorManage = colorManage
+		img, err := New().Draw(context.Background(), opts)
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// the margin is outside of the overlay
+		return img.At(2, 2)
+	}
+
+	unmanaged, managed := drawBg(false), drawBg(true)
+
+	if s := saturation(unmanaged); s < 45 || s > 55 {
+		t.Errorf("expected the color drawn as is without the color management, got %v", unmanaged)
+	}
+
+	// the muted Adobe RGB green is more saturated in sRGB
+	if saturation(managed) <= saturation(unmanaged)+10 {
+		t.Errorf("expected a more saturated color converted to sRGB, got %v drawn as %v", managed, unmanaged)
+	}
+}
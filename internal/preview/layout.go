@@ -0,0 +1,327 @@
+package preview
+
+import (
+	"fmt"
+	"math"
+)
+
+// Rect is a box on the canvas in px.
+type Rect struct {
+	X, Y, W, H float64
+}
+
+// LayoutInfo is where the elements of a preview land on the canvas. The zero Rect stands for an element
+// that is not drawn. The text boxes are as tall as the lines of the font with the last baseline at the bottom.
+type LayoutInfo struct {
+	// Avatar boxes by slot without the borders, the last one is the "+N" badge if the avatars don't fit
+	Avatars []Rect
+	// Author text box
+	Author Rect
+	// Meta text box right under the author
+	Meta Rect
+	// Title box: the title is wrapped to its width and takes its height
+	Title Rect
+	// Title font size, may be smaller than Options.TitleSize with AutoFitTitle
+	TitleSize float64
+	// Title wrapped into lines
+	TitleLines []string
+	// Subtitle box below the title
+	Subtitle Rect
+	// Subtitle wrapped and cut into lines
+	SubtitleLines []string
+	// Tag chip boxes in the order of the tags
+	Tags []Rect
+	// Logo image box
+	Logo Rect
+	// LabelL and LabelR text box
+	Label Rect
+}
+
+// Layout returns where the elements of a preview drawn with the Options land without fetching the images
+// and drawing. As the logo width is only known once the image is loaded, the logo is assumed to be square.
+func (p *Preview) Layout(opts Options) (LayoutInfo, error) {
+	opts, err := p.prepareOptions(opts)
+
+	if err != nil {
+		return LayoutInfo{}, err
+	}
+
+	d := p.newPooledDrawing(opts)
+
+	defer d.release()
+
+	logoW := 0
+
+	if opts.LogoURL != "" {
+		logoW = opts.LogoH
+	}
+
+	if err := d.computeLayout(logoW); err != nil {
+		return LayoutInfo{}, err
+	}
+
+	return *d.layout, nil
+}
+
+// prepareOptions fills the Options defaults, validates them and clamps the sizes to the canvas.
+func (p *Preview) prepareOptions(opts Options) (Options, error) {
+	opts = opts.withDefaults()
+
+	if err := opts.Validate(); err != nil {
+		return Options{}, err
+	}
+
+	if maxD := maxAvatarD(opts.CanvasW, opts.CanvasH, opts.Padding); opts.AvaD > maxD {
+		p.logger.Printf("Clamping the avatar diameter %dpx to %dpx", opts.AvaD, maxD)
+		opts.AvaD = maxD
+	}
+
+	return opts, nil
+}
+
+// computeLayout positions the elements for the logo image of the width (zero if there is none)
+// and fits the title size if AutoFitTitle is set. All the drawing methods take the positions from the layout.
+func (p *drawing) computeLayout(logoW int) error {
+	if err := p.replaceMissingTextGlyphs(); err != nil {
+		return err
+	}
+
+	l := &LayoutInfo{}
+	avaR := float64(p.opts.AvaD) / 2
+
+	for slot := 0; slot < p.avatarSlots(); slot++ {
+		x, y := p.avatarPosition(slot)
+		l.Avatars = append(l.Avatars, Rect{X: x - avaR, Y: y - avaR, W: avaR * 2, H: avaR * 2})
+	}
+
+	// the avatar row fits the author without an avatar
+	p.rowH = 0
+
+	if len(l.Avatars) > 0 {
+		p.rowH = float64(p.opts.AvaD)
+	}
+
+	if p.opts.Author != "" {
+		box, err := p.authorBox()
+
+		if err != nil {
+			return err
+		}
+
+		l.Author = box
+		p.rowH = math.Max(p.rowH, box.H)
+	}
+
+	if p.opts.Meta != "" {
+		box, err := p.metaBox()
+
+		if err != nil {
+			return err
+		}
+
+		l.Meta = box
+	}
+
+	// the title makes room for the tags above it
+	if len(p.opts.Tags) > 0 {
+		_, _, maxWidth, _ := p.titleRegion()
+		tags, err := p.tagBoxes(maxWidth)
+
+		if err != nil {
+			return err
+		}
+
+		l.Tags = tags
+		p.tagsH = p.tagsHeight(tags)
+	}
+
+	if p.opts.AutoFitTitle {
+		if err := p.fitTitle(); err != nil {
+			return err
+		}
+	}
+
+	titleX, titleY, maxWidth, err := p.titlePosition()
+
+	if err != nil {
+		return err
+	}
+
+	titleH, err := p.measureTitle(p.opts.TitleSize)
+
+	if err != nil {
+		return err
+	}
+
+	font, err := p.loadFont(textFontSource(p.opts.TitleFont, p.opts.TitleWeight), p.opts.TitleSize)
+
+	if err != nil {
+		return fmt.Errorf("could not load the title font: %w", err)
+	}
+
+	l.Title = Rect{X: titleX, Y: titleY, W: maxWidth, H: titleH}
+
+	for i := range l.Tags {
+		l.Tags[i].X += titleX
+		l.Tags[i].Y += titleY - p.tagsH
+	}
+
+	l.TitleSize = p.opts.TitleSize
+	l.TitleLines, p.titleCut = p.titleLines(maxWidth, p.titleStyle(font))
+
+	if p.opts.Subtitle != "" {
+		gap, err := p.subtitleGap(p.opts.TitleSize)
+
+		if err != nil {
+			return err
+		}
+
+		lines, h, err := p.subtitleLines()
+
+		if err != nil {
+			return err
+		}
+
+		l.Subtitle = Rect{X: titleX, Y: titleY + titleH + gap, W: maxWidth, H: h}
+		l.SubtitleLines = lines
+	}
+
+	logoRight, logoBottom, err := p.logoCorner()
+
+	if err != nil {
+		return err
+	}
+
+	if logoW > 0 {
+		l.Logo = Rect{
+			X: float64(p.opts.CanvasW) - p.opts.Padding - float64(logoW),
+			Y: float64(p.opts.CanvasH) - p.opts.Padding - float64(p.opts.LogoH),
+			W: float64(logoW),
+			H: float64(p.opts.LogoH),
+		}
+
+		if !logoRight {
+			l.Logo.X = p.opts.Padding
+		}
+
+		if !logoBottom {
+			l.Logo.Y = p.opts.Padding
+		}
+	}
+
+	// the label only makes room for the logo sharing its corner
+	if !logoRight || !logoBottom {
+		logoW = 0
+	}
+
+	if p.opts.LabelL != "" || p.opts.LabelR != "" {
+		box, err := p.labelBox(logoW)
+
+		if err != nil {
+			return err
+		}
+
+		l.Label = box
+	}
+
+	p.layout = l
+
+	return nil
+}
+
+// avatarPosition returns the center of the avatar in the slot, every next slot is shifted to the right
+// overlapping the previous one.
+func (p *drawing) avatarPosition(slot int) (x, y float64) {
+	offset := p.opts.Padding + float64(p.opts.AvaD)/2 + float64(p.opts.AvaBorderW)
+
+	return offset + float64(slot)*float64(p.opts.AvaD)*avatarStep, offset
+}
+
+// authorBox returns the author text box vertically centered on the avatar row after the whole stack of avatars,
+// at the top padding if there are no avatars, or against the right padding if AuthorPosition is right.
+func (p *drawing) authorBox() (Rect, error) {
+	font, err := p.loadFont(textFontSource(p.opts.AuthorFont, p.opts.AuthorWeight), p.opts.AuthorSize)
+
+	if err != nil {
+		return Rect{}, fmt.Errorf("could not load the author font: %w", err)
+	}
+
+	p.ctx.SetFontFace(font)
+
+	x := p.opts.Padding
+
+	if p.opts.AvaD > 0 {
+		x += float64(p.opts.AvaD) + p.opts.Padding/2
+	}
+
+	if slots := p.avatarSlots(); slots > 1 {
+		x += float64(slots-1) * float64(p.opts.AvaD) * avatarStep
+	}
+
+	w := p.measureString(p.opts.Author, textStyle{tracking: p.opts.AuthorTracking})
+	h := p.ctx.FontHeight()
+	y := p.opts.Padding + math.Max(float64(p.opts.AvaD), h)/2 - h/2
+
+	if p.opts.AuthorPosition == AlignRight {
+		x = float64(p.opts.CanvasW) - p.opts.Padding - w
+	}
+
+	return Rect{X: x, Y: y, W: w, H: h}, nil
+}
+
+// metaBox returns the meta text box aligned with the author right under the author baseline.
+func (p *drawing) metaBox() (Rect, error) {
+	author, err := p.authorBox()
+
+	if err != nil {
+		return Rect{}, err
+	}
+
+	font, err := p.loadFont(textFontSource(p.opts.AuthorFont, p.opts.AuthorWeight), p.opts.AuthorSize*metaScale)
+
+	if err != nil {
+		return Rect{}, fmt.Errorf("could not load the meta font: %w", err)
+	}
+
+	p.ctx.SetFontFace(font)
+
+	w := p.measureString(p.opts.Meta, textStyle{tracking: p.opts.AuthorTracking})
+
+	// the meta line leaves room for the author descent
+	descent := float64(font.Metrics().Descent) / 64
+
+	x := author.X
+
+	if p.opts.AuthorPosition == AlignRight {
+		x = float64(p.opts.CanvasW) - p.opts.Padding - w
+	}
+
+	return Rect{X: x, Y: author.Y + author.H + descent, W: w, H: p.ctx.FontHeight()}, nil
+}
+
+// labelBox returns the label text box in the bottom right corner to the left of the logo of the width (if any),
+// vertically centered on the logo row.
+func (p *drawing) labelBox(logoW int) (Rect, error) {
+	font, err := p.loadFont(FontSource{}, p.opts.LabelSize)
+
+	if err != nil {
+		return Rect{}, fmt.Errorf("could not load a font face: %w", err)
+	}
+
+	p.ctx.SetFontFace(font)
+
+	right := float64(p.opts.CanvasW) - p.opts.Padding
+	rowH := p.opts.LabelSize
+
+	if logoW > 0 {
+		right -= float64(logoW) + p.opts.Padding/2
+		rowH = float64(p.opts.LogoH)
+	}
+
+	// the parts are measured apart as they are drawn apart
+	lw, h := p.ctx.MeasureString(p.opts.LabelL)
+	rw, _ := p.ctx.MeasureString(p.opts.LabelR)
+	y := float64(p.opts.CanvasH) - p.opts.Padding - rowH/2 - h/2
+
+	return Rect{X: right - lw - rw, Y: y, W: lw + rw, H: h}, nil
+}
@@ -0,0 +1,390 @@
+package preview
+
+import (
+	"context"
+	"image"
+	"image/color"
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestLayoutMatchesDraw(t *testing.T) {
+	opts := testOptions()
+	opts.Title = "The quick brown fox jumps over the lazy dog"
+	opts.Author = "Jane Doe"
+	opts.AvaD = 64
+	opts.AvaFallbackColor = "#00FF00"
+	opts.LabelL = "vas3k"
+	opts.LabelR = ".club"
+
+	layout, err := New().Layout(opts)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	d := New().newDrawing(opts.withDefaults())
+	img, err := d.draw(context.Background())
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(*d.layout, layout) {
+		t.Errorf("expected Draw to use the same layout, got %+v, want %+v", *d.layout, layout)
+	}
+
+	if len(layout.Avatars) != 1 {
+		t.Fatalf("expected a single avatar slot, got %v", layout.Avatars)
+	}
+
+	// the bounding box of the fallback color is the avatar
+	var green image.Rectangle
+
+	for y := 0; y < opts.CanvasH; y++ {
+		for x := 0; x < opts.CanvasW; x++ {
+			if r, g, b, _ := img.At(x, y).RGBA(); g>>8 > 200 && r>>8 < 50 && b>>8 < 50 {
+				green = green.Union(image.Rect(x, y, x+1, y+1))
+			}
+		}
+	}
+
+	ava := layout.Avatars[0]
+	want := image.Rect(int(ava.X), int(ava.Y), int(math.Ceil(ava.X+ava.W)), int(math.Ceil(ava.Y+ava.H)))
+
+	if green != want {
+		t.Errorf("expected the avatar drawn at %v, got %v", want, green)
+	}
+
+	if len(layout.TitleLines) != 2 || layout.TitleSize != opts.TitleSize {
+		t.Errorf("expected the title in 2 lines of the size %v, got %q of %v", opts.TitleSize, layout.TitleLines, layout.TitleSize)
+	}
+
+	for name, box := range map[string]Rect{"author": layout.Author, "title": layout.Title, "label": layout.Label} {
+		rect := image.Rect(int(box.X), int(box.Y), int(box.X+box.W), int(box.Y+box.H))
+
+		if box.W <= 0 || box.H <= 0 || !hasInk(img, rect) {
+			t.Errorf("expected the %s drawn within %+v", name, box)
+		}
+	}
+
+	if layout.Logo != (Rect{}) {
+		t.Errorf("expected no logo box, got %+v", layout.Logo)
+	}
+}
+
+func TestLayoutLogo(t *testing.T) {
+	opts := testOptions()
+	opts.LogoURL = "logo.png"
+	opts.LogoPosition = LogoTopLeft
+	opts.LabelR = "label"
+
+	layout, err := New().Layout(opts)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if want := (Rect{X: DefaultPadding, Y: DefaultPadding, W: 48, H: 48}); layout.Logo != want {
+		t.Errorf("expected a square logo in the top left corner %+v, got %+v", want, layout.Logo)
+	}
+
+	// the label doesn't make room for a logo in the other corner
+	if right := layout.Label.X + layout.Label.W; right != float64(opts.CanvasW)-DefaultPadding {
+		t.Errorf("expected the label at the right edge, got %v", right)
+	}
+
+	opts.LogoPosition = "middle"
+
+	if _, err := New().Layout(opts); err == nil {
+		t.Error("expected an unknown logo position error")
+	}
+}
+
+func TestLayoutPadding(t *testing.T) {
+	opts := testOptions()
+	opts.Author = "Jane Doe"
+	opts.AvaD = 64
+	opts.LogoURL = "logo.png"
+
+	tight, err := New().Layout(opts)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	opts.Padding = DefaultPadding * 2
+	airy, err := New().Layout(opts)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	shifts := map[string][2]float64{
+		"avatar": {airy.Avatars[0].X - tight.Avatars[0].X, airy.Avatars[0].Y - tight.Avatars[0].Y},
+		"author": {airy.Author.X - tight.Author.X, airy.Author.Y - tight.Author.Y},
+		"title":  {airy.Title.X - tight.Title.X, airy.Title.Y - tight.Title.Y},
+		"logo":   {tight.Logo.X - airy.Logo.X, tight.Logo.Y - airy.Logo.Y},
+	}
+
+	// the author is half a padding further from the avatar, and the title is a padding further from the avatar row
+	want := map[string][2]float64{
+		"avatar": {DefaultPadding, DefaultPadding},
+		"author": {DefaultPadding * 1.5, DefaultPadding},
+		"title":  {DefaultPadding, DefaultPadding * 2},
+		"logo":   {DefaultPadding, DefaultPadding},
+	}
+
+	if !reflect.DeepEqual(shifts, want) {
+		t.Errorf("expected the elements to shift by %v, got %v", want, shifts)
+	}
+
+	if airy.Title.W >= tight.Title.W {
+		t.Errorf("expected a narrower title, got %v and %v", airy.Title.W, tight.Title.W)
+	}
+}
+
+func TestDrawMargin(t *testing.T) {
+	opts := testOptions()
+	opts.Bg = "#FFFFFF"
+	opts.Opacity = 1
+	opts.Margin = DefaultMargin * 2
+	opts.Title = " "
+
+	img, err := New().Draw(context.Background(), opts)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the overlay starts at the doubled margin
+	if r, _, _, _ := img.At(int(DefaultMargin)+2, int(DefaultMargin)+2).RGBA(); r>>8 != 0xFF {
+		t.Errorf("expected no overlay within the margin, got %d", r>>8)
+	}
+
+	if r, _, _, _ := img.At(int(opts.Margin)+2, int(opts.Margin)+2).RGBA(); r>>8 != 0 {
+		t.Errorf("expected the overlay past the margin, got %d", r>>8)
+	}
+}
+
+func TestDrawMeta(t *testing.T) {
+	opts := testOptions()
+	opts.Author = "Jane Doe"
+	opts.AvaD = 64
+	opts.AvaFallbackColor = "#00FF00"
+	opts.Meta = "5 min read · Jan 2024"
+
+	layout, err := New().Layout(opts)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	meta, author, ava := layout.Meta, layout.Author, layout.Avatars[0]
+
+	if meta.X != author.X || meta.Y < author.Y+author.H {
+		t.Errorf("expected the meta %+v under the author %+v", meta, author)
+	}
+
+	if meta.X < ava.X+ava.W {
+		t.Errorf("expected the meta %+v to the right of the avatar %+v", meta, ava)
+	}
+
+	img, err := New().Draw(context.Background(), opts)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !hasInk(img, image.Rect(int(meta.X), int(meta.Y), int(meta.X+meta.W), int(meta.Y+meta.H))) {
+		t.Errorf("expected the meta drawn within %+v", meta)
+	}
+
+	// the meta is spaced like the author
+	opts.AuthorTracking = 4
+
+	tracked, err := New().Layout(opts)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if tracked.Meta.W <= meta.W {
+		t.Errorf("expected the tracking to widen the meta, got %v, %v without it", tracked.Meta.W, meta.W)
+	}
+
+	if img, err = New().Draw(context.Background(), opts); err != nil {
+		t.Fatal(err)
+	}
+
+	meta = tracked.Meta
+	right := image.Rect(int(meta.X+meta.W)-10, int(meta.Y), int(meta.X+meta.W), int(meta.Y+meta.H))
+
+	if !hasInk(img, right) {
+		t.Errorf("expected the tracked meta drawn up to the end of %+v", meta)
+	}
+
+	opts.Meta = ""
+
+	if layout, err = New().Layout(opts); err != nil {
+		t.Fatal(err)
+	}
+
+	if layout.Meta != (Rect{}) {
+		t.Errorf("expected no meta box, got %+v", layout.Meta)
+	}
+}
+
+func TestLayoutAvatarRow(t *testing.T) {
+	avatar := pngDataURL(t, 64, 64, color.RGBA{R: 255, A: 255})
+
+	testCases := []struct {
+		name   string
+		author string
+		avaURL string
+		// whether there is the author and the avatar
+		hasAuthor, hasAvatar bool
+	}{
+		{name: "both", author: "Jane Doe", avaURL: avatar, hasAuthor: true, hasAvatar: true},
+		{name: "author only", author: "Jane Doe", hasAuthor: true},
+		{name: "avatar only", avaURL: avatar, hasAvatar: true},
+		{name: "none"},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := testOptions()
+			opts.Author = tt.author
+			opts.AvaURL = tt.avaURL
+
+			layout, err := New().Layout(opts)
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if hasAvatar := len(layout.Avatars) > 0; hasAvatar != tt.hasAvatar {
+				t.Errorf("expected an avatar: %v, got %+v", tt.hasAvatar, layout.Avatars)
+			}
+
+			if hasAuthor := layout.Author != (Rect{}); hasAuthor != tt.hasAuthor {
+				t.Errorf("expected an author: %v, got %+v", tt.hasAuthor, layout.Author)
+			}
+
+			// the title starts a padding below the lowest element of the avatar row, or at the padding without it
+			rowBottom := 0.0
+
+			if tt.hasAvatar {
+				rowBottom = layout.Avatars[0].Y + layout.Avatars[0].H
+			}
+
+			if tt.hasAuthor {
+				rowBottom = math.Max(rowBottom, layout.Author.Y+layout.Author.H)
+				// the author without an avatar takes its place
+				if !tt.hasAvatar && (layout.Author.X != DefaultPadding || layout.Author.Y != DefaultPadding) {
+					t.Errorf("expected the author at the top left padding, got %+v", layout.Author)
+				}
+			}
+
+			titleY := DefaultPadding
+
+			if rowBottom > 0 {
+				titleY = rowBottom + DefaultPadding
+			}
+
+			if math.Abs(layout.Title.Y-titleY) > 0.5 {
+				t.Errorf("expected the title at %v, got %v", titleY, layout.Title.Y)
+			}
+		})
+	}
+}
+
+func TestDrawAuthorRight(t *testing.T) {
+	opts := testOptions()
+	opts.Author = "Jane Doe"
+	opts.AvaD = 64
+	opts.AvaFallbackColor = "#00FF00"
+	opts.Meta = "5 min read"
+	opts.AuthorPosition = AlignRight
+
+	d := New().newDrawing(opts.withDefaults())
+	img, err := d.draw(context.Background())
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	author, ava := d.layout.Author, d.layout.Avatars[0]
+	right := float64(opts.CanvasW) - d.opts.Padding
+
+	if ava.X != d.opts.Padding {
+		t.Errorf("expected the avatar to stay at the top left, got %+v", ava)
+	}
+
+	if meta := d.layout.Meta; math.Abs(meta.X+meta.W-right) > 0.5 {
+		t.Errorf("expected the meta %+v to follow the author", meta)
+	}
+
+	// the rightmost author pixel within the glyph side bearing of the right padding
+	inkRight := 0
+
+	for x := int(author.X); x < opts.CanvasW; x++ {
+		if hasInk(img, image.Rect(x, int(author.Y), x+1, int(author.Y+author.H))) {
+			inkRight = x
+		}
+	}
+
+	if inkRight >= int(right) || inkRight < int(right)-8 {
+		t.Errorf("expected the author to end at the right padding %g, got %d", right, inkRight)
+	}
+}
+
+func TestRelativeSizes(t *testing.T) {
+	layoutAt := func(w, h int) LayoutInfo {
+		opts := testOptions()
+		opts.CanvasW, opts.CanvasH = w, h
+		opts.Author = "Author"
+		opts.TitleSizePct = 10
+		opts.AuthorSizePct = 5
+		opts.LabelSizePct = 4
+		opts.AvaDPct = 12
+
+		resolved := opts.withDefaults()
+
+		if want := float64(h) / 10; resolved.TitleSize != want {
+			t.Errorf("expected the title size %v at %dpx high, got %v", want, h, resolved.TitleSize)
+		}
+
+		if want := float64(h) / 20; resolved.AuthorSize != want {
+			t.Errorf("expected the author size %v at %dpx high, got %v", want, h, resolved.AuthorSize)
+		}
+
+		if want := float64(h) / 25; resolved.LabelSize != want {
+			t.Errorf("expected the label size %v at %dpx high, got %v", want, h, resolved.LabelSize)
+		}
+
+		layout, err := New().Layout(opts)
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return layout
+	}
+
+	large, small := layoutAt(1200, 630), layoutAt(600, 315)
+
+	if large.TitleSize != 2*small.TitleSize {
+		t.Errorf("expected the title size to scale with the canvas, got %v and %v", large.TitleSize, small.TitleSize)
+	}
+
+	if len(large.Avatars) != 1 || len(small.Avatars) != 1 {
+		t.Fatalf("expected a single avatar slot, got %v and %v", large.Avatars, small.Avatars)
+	}
+
+	// 12% of 630 and 315 rounded
+	if large.Avatars[0].W != 76 || small.Avatars[0].W != 38 {
+		t.Errorf("expected the avatar diameters 76 and 38, got %v and %v", large.Avatars[0].W, small.Avatars[0].W)
+	}
+}
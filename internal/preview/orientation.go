@@ -0,0 +1,87 @@
+package preview
+
+import (
+	"bytes"
+	"encoding/binary"
+)
+
+const (
+	jpegSOI            = 0xD8
+	jpegSOS            = 0xDA
+	jpegAPP1           = 0xE1
+	orientationTag     = 0x0112
+	exifHeader         = "Exif\x00\x00"
+	ifdEntrySize       = 12
+	orientationUpright = 1
+	maxOrientation     = 8
+)
+
+// exifOrientation returns the EXIF orientation (1-8) of a JPEG image, 1 (upright) if it has none.
+// Only the headers are read, so the images that are upright already can skip vips.
+func exifOrientation(buf []byte) int {
+	if len(buf) < 4 || buf[0] != 0xFF || buf[1] != jpegSOI {
+		return orientationUpright
+	}
+
+	// the segments up to the image data: a marker and a big-endian size including itself
+	for i := 2; i+4 <= len(buf) && buf[i] == 0xFF; {
+		marker := buf[i+1]
+		end := i + 2 + int(binary.BigEndian.Uint16(buf[i+2:]))
+
+		if marker == jpegSOS || end > len(buf) {
+			break
+		}
+
+		if segment := buf[i+4 : end]; marker == jpegAPP1 && bytes.HasPrefix(segment, []byte(exifHeader)) {
+			return tiffOrientation(segment[len(exifHeader):])
+		}
+
+		i = end
+	}
+
+	return orientationUpright
+}
+
+// tiffOrientation returns the orientation tag value of the first IFD of the TIFF structure of EXIF.
+func tiffOrientation(tiff []byte) int {
+	if len(tiff) < 8 {
+		return orientationUpright
+	}
+
+	var order binary.ByteOrder
+
+	switch string(tiff[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return orientationUpright
+	}
+
+	ifd := int(order.Uint32(tiff[4:]))
+
+	if ifd < 0 || ifd+2 > len(tiff) {
+		return orientationUpright
+	}
+
+	for k := 0; k < int(order.Uint16(tiff[ifd:])); k++ {
+		entry := ifd + 2 + k*ifdEntrySize
+
+		if entry+ifdEntrySize > len(tiff) {
+			break
+		}
+
+		if order.Uint16(tiff[entry:]) != orientationTag {
+			continue
+		}
+
+		if o := int(order.Uint16(tiff[entry+8:])); o >= orientationUpright && o <= maxOrientation {
+			return o
+		}
+
+		break
+	}
+
+	return orientationUpright
+}
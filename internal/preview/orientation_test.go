@@ -0,0 +1,117 @@
+package preview
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/jpeg"
+	"testing"
+
+	"github.com/davidbyttow/govips/v2/vips"
+)
+
+// jpegWithOrientation returns a JPEG of the image with an EXIF segment holding the orientation.
+func jpegWithOrientation(t *testing.T, img image.Image, order binary.ByteOrder, orientation uint16) []byte {
+	buf := new(bytes.Buffer)
+
+	if err := jpeg.Encode(buf, img, &jpeg.Options{Quality: 100}); err != nil {
+		t.Fatal(err)
+	}
+
+	tiff := new(bytes.Buffer)
+
+	if order == binary.LittleEndian {
+		tiff.WriteString("II")
+	} else {
+		tiff.WriteString("MM")
+	}
+
+	// the header, an IFD of a single SHORT entry and no next IFD
+	binary.Write(tiff, order, []uint16{42})
+	binary.Write(tiff, order, []uint32{8})
+	binary.Write(tiff, order, []uint16{1, orientationTag, 3})
+	binary.Write(tiff, order, []uint32{1})
+	binary.Write(tiff, order, []uint16{orientation, 0})
+	binary.Write(tiff, order, []uint32{0})
+
+	segment := append([]byte(exifHeader), tiff.Bytes()...)
+	app1 := []byte{0xFF, jpegAPP1, 0, 0}
+	binary.BigEndian.PutUint16(app1[2:], uint16(len(segment)+2))
+
+	jpg := buf.Bytes()
+
+	return append(append(append([]byte{}, jpg[:2]...), append(app1, segment...)...), jpg[2:]...)
+}
+
+// halves returns an image of the size with the left half red and the right half blue.
+func halves(w, h int) image.Image {
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	draw.Draw(img, image.Rect(0, 0, w/2, h), image.NewUniform(color.RGBA{R: 255, A: 255}), image.Point{}, draw.Src)
+	draw.Draw(img, image.Rect(w/2, 0, w, h), image.NewUniform(color.RGBA{B: 255, A: 255}), image.Point{}, draw.Src)
+
+	return img
+}
+
+func TestExifOrientation(t *testing.T) {
+	plain := new(bytes.Buffer)
+
+	if err := jpeg.Encode(plain, halves(16, 8), nil); err != nil {
+		t.Fatal(err)
+	}
+
+	testCases := []struct {
+		name string
+		buf  []byte
+		want int
+	}{
+		{name: "no EXIF", buf: plain.Bytes(), want: 1},
+		{name: "big-endian", buf: jpegWithOrientation(t, halves(16, 8), binary.BigEndian, 6), want: 6},
+		{name: "little-endian", buf: jpegWithOrientation(t, halves(16, 8), binary.LittleEndian, 3), want: 3},
+		{name: "out of range", buf: jpegWithOrientation(t, halves(16, 8), binary.BigEndian, 9), want: 1},
+		{name: "not a JPEG", buf: []byte("\x89PNG\r\n\x1a\n"), want: 1},
+		{name: "truncated", buf: jpegWithOrientation(t, halves(16, 8), binary.BigEndian, 6)[:12], want: 1},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := exifOrientation(tt.buf); got != tt.want {
+				t.Errorf("expected %d, got %d", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestResizeAutoRotate(t *testing.T) {
+	// rotated by 90 degrees clockwise to display, so the left half goes on top
+	buf := jpegWithOrientation(t, halves(16, 8), binary.BigEndian, 6)
+
+	if w, h, err := imageSize(buf); err != nil || w != 8 || h != 16 {
+		t.Errorf("expected the upright size of 8x16, got %dx%d, %v", w, h, err)
+	}
+
+	resized, err := New().resize(buf, 8, 16, 0, vips.InterestingCentre)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(resized))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if size := img.Bounds().Size(); size.X != 8 || size.Y != 16 {
+		t.Fatalf("expected 8x16, got %v", size)
+	}
+
+	if r, _, b, _ := img.At(4, 2).RGBA(); r>>8 < 0xC0 || b>>8 > 0x40 {
+		t.Errorf("expected red on top, got %d %d", r>>8, b>>8)
+	}
+
+	if r, _, b, _ := img.At(4, 13).RGBA(); r>>8 > 0x40 || b>>8 < 0xC0 {
+		t.Errorf("expected blue at the bottom, got %d %d", r>>8, b>>8)
+	}
+}
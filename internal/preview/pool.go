@@ -0,0 +1,109 @@
+package preview
+
+import (
+	"image"
+	"image/png"
+	"sync"
+
+	"github.com/fogleman/gg"
+)
+
+// maxPooledSizes limits the canvas sizes pooled, so the arbitrary sizes of the requests don't grow the pool
+// without bound. The first sizes drawn are pooled, which are usually the default and the few sizes in use.
+const maxPooledSizes = 8
+
+// canvasPool keeps the canvases that don't outlive their drawing (e.g. of the encoded previews)
+// for reuse by the next drawings of the same size, so they don't allocate a canvas every time.
+// The canvases of the sizes beyond maxPooledSizes are allocated and dropped.
+type canvasPool struct {
+	mu sync.Mutex
+	// the pools of the canvases by their size
+	pools map[image.Point]*sync.Pool
+}
+
+// defaultCanvases is the canvas pool shared by all the previews.
+var defaultCanvases = &canvasPool{}
+
+// get returns a transparent canvas of the size.
+func (c *canvasPool) get(w, h int) *image.RGBA {
+	pool := c.pool(w, h)
+
+	if pool == nil {
+		return image.NewRGBA(image.Rect(0, 0, w, h))
+	}
+
+	if canvas, ok := pool.Get().(*image.RGBA); ok {
+		// a reused canvas starts transparent like a new one, so nothing is left of the previous drawing
+		for i := range canvas.Pix {
+			canvas.Pix[i] = 0
+		}
+
+		return canvas
+	}
+
+	return image.NewRGBA(image.Rect(0, 0, w, h))
+}
+
+// put makes the canvas reusable, it must not be used after that.
+func (c *canvasPool) put(canvas *image.RGBA) {
+	if pool := c.pool(canvas.Rect.Dx(), canvas.Rect.Dy()); pool != nil {
+		pool.Put(canvas)
+	}
+}
+
+// pool returns the pool of the canvases of the size, or nil if the size isn't pooled.
+func (c *canvasPool) pool(w, h int) *sync.Pool {
+	size := image.Pt(w, h)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if pool, exists := c.pools[size]; exists {
+		return pool
+	}
+
+	if len(c.pools) >= maxPooledSizes {
+		return nil
+	}
+
+	if c.pools == nil {
+		c.pools = make(map[image.Point]*sync.Pool)
+	}
+
+	pool := &sync.Pool{}
+	c.pools[size] = pool
+
+	return pool
+}
+
+// scratchContext returns a context of the canvas size for drawing off-screen and a func releasing it.
+// A fresh context is made around a pooled canvas, so no drawing state is shared with its previous use.
+func (p *drawing) scratchContext() (*gg.Context, func()) {
+	w, h := p.ctx.Width(), p.ctx.Height()
+
+	if p.canvases == nil {
+		return gg.NewContext(w, h), func() {}
+	}
+
+	canvas := p.canvases.get(w, h)
+
+	return gg.NewContextForRGBA(canvas), func() { p.canvases.put(canvas) }
+}
+
+// pngBufferPool reuses the compression buffers of the PNG encoder between the encodes.
+type pngBufferPool struct {
+	pool sync.Pool
+}
+
+func (b *pngBufferPool) Get() *png.EncoderBuffer {
+	buf, _ := b.pool.Get().(*png.EncoderBuffer)
+
+	return buf
+}
+
+func (b *pngBufferPool) Put(buf *png.EncoderBuffer) {
+	b.pool.Put(buf)
+}
+
+// pngEncoder encodes the intermediate PNG images passed to vips.
+var pngEncoder = &png.Encoder{BufferPool: &pngBufferPool{}}
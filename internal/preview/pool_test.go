@@ -0,0 +1,90 @@
+package preview
+
+import (
+	"bytes"
+	"context"
+	"image/png"
+	"testing"
+)
+
+func TestCanvasPoolResets(t *testing.T) {
+	p := New(func(p *Preview) { p.canvases = &canvasPool{} })
+
+	opts := testOptions()
+	opts.Bg = "#FFFFFF"
+
+	if _, err := p.DrawPNG(context.Background(), opts); err != nil {
+		t.Fatal(err)
+	}
+
+	// the next drawing of the same size reuses the canvas of the white preview
+	opts.Bg = ""
+	opts.Opacity = 0.6
+	opts.Transparent = true
+
+	buf, err := p.DrawPNG(context.Background(), opts)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	img, err := png.Decode(bytes.NewReader(buf))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, _, _, a := img.At(0, 0).RGBA(); a != 0 {
+		t.Errorf("expected a transparent corner, got alpha %d", a)
+	}
+}
+
+func TestCanvasPoolBounded(t *testing.T) {
+	c := &canvasPool{}
+
+	for i := 1; i <= maxPooledSizes+2; i++ {
+		c.put(c.get(i, i))
+	}
+
+	if len(c.pools) != maxPooledSizes {
+		t.Errorf("expected %d pooled sizes, got %d", maxPooledSizes, len(c.pools))
+	}
+
+	// the first sizes stay pooled, the rest are allocated directly
+	if c.pool(1, 1) == nil || c.pool(maxPooledSizes+1, maxPooledSizes+1) != nil {
+		t.Error("expected only the first sizes pooled")
+	}
+
+	if canvas := c.get(maxPooledSizes+2, 1); canvas.Rect.Dx() != maxPooledSizes+2 || canvas.Rect.Dy() != 1 {
+		t.Errorf("expected a canvas of the size for an unpooled size, got %v", canvas.Rect)
+	}
+}
+
+func BenchmarkDrawJPEGCanvasPool(b *testing.B) {
+	opts := testOptions()
+	opts.TitleShadow = true
+
+	b.Run("without the pool", func(b *testing.B) {
+		p := New(func(p *Preview) { p.canvases = nil })
+
+		b.ReportAllocs()
+
+		for n := 0; n < b.N; n++ {
+			if _, err := p.DrawJPEG(context.Background(), opts); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+
+	b.Run("with the pool", func(b *testing.B) {
+		p := New()
+
+		b.ReportAllocs()
+
+		for n := 0; n < b.N; n++ {
+			if _, err := p.DrawJPEG(context.Background(), opts); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
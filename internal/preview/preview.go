@@ -3,293 +3,2128 @@ package preview
 import (
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"image"
 	"image/color"
+	"image/draw"
+	"io"
+	"io/fs"
 	"log"
 	"math"
+	"net/http"
 	"regexp"
-	"unicode/utf8"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
 
 	"github.com/davidbyttow/govips/v2/vips"
 	"github.com/fogleman/gg"
 	"github.com/nDmitry/ogimgd/internal/remote"
+	"golang.org/x/image/font"
 )
 
 const (
-	margin            = 20.0
-	padding           = 48.0
-	border            = 8
 	maxTitleLength    = 90
+	titleSizeStep     = 2.0
+	minTitleSize      = 24.0
+	maxBlurSigma      = 50.0
 	defaultBgColor    = "#FFFFFF"
 	avatarBorderColor = "#FFFFFF"
+	labelLColor       = "#FFFFFF"
+	labelRColor       = "#FFB800"
 	logoKey           = "logo"
 	avaKey            = "avatar"
+	avatarStep        = 0.6
+	avatarBadgeColor  = "#333333"
+	avatarFallback    = "#5B6EE1"
+	avatarPlaceholder = "#9E9E9E"
+	defaultMaxAvatars = 4
+	maxFetchTimeout   = time.Minute
 	bgKey             = "bg"
+	// the meta line size relative to the author size
+	metaScale = 0.6
 )
 
-var hexRe = regexp.MustCompile("^#(?:[0-9a-fA-F]{3}){1,2}$")
+// Defaults for zero-valued Options
+const (
+	DefaultTitleSize     = 76.0
+	DefaultLineHeight    = 1.2
+	DefaultAuthorSize    = 36.0
+	DefaultSubtitleSize  = 40.0
+	DefaultSubtitleLines = 2
+	DefaultLabelSize     = 40.0
+	DefaultAvaD          = 64
+	DefaultLogoH         = 48
+	DefaultOpacity       = 0.6
+	DefaultQuality       = 80
+	DefaultMargin        = 20.0
+	DefaultPadding       = 48.0
+	// 40 megapixels take 160 MB decoded
+	DefaultMaxImagePixels = 40 * 1000 * 1000
+)
+
+// The largest canvas, 4096x4096 px at most but 16:9 4K by area, as the canvas is allocated before drawing
+const (
+	MaxCanvasSide   = 4096
+	MaxCanvasPixels = 3840 * 2160
+)
+
+// NoOverlay is the Opacity of no foreground overlay, as zero stands for DefaultOpacity over an image
+const NoOverlay = -1.0
+
+// Avatar shapes
+const (
+	ShapeCircle  = "circle"
+	ShapeSquare  = "square"
+	ShapeRounded = "rounded"
+)
+
+// Title alignments
+const (
+	AlignLeft   = "left"
+	AlignCenter = "center"
+	AlignRight  = "right"
+)
+
+// Title vertical alignments
+const (
+	VAlignTop    = "top"
+	VAlignMiddle = "middle"
+	VAlignBottom = "bottom"
+)
+
+// Crop modes
+const (
+	CropAttention = "attention"
+	CropCentre    = "centre"
+	CropEntropy   = "entropy"
+)
+
+// cropModes maps the crop modes to the vips ones.
+var cropModes = map[string]vips.Interesting{
+	"":            vips.InterestingAttention,
+	CropAttention: vips.InterestingAttention,
+	CropCentre:    vips.InterestingCentre,
+	CropEntropy:   vips.InterestingEntropy,
+}
+
+// Background fit modes
+const (
+	FitCover   = "cover"
+	FitContain = "contain"
+	FitFill    = "fill"
+)
+
+// Background gravities
+const (
+	GravityCentre    = "centre"
+	GravityNorth     = "north"
+	GravitySouth     = "south"
+	GravityEast      = "east"
+	GravityWest      = "west"
+	GravityNorthEast = "north-east"
+	GravityNorthWest = "north-west"
+	GravitySouthEast = "south-east"
+	GravitySouthWest = "south-west"
+)
+
+// gravityFocus maps the gravities to the focus points they crop around.
+var gravityFocus = map[string]Focus{
+	GravityCentre:    {X: 0.5, Y: 0.5},
+	GravityNorth:     {X: 0.5, Y: 0},
+	GravitySouth:     {X: 0.5, Y: 1},
+	GravityEast:      {X: 1, Y: 0.5},
+	GravityWest:      {X: 0, Y: 0.5},
+	GravityNorthEast: {X: 1, Y: 0},
+	GravityNorthWest: {X: 0, Y: 0},
+	GravitySouthEast: {X: 1, Y: 1},
+	GravitySouthWest: {X: 0, Y: 1},
+}
+
+// Logo positions
+const (
+	LogoBottomRight = "bottom-right"
+	LogoBottomLeft  = "bottom-left"
+	LogoTopRight    = "top-right"
+	LogoTopLeft     = "top-left"
+)
+
+var hexRe = regexp.MustCompile("^#(?:(?:[0-9a-fA-F]{3}){1,2}|[0-9a-fA-F]{8})$")
+
+// ErrTooManyPixels is returned for an image larger than the pixel budget (see Options.MaxImagePixels).
+var ErrTooManyPixels = errors.New("too many pixels")
+
+// defaultAuthorColor is a semi-transparent white
+var defaultAuthorColor = color.NRGBA{R: 255, G: 255, B: 255, A: 204}
+
+// defaultMetaColor is a white more transparent than the author
+var defaultMetaColor = color.RGBA{R: 255, G: 255, B: 255, A: 153}
+
+// Focus is a point of an image in the fractions (0-1) of its width and height.
+type Focus struct {
+	X, Y float64
+}
+
+type getter interface {
+	GetAll(context.Context, map[string]string) (map[string][]byte, error)
+}
+
+// Options defines a set of options required to draw a p.ctx.
+type Options struct {
+	// Canvas width
+	CanvasW int
+	// Canvas height
+	CanvasH int
+	// Space between the canvas edges and the foreground overlay, DefaultMargin if zero
+	Margin float64
+	// Space between the canvas edges and the elements, DefaultPadding if zero
+	Padding float64
+	// Opacity value for the foreground overlay under the title,
+	// DefaultOpacity if zero and Bg is an image or empty (zero keeps explicit colors and gradients intact),
+	// NoOverlay for no overlay over any background
+	Opacity float64
+	// Foreground overlay HEX-color, black by default
+	OverlayColor string
+	// Fade the overlay in from transparent at the top to Opacity at the bottom instead of a flat fill
+	OverlayGradient bool
+	// Avatar diameter, DefaultAvaD if zero and there is an avatar URL,
+	// clamped to a third of the smaller canvas side minus the padding
+	AvaD int
+	// Avatar diameter in percent of CanvasH, overrides AvaD if positive
+	AvaDPct float64
+	// Avatar border (ring) width, no border if zero
+	AvaBorderW int
+	// Avatar border HEX-color, #FFFFFF by default
+	AvaBorderColor string
+	// Avatar shape: circle (default), square or rounded
+	AvaShape string
+	// Avatar corner radius for the rounded shape
+	AvaCornerRadius int
+	// Title text, a line break (\n) starts a new line
+	Title string
+	// Title font size, DefaultTitleSize if zero
+	TitleSize float64
+	// Title font size in percent of CanvasH, overrides TitleSize if positive
+	TitleSizePct float64
+	// Title line height relative to the font size, DefaultLineHeight if zero
+	TitleLineHeight float64
+	// Fonts (and emoji images) of this draw instead of the ones of the Preview (see WithFontDir),
+	// e.g. to draw the previews of different brands with a single Preview
+	FontSet *FontSet
+	// Title font, the Ubuntu of TitleWeight by default
+	TitleFont FontSource
+	// Title font weight: regular, medium (default) or bold, ignored for a custom TitleFont.
+	// The Ubuntu-Regular.ttf and Ubuntu-Bold.ttf of the font dir (see WithFontDir and FontSet) take precedence
+	// over the embedded ones like the other fonts, the medium one is drawn if neither has them
+	TitleWeight string
+	// Extra px between the title glyphs, negative values tighten them
+	TitleTracking float64
+	// Draw the title emoji as the color images of the emoji dir of the font dir (e.g. emoji/1f389.png)
+	// or the ones fetched from DefaultEmojiURL (see WithEmojiURL) instead of the monochrome glyphs,
+	// the emoji without an image keep the glyph
+	ColorEmoji bool
+	// Decrease the title font size until the wrapped title fits above the logo
+	AutoFitTitle bool
+	// The smallest title font size AutoFitTitle can go down to, 24 by default
+	MinTitleSize float64
+	// Lines the wrapped title is cut to with an ellipsis, no limit if zero. The title is cut to 90 runes anyway
+	MaxTitleLines int
+	// Title horizontal alignment: left (right for a right-to-left title by default), center or right
+	TitleAlign string
+	// Title vertical alignment between the avatar and the logo rows: top (default), middle or bottom
+	TitleVAlign string
+	// Title direction: auto (default, by the predominant script), ltr or rtl.
+	// The right-to-left text is reordered for drawing but not shaped, so there are no Arabic joining forms and ligatures
+	TitleDirection string
+	// Title HEX-color, #FFFFFF by default
+	TitleColor string
+	// Title outline width in px, no outline if zero
+	TitleStrokeWidth float64
+	// Title outline HEX-color, semi-transparent black by default
+	TitleStrokeColor string
+	// Draw a rounded band behind the wrapped title, e.g. instead of darkening the whole card with Opacity
+	TitleBand bool
+	// Title band HEX-color, semi-transparent black by default
+	TitleBandColor string
+	// Space in px between the title and the band edges, 16 by default
+	TitleBandPadding float64
+	// Draw a drop shadow under the title
+	TitleShadow bool
+	// Title shadow HEX-color, semi-transparent black by default
+	TitleShadowColor string
+	// Gaussian blur sigma of the title shadow, a sharp shadow if zero (clamped to 50)
+	TitleShadowBlur float64
+	// Title shadow offset in px, positive values move it right and down
+	TitleShadowX float64
+	TitleShadowY float64
+	// Description drawn below the wrapped title, wrapped to the title width
+	Subtitle string
+	// Subtitle font size, DefaultSubtitleSize if zero
+	SubtitleSize float64
+	// Lines the subtitle is cut to with an ellipsis, DefaultSubtitleLines if zero
+	SubtitleMaxLines int
+	// Subtitle HEX-color, an 8-digit value (#RRGGBBAA) sets opacity too, a dimmer white than the title by default
+	SubtitleColor string
+	Author        string
+	// Author font size, DefaultAuthorSize if zero
+	AuthorSize float64
+	// Author font size in percent of CanvasH, overrides AuthorSize if positive
+	AuthorSizePct float64
+	// Author font, the Ubuntu of AuthorWeight by default
+	AuthorFont FontSource
+	// Author font weight: regular, medium (default) or bold, ignored for a custom AuthorFont,
+	// the fonts are looked up like the TitleWeight ones
+	AuthorWeight string
+	// Extra px between the author and the meta glyphs, negative values tighten them
+	AuthorTracking float64
+	// Author HEX-color, an 8-digit value (#RRGGBBAA) sets opacity too, semi-transparent white by default
+	AuthorColor string
+	// Author and meta horizontal position: left (default, next to the avatars) or right (against the right padding)
+	AuthorPosition string
+	// Secondary line under the author like "5 min read · Jan 2024" drawn in the author font smaller and lighter
+	Meta string
+	// Meta HEX-color, an 8-digit value (#RRGGBBAA) sets opacity too, a more transparent white than the author by default
+	MetaColor string
+	// Logo left part text drawn in a neutral color (optional)
+	LabelL string
+	// Logo right part text drawn in an accent color (optional)
+	LabelR string
+	// Label font size, DefaultLabelSize if zero
+	LabelSize float64
+	// Label font size in percent of CanvasH, overrides LabelSize if positive
+	LabelSizePct float64
+	// Category chips drawn in a row above the title, wrapping to the next rows if they don't fit
+	Tags []string
+	// Tag chip HEX-color, the accent color of the label by default
+	TagColor string
+	// Tag text HEX-color, black by default
+	TagTextColor string
+	// Either an URL to a remote background image, or filename of the local image (see WithAssetFS), or a HEX-color,
+	// or a linear gradient like gradient:45,#FF0000,#0000FF (CSS-like angle and evenly distributed stops),
+	// or a radial gradient like radial:#FFFFFF,#000000 with an optional center: radial:0.25,0.5,#FFFFFF,#000000,
+	// or the dominant color of an image like dominant:https://example.com/bg.jpg, or of the avatar (dominant:ava)
+	// or the logo (dominant:logo) falling back to the default color without them
+	// An image will be thumbnailed and smart-cropped if it's not of the canvas size,
+	// an animated GIF or WebP is drawn as its first frame
+	Bg string
+	// Typed background overriding Bg: HexBackground, ImageBackground, GradientBackground or ColorFromImage
+	BackgroundSource Background
+	// Fail the preview if the background image can't be loaded instead of falling back to the default color
+	RequireBg bool
+	// Gaussian blur sigma applied to the background image, no blur if zero or negative (clamped to 50)
+	BgBlur float64
+	// How the background and avatar images are cropped to their aspect ratio: attention (default, smart crop),
+	// centre or entropy. The centre crop doesn't depend on the vips version, so it suits the golden image tests
+	CropMode string
+	// How the background image fits the canvas: cover (default, cropped to fill it), contain (fit inside it
+	// with the rest padded with BgPadColor) or fill (stretched ignoring its aspect ratio)
+	BgFit string
+	// HEX-color of the padding around a contained background image, the default background color if empty
+	BgPadColor string
+	// Part of the background image kept when it's cropped to cover the canvas: centre, north, south, east, west,
+	// north-east, north-west, south-east or south-west. The image is cropped by CropMode if empty
+	BgGravity string
+	// Point of the background image to crop around, overrides BgGravity
+	BgFocus *Focus
+	// Background image brightness multiplier (0-3), 1 if zero, a negative value is clamped to 0
+	BgBrightness float64
+	// Background image contrast multiplier around the middle gray (0-3), 1 if zero, a negative value is clamped to 0
+	BgContrast float64
+	// Background image saturation multiplier (0-3), 1 if zero, a negative value is clamped to 0 (grayscale)
+	BgSaturation float64
+	// Background image filter: none (default), grayscale or duotone
+	BgFilter string
+	// Duotone HEX-color the shadows of the background image are mapped to, dark indigo by default
+	DuotoneDark string
+	// Duotone HEX-color the highlights of the background image are mapped to, pale yellow by default
+	DuotoneLight string
+	// Repeat the background image of its native size across the canvas instead of resizing it, overrides BgFit
+	BgTile bool
+	// Strength (0-1) of the fine monochrome noise drawn over the background to hide the gradient banding,
+	// no grain if zero. Even the full strength shifts the pixels by 32 levels at most
+	Grain float64
+	// Seed of the grain noise, the same seed draws the same grain, a random one if zero
+	Seed int64
+	// An URL to an author avatar pic
+	AvaURL string
+	// URLs to co-authors avatar pics, drawn after AvaURL overlapping each other
+	AvaURLs []string
+	// HEX-color of the initials avatar drawn when there is no avatar URL or it fails to load
+	AvaFallbackColor string
+	// Max number of avatars to draw, the rest is collapsed to a "+k" badge, 4 by default
+	MaxAvatars int
+	// An URL to a logo image (optional if a label is set)
+	LogoURL string
+	// Fail the preview if the logo can't be loaded instead of skipping it
+	RequireLogo bool
+	// Logo height, DefaultLogoH if zero and there is a logo URL
+	LogoH int
+	// Logo opacity (0-1) for a watermark-like look, fully opaque when zero
+	LogoOpacity float64
+	// Avatar image opacity (0-1), fully opaque when zero
+	AvaOpacity float64
+	// Author opacity (0-1) applied on top of the AuthorColor alpha, fully opaque when zero
+	AuthorOpacity float64
+	// Logo corner: bottom-right (default), bottom-left, top-right or top-left
+	LogoPosition string
+	// Keep the canvas transparent when Bg is empty (makes sense for PNG output only)
+	Transparent bool
+	// Corner radius of the whole card, the corners are left transparent (makes sense for PNG output only)
+	CardRadius int
+	// Width of the frame drawn inside the canvas edges above everything else, no frame if zero
+	CardBorderW int
+	// Card frame HEX-color, #000000 by default
+	CardBorderColor string
+	// Draw a soft shadow under the avatars and the logo to lift them off a busy background
+	ElementShadow bool
+	// Pick black or white title and author colors depending on what is drawn behind the title,
+	// overrides TitleColor and AuthorColor
+	AutoContrast bool
+	// Output format of DrawTo: jpeg (default), png, webp or avif
+	Format Format
+	// Resulting JPEG/WebP quality (1-100), DefaultQuality if zero
+	Quality int
+	// Use lossless compression for WebP output
+	Lossless bool
+	// Use progressive (interlaced) encoding for JPEG output, baseline by default
+	Progressive bool
+	// AVIF encoder speed (1-8), the faster the bigger the output, the vips default if zero
+	AvifSpeed int
+	// Strip the metadata (EXIF, XMP, ICC profile) from the encoded preview, true if nil
+	StripMetadata *bool
+	// Number of retries for transient remote image fetch failures (capped at 5)
+	FetchRetries int
+	// Headers (e.g. Authorization) sent with the requests to the image URLs starting with the prefix they are keyed by,
+	// e.g. "https://cdn.example.com/private/". The images fetched with headers are not cached, the headers are not logged
+	FetchHeaders map[string]http.Header
+	// Max size in bytes of each fetched image, 10 MiB by default (or the limit of a custom getter) if zero
+	MaxImageBytes int64
+	// Max width*height of each fetched image checked before decoding it, DefaultMaxImagePixels if zero
+	MaxImagePixels int64
+	// Convert the images with an embedded ICC profile (Adobe RGB, Display P3, etc.) to sRGB before drawing them
+	ColorManage bool
+	// Timeout for fetching all the remote images, no timeout but the one of the parent context if zero (capped at 1 min)
+	FetchTimeout time.Duration
+}
+
+// withDefaults returns a copy of Options with the zero values filled with the defaults.
+// Zero sizes of elements that are not drawn are left as they are not to change the layout.
+func (o Options) withDefaults() Options {
+	o.Bg = o.bg()
+
+	// the relative sizes are resolved first, so a card keeps its proportions at any canvas size
+	if o.TitleSizePct > 0 {
+		o.TitleSize = o.relativeSize(o.TitleSizePct)
+	}
+
+	if o.AuthorSizePct > 0 {
+		o.AuthorSize = o.relativeSize(o.AuthorSizePct)
+	}
+
+	if o.LabelSizePct > 0 {
+		o.LabelSize = o.relativeSize(o.LabelSizePct)
+	}
+
+	if o.AvaDPct > 0 {
+		o.AvaD = int(math.Round(o.relativeSize(o.AvaDPct)))
+	}
+
+	if o.TitleSize == 0 {
+		o.TitleSize = DefaultTitleSize
+	}
+
+	if o.TitleLineHeight == 0 {
+		o.TitleLineHeight = DefaultLineHeight
+	}
+
+	if o.AuthorSize == 0 {
+		o.AuthorSize = DefaultAuthorSize
+	}
+
+	if o.SubtitleSize == 0 && o.Subtitle != "" {
+		o.SubtitleSize = DefaultSubtitleSize
+	}
+
+	if o.SubtitleMaxLines == 0 && o.Subtitle != "" {
+		o.SubtitleMaxLines = DefaultSubtitleLines
+	}
+
+	if o.LabelSize == 0 {
+		o.LabelSize = DefaultLabelSize
+	}
+
+	if o.AvaD == 0 && (o.AvaURL != "" || len(o.AvaURLs) > 0) {
+		o.AvaD = DefaultAvaD
+	}
+
+	if o.LogoH == 0 && o.LogoURL != "" {
+		o.LogoH = DefaultLogoH
+	}
+
+	if o.Opacity == 0 && !hexRe.MatchString(o.Bg) && !isGradient(o.Bg) && !isDominant(o.Bg) {
+		o.Opacity = DefaultOpacity
+	}
+
+	if o.Quality == 0 {
+		o.Quality = DefaultQuality
+	}
+
+	if o.Margin == 0 {
+		o.Margin = DefaultMargin
+	}
+
+	if o.Padding == 0 {
+		o.Padding = DefaultPadding
+	}
+
+	if o.StripMetadata == nil {
+		strip := true
+		o.StripMetadata = &strip
+	}
+
+	return o
+}
+
+// relativeSize returns the pixel size of the percent of the canvas height.
+func (o Options) relativeSize(pct float64) float64 {
+	return pct / 100 * float64(o.CanvasH)
+}
+
+// Preview can draw a preview using the provided Options.
+// It holds no per-call state, so a single Preview can draw concurrently.
+type Preview struct {
+	remote       getter
+	logger       *log.Logger
+	fonts        *fontSet
+	batchWorkers int
+	assetFS      fs.FS
+	// base URL of the color emoji images missing in the font dir, no images are fetched if empty
+	emojiURL string
+	// the canvases of the drawings whose image is not returned, no pooling if nil
+	canvases *canvasPool
+}
+
+// drawing is the state of a single Draw call.
+type drawing struct {
+	*Preview
+	opts   *Options
+	ctx    *gg.Context
+	faces  map[faceKey]font.Face
+	layout *LayoutInfo
+	stats  Stats
+	// height of the avatar row, zero if it's hidden
+	rowH float64
+	// height of the tag rows above the title
+	tagsH float64
+	// the wrapped title was cut to MaxTitleLines
+	titleCut bool
+	// the canvas from the pool to return on release, nil if the drawn image is owned by the caller
+	canvas *image.RGBA
+	// the fonts of the draw: the ones of Options.FontSet or of the Preview
+	fonts *fontSet
+	// the color emoji images fetched from the emoji URL by their sequences
+	emoji map[string]image.Image
+}
+
+// Option configures a Preview.
+type Option func(*Preview)
+
+// WithGetter makes the Preview fetch images using the getter instead of the default remote.
+func WithGetter(g remote.Getter) Option {
+	return func(p *Preview) {
+		p.remote = g
+	}
+}
+
+// WithLogger routes the Preview messages to the logger, they are discarded by default.
+func WithLogger(l *log.Logger) Option {
+	return func(p *Preview) {
+		p.logger = l
+	}
+}
+
+// WithFontDir makes the Preview load fonts from the dir by their embedded file names
+// (e.g. Ubuntu-Medium.ttf). The fonts missing in the dir fall back to the embedded ones.
+// A TrueType NotoSansCJK.ttf in the dir is used for the CJK text, as no CJK font is embedded.
+// The emoji subdir of the dir holds the PNG images for ColorEmoji, they take precedence over the ones
+// fetched from the emoji URL (see WithEmojiURL).
+func WithFontDir(dir fs.FS) Option {
+	return func(p *Preview) {
+		p.fonts = &fontSet{dir: dir}
+	}
+}
+
+// WithCache keeps up to maxEntries fetched images in memory for the TTL.
+func WithCache(maxEntries int, ttl time.Duration) Option {
+	return func(p *Preview) {
+		p.remote = remote.NewCached(p.remote, maxEntries, ttl)
+	}
+}
+
+// bg returns the Bg string of the BackgroundSource if it's set, or Bg.
+func (o Options) bg() string {
+	if o.BackgroundSource != nil {
+		return o.BackgroundSource.bg()
+	}
+
+	return o.Bg
+}
+
+// New returns an initialized Preview starting libvips with the default Config unless it's already started (see Startup).
+func New(options ...Option) *Preview {
+	startVips()
+
+	p := &Preview{
+		remote:   remote.New(),
+		logger:   log.New(io.Discard, "", 0),
+		fonts:    defaultFonts,
+		emojiURL: DefaultEmojiURL,
+		canvases: defaultCanvases,
+	}
+
+	for _, option := range options {
+		option(p)
+	}
+
+	return p
+}
+
+// Draw draws a preview using the provided Options.
+func (p *Preview) Draw(ctx context.Context, opts Options) (image.Image, error) {
+	img, _, err := p.DrawWithStats(ctx, opts)
+
+	return img, err
+}
+
+// DrawWithStats draws a preview like Draw and returns how it was drawn.
+// The stats collected before an error are returned along with it.
+func (p *Preview) DrawWithStats(ctx context.Context, opts Options) (image.Image, Stats, error) {
+	opts, err := p.prepareOptions(opts)
+
+	if err != nil {
+		return nil, Stats{}, err
+	}
+
+	d := p.newDrawing(opts)
+
+	defer d.release()
+
+	img, err := d.draw(ctx)
+
+	return img, d.stats, err
+}
+
+// maxAvatarD returns the largest avatar diameter that leaves room for the title and the logo rows:
+// a third of the smaller canvas side minus the padding.
+func maxAvatarD(canvasW, canvasH int, padding float64) int {
+	side := canvasW
+
+	if canvasH < side {
+		side = canvasH
+	}
+
+	if d := side/3 - int(padding); d > 1 {
+		return d
+	}
+
+	return 1
+}
+
+// newDrawing returns the state of a single Draw call with a blank canvas.
+func (p *Preview) newDrawing(opts Options) *drawing {
+	return &drawing{
+		Preview: p,
+		opts:    &opts,
+		ctx:     gg.NewContext(opts.CanvasW, opts.CanvasH),
+		faces:   make(map[faceKey]font.Face),
+		fonts:   p.fontsOf(opts),
+	}
+}
+
+// newPooledDrawing returns a drawing on a canvas from the pool that goes back to the pool on release,
+// so the drawn image must not be used after that.
+func (p *Preview) newPooledDrawing(opts Options) *drawing {
+	if p.canvases == nil {
+		return p.newDrawing(opts)
+	}
+
+	canvas := p.canvases.get(opts.CanvasW, opts.CanvasH)
+
+	return &drawing{
+		Preview: p,
+		opts:    &opts,
+		ctx:     gg.NewContextForRGBA(canvas),
+		faces:   make(map[faceKey]font.Face),
+		canvas:  canvas,
+		fonts:   p.fontsOf(opts),
+	}
+}
+
+// fontsOf returns the font set the Options are drawn with.
+func (p *Preview) fontsOf(opts Options) *fontSet {
+	if opts.FontSet != nil {
+		return opts.FontSet.fonts
+	}
+
+	return p.fonts
+}
+
+// drawPooled draws a preview on a pooled canvas and passes the image to use before the canvas is reused.
+func (p *Preview) drawPooled(ctx context.Context, opts Options, use func(image.Image) error) error {
+	opts, err := p.prepareOptions(opts)
+
+	if err != nil {
+		return err
+	}
+
+	d := p.newPooledDrawing(opts)
+
+	defer d.release()
+
+	img, err := d.draw(ctx)
+
+	if err != nil {
+		return err
+	}
+
+	return use(img)
+}
+
+// loadFont returns a face of the primary font of the size reusing the faces loaded during the draw.
+func (p *drawing) loadFont(primary FontSource, points float64) (font.Face, error) {
+	key := faceKey{font: primary.key(), points: points}
+
+	if face, exists := p.faces[key]; exists {
+		return face, nil
+	}
+
+	face, err := p.fonts.acquireFace(primary, points)
+
+	if err != nil {
+		return nil, err
+	}
+
+	p.faces[key] = face
+
+	return face, nil
+}
+
+// release returns the font faces loaded during the draw and the pooled canvas for reuse.
+func (p *drawing) release() {
+	for key, face := range p.faces {
+		p.fonts.releaseFace(key, face)
+	}
+
+	p.faces = nil
+
+	if p.canvas != nil {
+		p.canvases.put(p.canvas)
+		p.canvas = nil
+	}
+}
+
+// draw fetches the images and draws all the preview elements in order.
+func (p *drawing) draw(ctx context.Context) (image.Image, error) {
+	bgColor := defaultBgColor
+	isBgHEX := hexRe.Match([]byte(p.opts.Bg))
+	urlsOrPaths := map[string]string{}
+	// avatars are always optional as they fall back to initials
+	optional := map[string]string{}
+
+	if p.opts.LogoURL != "" && p.opts.RequireLogo {
+		urlsOrPaths[logoKey] = p.opts.LogoURL
+	} else if p.opts.LogoURL != "" {
+		optional[logoKey] = p.opts.LogoURL
+	}
+
+	avaURLs := p.avatarURLs()
+
+	for i := 0; i < len(avaURLs) && i < p.maxAvatars(); i++ {
+		optional[avaKey+strconv.Itoa(i)] = avaURLs[i]
+	}
+
+	if bgURL := bgImageURL(p.opts.Bg); isBgHEX {
+		bgColor = p.opts.Bg
+	} else if bgURL != "" && p.opts.RequireBg {
+		urlsOrPaths[bgKey] = bgURL
+	} else if bgURL != "" {
+		optional[bgKey] = bgURL
+	}
+
+	// the color emoji images are optional as the emoji fall back to the glyphs
+	for key, emojiURL := range p.emojiURLs() {
+		optional[key] = emojiURL
+	}
+
+	fetchCtx, cancel := p.fetchContext(ctx)
+	defer cancel()
+
+	start := time.Now()
+	imgBufs, err := p.fetch(fetchCtx, urlsOrPaths, optional)
+	p.stats.Fetch = time.Since(start)
+
+	if err != nil {
+		return nil, withKind(ErrFetch, fmt.Errorf("could not get an image: %w", err))
+	}
+
+	p.stats.FetchedBytes = make(map[string]int, len(imgBufs))
+
+	for key, buf := range imgBufs {
+		urlOrPath, exists := urlsOrPaths[key]
+
+		if !exists {
+			urlOrPath = optional[key]
+		}
+
+		p.stats.FetchedBytes[urlOrPath] += len(buf)
+	}
+
+	start = time.Now()
+	assets := p.prepareAssets(imgBufs)
+	p.emoji = p.decodeEmoji(imgBufs)
+	p.stats.Prepare = time.Since(start)
+
+	start = time.Now()
+
+	defer func() { p.stats.Draw = time.Since(start) }()
+
+	logoW := 0
+
+	if assets.logo != nil {
+		logoW = assets.logo.Bounds().Dx()
+	}
+
+	if err := p.computeLayout(logoW); err != nil {
+		return nil, err
+	}
+
+	p.stats.TitleSize = p.layout.TitleSize
+	p.stats.TitleTruncated = p.titleText() != p.normalizedTitle() || p.titleCut
+
+	if assets.bgErr != nil && p.opts.RequireBg {
+		return nil, withKind(ErrDecode, assets.bgErr)
+	} else if assets.bgErr != nil {
+		p.logger.Printf("falling back to the default background: %s", assets.bgErr)
+	}
+
+	if assets.bgColor != "" {
+		bgColor = assets.bgColor
+	}
+
+	if err := p.drawBackground(assets.bg, bgColor); err != nil {
+		return nil, err
+	}
+
+	p.drawGrain()
+
+	if err := p.drawForeground(); err != nil {
+		return nil, err
+	}
+
+	if p.opts.AutoContrast {
+		if err := p.pickContrastColors(); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := p.drawAvatarShadows(); err != nil {
+		return nil, err
+	}
+
+	if len(avaURLs) == 0 && p.avatarSlots() > 0 {
+		if err := p.drawAvatarFallback(0); err != nil {
+			return nil, err
+		}
+	}
+
+	for i := 0; i < len(avaURLs) && i < p.maxAvatars(); i++ {
+		if assets.avatars[i] != nil {
+			if err := p.drawAvatar(assets.avatars[i], i); err != nil {
+				return nil, err
+			}
+
+			continue
+		}
+
+		if assets.avatarErrs[i] != nil {
+			p.logger.Printf("falling back to initials: %s", assets.avatarErrs[i])
+		}
+
+		if err := p.drawAvatarFallback(i); err != nil {
+			return nil, err
+		}
+	}
+
+	if len(avaURLs) > p.maxAvatars() {
+		if err := p.drawAvatarPlaceholder(p.maxAvatars(), avatarBadgeColor, fmt.Sprintf("+%d", len(avaURLs)-p.maxAvatars())); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := p.drawAuthor(); err != nil {
+		return nil, err
+	}
+
+	if err := p.drawMeta(); err != nil {
+		return nil, err
+	}
+
+	if err := p.drawTags(); err != nil {
+		return nil, err
+	}
+
+	if err := p.drawTitle(); err != nil {
+		return nil, err
+	}
+
+	if err := p.drawSubtitle(); err != nil {
+		return nil, err
+	}
+
+	if assets.logoErr != nil && p.opts.RequireLogo {
+		return nil, withKind(ErrDecode, assets.logoErr)
+	} else if assets.logoErr != nil {
+		p.logger.Printf("skipping the logo: %s", assets.logoErr)
+	}
+
+	if assets.logo != nil {
+		if err := p.drawLogoShadow(assets.logo); err != nil {
+			return nil, err
+		}
+
+		p.drawLogo(assets.logo)
+	}
+
+	if err := p.drawLabel(); err != nil {
+		return nil, err
+	}
+
+	if err := p.drawCardBorder(); err != nil {
+		return nil, err
+	}
+
+	return p.clipCard(), nil
+}
+
+// assets are the fetched images resized and decoded for drawing, or the errors preparing them.
+type assets struct {
+	bg         image.Image
+	bgColor    string
+	bgErr      error
+	avatars    []image.Image
+	avatarErrs []error
+	logo       image.Image
+	logoErr    error
+}
+
+// prepareAssets resizes and decodes the fetched images concurrently as they are independent.
+// A failed image doesn't stop the others, its error is kept for the drawing step to decide on.
+func (p *drawing) prepareAssets(bufs map[string][]byte) *assets {
+	a := &assets{
+		avatars:    make([]image.Image, p.maxAvatars()),
+		avatarErrs: make([]error, p.maxAvatars()),
+	}
+
+	var wg sync.WaitGroup
+
+	run := func(prepare func()) {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			prepare()
+		}()
+	}
+
+	bgBuf, bgExists := bufs[bgKey]
+
+	// the dominant color of the avatar or the logo is picked from its image fetched to be drawn
+	if key, exists := dominantImageKey(p.opts.Bg); exists {
+		if bgBuf, bgExists = bufs[key]; !bgExists {
+			a.bgErr = withKind(ErrFetch, fmt.Errorf("could not pick the dominant color: no %s image", strings.TrimPrefix(p.opts.Bg, dominantPrefix)))
+		}
+	}
+
+	if bgExists && isDominant(p.opts.Bg) {
+		run(func() { a.bgColor, a.bgErr = p.dominantColor(bgBuf) })
+	} else if bgExists {
+		run(func() { a.bg, a.bgErr = p.prepareBackground(bgBuf) })
+	}
+
+	for i := range a.avatars {
+		if buf, exists := bufs[avaKey+strconv.Itoa(i)]; exists {
+			i := i
+
+			run(func() { a.avatars[i], a.avatarErrs[i] = p.prepareAvatar(buf) })
+		}
+	}
+
+	if buf, exists := bufs[logoKey]; exists {
+		run(func() { a.logo, a.logoErr = p.prepareLogo(buf) })
+	}
+
+	wg.Wait()
+
+	return a
+}
+
+func (p *drawing) prepareBackground(bgBuf []byte) (image.Image, error) {
+	if err := p.checkPixels(bgBuf); err != nil {
+		return nil, fmt.Errorf("could not load the background: %w", err)
+	}
+
+	bgBuf, err := p.decodable(bgBuf)
+
+	if err != nil {
+		return nil, fmt.Errorf("could not convert the background to PNG: %w", err)
+	}
+
+	bgBuf, err = p.manageColor(bgBuf)
+
+	if err != nil {
+		return nil, fmt.Errorf("could not convert the background to sRGB: %w", err)
+	}
+
+	w, h := p.opts.CanvasW, p.opts.CanvasH
+	crop := cropModes[p.opts.CropMode]
+
+	switch {
+	case p.opts.BgTile:
+		// a tile keeps its native size
+		if w, h, err = imageSize(bgBuf); err != nil {
+			return nil, fmt.Errorf("could not get the background size: %w", err)
+		}
+	case p.opts.BgFit == FitContain:
+		crop = vips.InterestingNone
+	case p.opts.BgFit == FitFill:
+		if bgBuf, err = p.stretch(bgBuf, w, h); err != nil {
+			return nil, fmt.Errorf("could not stretch the background: %w", err)
+		}
+	default:
+		focus, exists := gravityFocus[p.opts.BgGravity]
+
+		if p.opts.BgFocus != nil {
+			focus, exists = *p.opts.BgFocus, true
+		}
+
+		if exists {
+			if bgBuf, err = p.cropAround(bgBuf, w, h, focus); err != nil {
+				return nil, fmt.Errorf("could not crop the background: %w", err)
+			}
+		}
+	}
+
+	bgBuf, err = p.resize(bgBuf, w, h, p.opts.BgBlur, crop)
+
+	if err != nil {
+		return nil, fmt.Errorf("could not resize the background: %w", err)
+	}
+
+	if bgBuf, err = p.filterBackground(bgBuf); err != nil {
+		return nil, fmt.Errorf("could not filter the background: %w", err)
+	}
+
+	bgImg, _, err := image.Decode(bytes.NewReader(bgBuf))
+
+	if err != nil {
+		return nil, fmt.Errorf("could not decode the background: %w", err)
+	}
+
+	return bgImg, nil
+}
+
+func (p *drawing) prepareAvatar(avaBuf []byte) (image.Image, error) {
+	if err := p.checkPixels(avaBuf); err != nil {
+		return nil, fmt.Errorf("could not load the avatar: %w", err)
+	}
+
+	avaBuf, err := p.decodable(avaBuf)
+
+	if err != nil {
+		return nil, fmt.Errorf("could not convert the avatar to PNG: %w", err)
+	}
+
+	avaBuf, err = p.manageColor(avaBuf)
+
+	if err != nil {
+		return nil, fmt.Errorf("could not convert the avatar to sRGB: %w", err)
+	}
+
+	avaBuf, err = p.resize(avaBuf, p.opts.AvaD, p.opts.AvaD, 0, cropModes[p.opts.CropMode])
+
+	if err != nil {
+		return nil, fmt.Errorf("could not resize the avatar: %w", err)
+	}
+
+	avaImg, _, err := image.Decode(bytes.NewReader(avaBuf))
+
+	if err != nil {
+		return nil, fmt.Errorf("could not decode the avatar: %w", err)
+	}
+
+	return avaImg, nil
+}
+
+func (p *drawing) prepareLogo(logoBuf []byte) (image.Image, error) {
+	if err := p.checkPixels(logoBuf); err != nil {
+		return nil, fmt.Errorf("could not load the logo: %w", err)
+	}
+
+	logoBuf, err := p.decodable(logoBuf)
+
+	if err != nil {
+		return nil, fmt.Errorf("could not convert the logo to PNG: %w", err)
+	}
+
+	logoBuf, err = p.manageColor(logoBuf)
+
+	if err != nil {
+		return nil, fmt.Errorf("could not convert the logo to sRGB: %w", err)
+	}
+
+	logoBuf, err = p.scale(logoBuf, p.opts.LogoH)
+
+	if err != nil {
+		return nil, fmt.Errorf("could not resize the logo: %w", err)
+	}
+
+	logoImg, _, err := image.Decode(bytes.NewReader(logoBuf))
+
+	if err != nil {
+		return nil, fmt.Errorf("could not decode the logo: %w", err)
+	}
+
+	return logoImg, nil
+}
+
+// checkPixels returns ErrTooManyPixels for an image larger than the pixel budget reading only its header,
+// so a small file can't be decoded into a huge bitmap. SVGs are always rasterized at the sizes they are drawn at.
+func (p *drawing) checkPixels(buf []byte) error {
+	if isSVG(buf) {
+		return nil
+	}
+
+	config, err := imageConfig(buf)
+
+	if err != nil {
+		return err
+	}
+
+	budget := p.opts.MaxImagePixels
+
+	if budget == 0 {
+		budget = DefaultMaxImagePixels
+	}
+
+	if pixels := int64(config.Width) * int64(config.Height); pixels > budget {
+		return fmt.Errorf("%dx%d px is over the budget of %d px: %w", config.Width, config.Height, budget, ErrTooManyPixels)
+	}
+
+	return nil
+}
+
+// manageColor converts the image to sRGB if ColorManage is set.
+func (p *drawing) manageColor(buf []byte) ([]byte, error) {
+	if !p.opts.ColorManage {
+		return buf, nil
+	}
+
+	return p.toSRGB(buf)
+}
+
+// drawBackground draws the background image, or the gradient, or fills the canvas with the color if there is no image.
+func (p *drawing) drawBackground(bgImg image.Image, bgColor string) error {
+	if bgImg == nil && p.opts.Transparent && p.opts.Bg == "" {
+		return nil
+	}
+
+	if isGradient(p.opts.Bg) {
+		grad, err := parseGradient(p.opts.Bg, float64(p.opts.CanvasW), float64(p.opts.CanvasH))
+
+		if err != nil {
+			return err
+		}
+
+		p.ctx.SetFillStyle(grad)
+		p.ctx.DrawRectangle(0, 0, float64(p.opts.CanvasW), float64(p.opts.CanvasH))
+		p.ctx.Fill()
+
+		return nil
+	}
+
+	if bgImg == nil {
+		p.ctx.SetHexColor(bgColor)
+		p.ctx.DrawRectangle(0, 0, float64(p.opts.CanvasW), float64(p.opts.CanvasH))
+		p.ctx.Fill()
+
+		return nil
+	}
+
+	if p.opts.BgTile {
+		size := bgImg.Bounds().Size()
+
+		for y := 0; y < p.opts.CanvasH; y += size.Y {
+			for x := 0; x < p.opts.CanvasW; x += size.X {
+				p.ctx.DrawImage(bgImg, x, y)
+			}
+		}
+
+		return nil
+	}
+
+	// a contained image is smaller than the canvas in one dimension
+	if size := bgImg.Bounds().Size(); size.X < p.opts.CanvasW || size.Y < p.opts.CanvasH {
+		padColor := p.opts.BgPadColor
+
+		if padColor == "" {
+			padColor = defaultBgColor
+		}
+
+		p.ctx.SetHexColor(padColor)
+		p.ctx.DrawRectangle(0, 0, float64(p.opts.CanvasW), float64(p.opts.CanvasH))
+		p.ctx.Fill()
+		p.ctx.DrawImage(bgImg, (p.opts.CanvasW-size.X)/2, (p.opts.CanvasH-size.Y)/2)
+
+		return nil
+	}
+
+	p.ctx.DrawImage(bgImg, 0, 0)
+
+	return nil
+}
+
+func (p *drawing) drawForeground() error {
+	if p.opts.Opacity == NoOverlay {
+		return nil
+	}
+
+	overlay := color.NRGBA{A: 255}
+
+	if p.opts.OverlayColor != "" {
+		var err error
+
+		if overlay, err = parseHexColor(p.opts.OverlayColor); err != nil {
+			return fmt.Errorf("invalid overlay color: %w", err)
+		}
+	}
+
+	overlay.A = uint8(float64(overlay.A) * p.opts.Opacity)
+
+	if p.opts.OverlayGradient {
+		transparent := overlay
+		transparent.A = 0
+
+		grad := gg.NewLinearGradient(0, p.opts.Margin, 0, float64(p.opts.CanvasH)-p.opts.Margin)
+		grad.AddColorStop(0, transparent)
+		grad.AddColorStop(1, overlay)
+		p.ctx.SetFillStyle(grad)
+	} else {
+		p.ctx.SetColor(overlay)
+	}
+
+	p.ctx.DrawRectangle(p.opts.Margin, p.opts.Margin, float64(p.opts.CanvasW)-(p.opts.Margin*2), float64(p.opts.CanvasH)-(p.opts.Margin*2))
+	p.ctx.Fill()
+
+	return nil
+}
+
+// drawAvatar draws the avatar in the slot, every next slot is shifted to the right overlapping the previous one.
+func (p *drawing) drawAvatar(avaImg image.Image, slot int) error {
+	shape, err := p.drawAvatarBorder(slot)
+
+	if err != nil {
+		return err
+	}
+
+	// draw the avatar itself (cropped to the shape)
+	avaImg = p.maskShape(avaImg, shape, float64(p.opts.AvaCornerRadius))
+	avaX, avaY := p.avatarCenter(slot)
+	size := avaImg.Bounds().Size()
+
+	p.drawImageFaded(avaImg, int(avaX)-size.X/2, int(avaY)-size.Y/2, p.opts.AvaOpacity)
+
+	return nil
+}
+
+// drawAvatarFallback draws the author initials in the avatar slot, or a neutral placeholder if there is no author.
+// Initials are drawn for the first slot only, as co-authors have no names.
+func (p *drawing) drawAvatarFallback(slot int) error {
+	if slot > 0 || p.opts.Author == "" {
+		return p.drawAvatarPlaceholder(slot, avatarPlaceholder, "")
+	}
+
+	fill := p.opts.AvaFallbackColor
+
+	if fill == "" {
+		fill = avatarFallback
+	}
+
+	return p.drawAvatarPlaceholder(slot, fill, initials(p.opts.Author))
+}
+
+// drawAvatarPlaceholder fills the avatar slot with the HEX-color and draws the text in the middle of it.
+func (p *drawing) drawAvatarPlaceholder(slot int, fill, text string) error {
+	shape, err := p.drawAvatarBorder(slot)
+
+	if err != nil {
+		return err
+	}
+
+	if err := p.setColor(fill, nil); err != nil {
+		return fmt.Errorf("invalid avatar fallback color: %w", err)
+	}
+
+	avaX, avaY := p.avatarCenter(slot)
+	avaR := float64(p.opts.AvaD) / 2
+
+	drawShape(p.ctx, shape, avaX-avaR, avaY-avaR, avaR*2, avaR*2, float64(p.opts.AvaCornerRadius))
+	p.ctx.Fill()
+
+	if text == "" {
+		return nil
+	}
+
+	font, err := p.loadFont(FontSource{}, float64(p.opts.AvaD)*0.4)
+
+	if err != nil {
+		return fmt.Errorf("could not load a font face: %w", err)
+	}
+
+	p.ctx.SetFontFace(font)
+	p.ctx.SetColor(color.White)
+	p.ctx.DrawStringAnchored(text, avaX, avaY, 0.5, 0.5)
+
+	return nil
+}
+
+// drawAvatarBorder validates the avatar shape and draws the avatar border of that shape in the slot.
+func (p *drawing) drawAvatarBorder(slot int) (string, error) {
+	shape, err := p.avatarShape()
+
+	if err != nil {
+		return "", err
+	}
+
+	if p.opts.AvaBorderW == 0 {
+		return shape, nil
+	}
+
+	borderColor := p.opts.AvaBorderColor
+
+	if borderColor == "" {
+		borderColor = avatarBorderColor
+	}
+
+	if err := p.setColor(borderColor, nil); err != nil {
+		return "", fmt.Errorf("invalid avatar border color: %w", err)
+	}
+
+	avaX, avaY := p.avatarCenter(slot)
+	outerR := float64(p.opts.AvaD)/2 + float64(p.opts.AvaBorderW)
+
+	drawShape(p.ctx, shape, avaX-outerR, avaY-outerR, outerR*2, outerR*2, float64(p.opts.AvaCornerRadius+p.opts.AvaBorderW))
+	p.ctx.Fill()
+
+	return shape, nil
+}
+
+// avatarShape returns the avatar shape, a circle by default.
+func (p *drawing) avatarShape() (string, error) {
+	shape := p.opts.AvaShape
+
+	if shape == "" {
+		shape = ShapeCircle
+	}
+
+	if shape != ShapeCircle && shape != ShapeSquare && shape != ShapeRounded {
+		return "", fmt.Errorf("unknown avatar shape: %s", shape)
+	}
+
+	return shape, nil
+}
+
+// avatarCenter returns the center of the avatar in the slot.
+func (p *drawing) avatarCenter(slot int) (x, y float64) {
+	box := p.layout.Avatars[slot]
+
+	return box.X + box.W/2, box.Y + box.H/2
+}
+
+// avatarURLs returns AvaURL followed by AvaURLs.
+func (p *drawing) avatarURLs() []string {
+	urls := make([]string, 0, len(p.opts.AvaURLs)+1)
+
+	if p.opts.AvaURL != "" {
+		urls = append(urls, p.opts.AvaURL)
+	}
+
+	return append(urls, p.opts.AvaURLs...)
+}
+
+// maxAvatars returns how many avatars can be drawn before the rest are collapsed to a badge.
+func (p *drawing) maxAvatars() int {
+	if p.opts.MaxAvatars > 0 {
+		return p.opts.MaxAvatars
+	}
+
+	return defaultMaxAvatars
+}
+
+// avatarSlots returns the number of avatar slots taken by the avatars and the badge.
+// Without an author and avatars the avatar row is hidden, so there are no slots.
+func (p *drawing) avatarSlots() int {
+	urls := len(p.avatarURLs())
+
+	if urls > p.maxAvatars() {
+		return p.maxAvatars() + 1
+	}
+
+	if urls == 0 && p.opts.AvaD > 0 && p.opts.Author != "" {
+		return 1
+	}
+
+	return urls
+}
+
+// fetchContext returns the context the images are fetched with: limited by FetchTimeout
+// and carrying the retries, the size limit and the headers of the Options.
+func (p *drawing) fetchContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	cancel := func() {}
+
+	if timeout := p.opts.FetchTimeout; timeout > 0 {
+		if timeout > maxFetchTimeout {
+			timeout = maxFetchTimeout
+		}
+
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+	}
+
+	if p.opts.FetchRetries > 0 {
+		ctx = remote.WithRetries(ctx, p.opts.FetchRetries)
+	}
+
+	if p.opts.MaxImageBytes > 0 {
+		ctx = remote.WithMaxBytes(ctx, p.opts.MaxImageBytes)
+	}
+
+	if len(p.opts.FetchHeaders) > 0 {
+		ctx = remote.WithHeaders(ctx, p.opts.FetchHeaders)
+	}
+
+	return ctx, cancel
+}
+
+// fetch gets the required resources failing on any error, and the optional ones concurrently
+// leaving out those that failed.
+func (p *Preview) fetch(ctx context.Context, required, optional map[string]string) (map[string][]byte, error) {
+	bufs := make(map[string][]byte, len(required)+len(optional))
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+
+	wg.Add(len(optional))
+
+	for key, urlOrPath := range optional {
+		go func(key, urlOrPath string) {
+			defer wg.Done()
+
+			got, err := p.getter().GetAll(ctx, map[string]string{key: urlOrPath})
+
+			if err != nil {
+				p.logger.Printf("skipping an optional resource: %s", err)
+				return
+			}
+
+			mu.Lock()
+			bufs[key] = got[key]
+			mu.Unlock()
+		}(key, urlOrPath)
+	}
+
+	var got map[string][]byte
+	var err error
+
+	if len(required) > 0 {
+		got, err = p.getter().GetAll(ctx, required)
+	}
+
+	wg.Wait()
+
+	if err != nil && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return nil, fmt.Errorf("timed out: %w", err)
+	} else if err != nil {
+		return nil, err
+	}
+
+	for key, buf := range got {
+		bufs[key] = buf
+	}
+
+	return bufs, nil
+}
+
+// initials returns 1-2 uppercase initials of the first and the last words of the name.
+func initials(name string) string {
+	var letters []rune
+
+	for _, word := range strings.Fields(name) {
+		for _, r := range word {
+			if unicode.IsLetter(r) || unicode.IsDigit(r) {
+				letters = append(letters, unicode.ToUpper(r))
+				break
+			}
+		}
+	}
+
+	if len(letters) > 2 {
+		letters = []rune{letters[0], letters[len(letters)-1]}
+	}
+
+	return string(letters)
+}
+
+func (p *drawing) drawAuthor() error {
+	if p.opts.Author == "" {
+		return nil
+	}
+
+	font, err := p.loadFont(textFontSource(p.opts.AuthorFont, p.opts.AuthorWeight), p.opts.AuthorSize)
+
+	if err != nil {
+		return fmt.Errorf("could not load the author font: %w", err)
+	}
+
+	p.ctx.SetFontFace(font)
+
+	authorColor := defaultAuthorColor
+
+	if p.opts.AuthorColor != "" {
+		if authorColor, err = parseHexColor(p.opts.AuthorColor); err != nil {
+			return fmt.Errorf("invalid author color: %w", err)
+		}
+	}
+
+	// the opacity is folded into the alpha of the color
+	authorColor.A = uint8(float64(authorColor.A)*elementOpacity(p.opts.AuthorOpacity) + 0.5)
+	p.ctx.SetColor(authorColor)
+
+	box := p.layout.Author
+
+	return p.drawStringAnchored(p.opts.Author, box.X, box.Y+box.H, 0, 0, textStyle{face: font, tracking: p.opts.AuthorTracking})
+}
+
+func (p *drawing) drawMeta() error {
+	if p.opts.Meta == "" {
+		return nil
+	}
+
+	font, err := p.loadFont(textFontSource(p.opts.AuthorFont, p.opts.AuthorWeight), p.opts.AuthorSize*metaScale)
+
+	if err != nil {
+		return fmt.Errorf("could not load the meta font: %w", err)
+	}
+
+	p.ctx.SetFontFace(font)
+
+	if err := p.setColor(p.opts.MetaColor, defaultMetaColor); err != nil {
+		return fmt.Errorf("invalid meta color: %w", err)
+	}
+
+	box := p.layout.Meta
+
+	return p.drawStringAnchored(p.opts.Meta, box.X, box.Y+box.H, 0, 0, textStyle{face: font, tracking: p.opts.AuthorTracking})
+}
+
+func (p *drawing) drawTitle() error {
+	font, err := p.loadFont(textFontSource(p.opts.TitleFont, p.opts.TitleWeight), p.opts.TitleSize)
+
+	if err != nil {
+		return fmt.Errorf("could not load the title font: %w", err)
+	}
+
+	p.ctx.SetFontFace(font)
+
+	if err := p.setColor(p.opts.TitleColor, color.White); err != nil {
+		return fmt.Errorf("invalid title color: %w", err)
+	}
 
-type getter interface {
-	GetAll(context.Context, map[string]string) (map[string][]byte, error)
+	titleX, titleY, maxWidth := p.layout.Title.X, p.layout.Title.Y, p.layout.Title.W
+	style := p.titleStyle(font)
+	align := p.titleAlign(style)
+
+	if p.opts.TitleBand {
+		if err := p.drawTitleBand(align, style); err != nil {
+			return err
+		}
+
+		// the band has replaced the title color
+		if err := p.setColor(p.opts.TitleColor, color.White); err != nil {
+			return fmt.Errorf("invalid title color: %w", err)
+		}
+	}
+
+	if p.opts.TitleShadow {
+		if err := p.drawTitleShadow(titleX, titleY, maxWidth, align, style); err != nil {
+			return err
+		}
+	}
+
+	if p.opts.TitleStrokeWidth > 0 {
+		if err := p.drawTitleStroke(titleX, titleY, maxWidth, align, style); err != nil {
+			return err
+		}
+
+		// the stroke has replaced the title color
+		if err := p.setColor(p.opts.TitleColor, color.White); err != nil {
+			return fmt.Errorf("invalid title color: %w", err)
+		}
+	}
+
+	return p.drawStringWrapped(strings.Join(p.layout.TitleLines, "\n"), titleX, titleY, maxWidth, p.opts.TitleLineHeight, align, style)
 }
 
-// Options defines a set of options required to draw a p.ctx.
-type Options struct {
-	// Canvas width
-	CanvasW int
-	// Canvas height
-	CanvasH int
-	// Opacity value for the black foreground under the title
-	Opacity float64
-	// Avatar diameter
-	AvaD  int
-	Title string
-	// Title font size
-	TitleSize float64
-	Author    string
-	// Author font size
-	AuthorSize float64
-	// Logo left part text (optional)
-	LabelL string
-	// Logo right part text (optional)
-	LabelR string
-	// Label font size
-	LabelSize float64
-	// Either an URL to a remote background image, or filename of the local image, or a HEX-color
-	// An image will be thumbnailed and smart-cropped if it's not of the canvas size
-	Bg string
-	// An URL to an author avatar pic
-	AvaURL string
-	// An URL to a logo image
-	LogoURL string
-	// Logo height
-	LogoH int
-	// Resulting JPEG quality
-	Quality int
+// titleAlign returns the horizontal alignment of the title drawn with the style.
+func (p *drawing) titleAlign(style textStyle) gg.Align {
+	switch p.opts.TitleAlign {
+	case AlignCenter:
+		return gg.AlignCenter
+	case AlignRight:
+		return gg.AlignRight
+	case "":
+		// a right-to-left title is aligned to the right by default
+		if style.rtl {
+			return gg.AlignRight
+		}
+	}
+
+	return gg.AlignLeft
 }
 
-// Preview can draw a preview using the provided Options.
-type Preview struct {
-	opts   *Options
-	ctx    *gg.Context
-	remote getter
+// titleStyle returns the style the title is drawn with the face.
+func (p *drawing) titleStyle(face font.Face) textStyle {
+	title := p.titleText()
+	rtl := p.opts.TitleDirection == DirectionRTL || (p.opts.TitleDirection != DirectionLTR && isRTLText(title))
+
+	return textStyle{
+		face:       face,
+		tracking:   p.opts.TitleTracking,
+		colorEmoji: p.opts.ColorEmoji,
+		reorder:    rtl || hasRTL(title),
+		rtl:        rtl,
+	}
 }
 
-// New returns an initialized Preview.
-func New() *Preview {
-	return &Preview{
-		opts:   nil,
-		ctx:    nil,
-		remote: remote.New(),
+// titleRegion returns the box the title is drawn within: between the avatar row and the logo row.
+// The title takes the room of a hidden avatar row.
+func (p *drawing) titleRegion() (x, top, maxWidth, bottom float64) {
+	x = p.opts.Padding
+	top = p.opts.Padding + p.tagsH
+
+	if p.rowH > 0 {
+		top += p.rowH + p.opts.Padding
 	}
+
+	maxWidth = float64(p.opts.CanvasW) - p.opts.Padding - p.opts.Margin*2
+	bottom = float64(p.opts.CanvasH) - p.opts.Padding*2 - float64(p.opts.LogoH)
+
+	return
 }
 
-// Draw draws a preview using the provided Options.
-func (p *Preview) Draw(ctx context.Context, opts Options) (image.Image, error) {
-	p.opts = &opts
-	p.ctx = gg.NewContext(opts.CanvasW, opts.CanvasH)
-	bgColor := defaultBgColor
-	isBgHEX := hexRe.Match([]byte(p.opts.Bg))
-	urlsOrPaths := map[string]string{logoKey: p.opts.LogoURL}
+// titlePosition returns the top left corner of the title block aligned according to TitleVAlign and its max width.
+func (p *drawing) titlePosition() (x, y, maxWidth float64, err error) {
+	x, top, maxWidth, bottom := p.titleRegion()
 
-	if opts.AvaURL != "" {
-		urlsOrPaths[avaKey] = p.opts.AvaURL
+	if p.opts.TitleVAlign != VAlignMiddle && p.opts.TitleVAlign != VAlignBottom {
+		return x, top, maxWidth, nil
 	}
 
-	if isBgHEX {
-		bgColor = p.opts.Bg
-	} else if p.opts.Bg != "" {
-		urlsOrPaths[bgKey] = p.opts.Bg
+	h, err := p.measureTitle(p.opts.TitleSize)
+
+	if err != nil {
+		return 0, 0, 0, err
 	}
 
-	imgBufs, err := p.remote.GetAll(ctx, urlsOrPaths)
+	// the subtitle is aligned together with the title
+	subtitleH, err := p.subtitleHeight(p.opts.TitleSize)
 
 	if err != nil {
-		return nil, fmt.Errorf("could not get an image: %w", err)
+		return 0, 0, 0, err
 	}
 
-	if isBgHEX || p.opts.Bg == "" {
-		if err := p.drawBackground(nil, bgColor); err != nil {
-			return nil, err
-		}
+	h += subtitleH
+
+	if p.opts.TitleVAlign == VAlignMiddle {
+		y = top + (bottom-top-h)/2
 	} else {
-		if err := p.drawBackground(imgBufs[bgKey], bgColor); err != nil {
-			return nil, err
-		}
+		y = bottom - h
 	}
 
-	if err := p.drawForeground(); err != nil {
-		return nil, err
+	// a title that doesn't fit still starts right below the avatar row
+	return x, math.Max(y, top), maxWidth, nil
+}
+
+// titleText returns the title with the normalized line breaks trimmed to maxTitleLength.
+func (p *drawing) titleText() string {
+	return truncateTitle(p.normalizedTitle(), maxTitleLength)
+}
+
+// titleLines returns the title wrapped to the width and cut to MaxTitleLines with an ellipsis ending the last line,
+// and whether it was cut.
+func (p *drawing) titleLines(width float64, style textStyle) ([]string, bool) {
+	p.ctx.SetFontFace(style.face)
+
+	lines := p.wordWrap(p.titleText(), width, style)
+
+	if max := p.opts.MaxTitleLines; max > 0 && len(lines) > max {
+		lines = lines[:max]
+		// the ellipsis of the runes cut is on a dropped line if any
+		lines[max-1] = p.ellipsize(strings.TrimSuffix(lines[max-1], "…"), width, style)
+
+		return lines, true
 	}
 
-	if _, exists := imgBufs[avaKey]; exists {
-		if err := p.drawAvatar(imgBufs[avaKey]); err != nil {
-			return nil, err
+	return lines, false
+}
+
+// normalizedTitle returns the title with the normalized line breaks.
+func (p *drawing) normalizedTitle() string {
+	title := strings.ReplaceAll(p.opts.Title, "\r\n", "\n")
+
+	// a trailing line break would only add an empty line
+	return strings.TrimRightFunc(title, unicode.IsSpace)
+}
+
+// truncateTitle trims the string to max runes without splitting words and appends an ellipsis.
+// It cuts in the middle of a word only when there is no whitespace to break at (e.g. a very long word or CJK text).
+// The line breaks don't count towards max.
+func truncateTitle(s string, max int) string {
+	runes := []rune(s)
+	cut, visible := 0, 0
+
+	for ; cut < len(runes); cut++ {
+		if runes[cut] == '\n' {
+			continue
+		}
+
+		if visible == max {
+			break
 		}
+
+		visible++
 	}
 
-	if err := p.drawAuthor(); err != nil {
-		return nil, err
+	if cut == len(runes) {
+		return s
 	}
 
-	if err := p.drawTitle(); err != nil {
-		return nil, err
+	hardCut := cut
+
+	// the cut is already on a word boundary if the next rune is a whitespace
+	if !unicode.IsSpace(runes[cut]) {
+		for cut > 0 && !unicode.IsSpace(runes[cut-1]) {
+			cut--
+		}
 	}
 
-	if err := p.drawLogo(imgBufs[logoKey]); err != nil {
-		return nil, err
+	head := strings.TrimRightFunc(string(runes[:cut]), unicode.IsSpace)
+
+	if head == "" {
+		head = string(runes[:hardCut])
 	}
 
-	return p.ctx.Image(), nil
+	return head + "…"
 }
 
-func (p *Preview) drawBackground(bgBuf []byte, bgColor string) error {
-	if bgBuf == nil {
-		p.ctx.SetHexColor(bgColor)
-		p.ctx.DrawRectangle(0, 0, float64(p.opts.CanvasW), float64(p.opts.CanvasH))
-		p.ctx.Fill()
+// measureTitle returns the height of the wrapped title drawn with the font size.
+func (p *drawing) measureTitle(size float64) (float64, error) {
+	font, err := p.loadFont(textFontSource(p.opts.TitleFont, p.opts.TitleWeight), size)
 
-		return nil
+	if err != nil {
+		return 0, fmt.Errorf("could not load the title font: %w", err)
 	}
 
-	bgBuf, err := resize(bgBuf, p.opts.CanvasW, p.opts.CanvasH)
+	_, _, maxWidth, _ := p.titleRegion()
+	lines, _ := p.titleLines(maxWidth, p.titleStyle(font))
+	_, h := p.ctx.MeasureMultilineString(strings.Join(lines, "\n"), p.opts.TitleLineHeight)
 
-	if err != nil {
-		return fmt.Errorf("could not resize the background: %w", err)
+	return h, nil
+}
+
+// fitTitle decreases the title font size until the wrapped title and the subtitle fit above the logo
+// or the min size is reached.
+func (p *drawing) fitTitle() error {
+	minSize := p.opts.MinTitleSize
+
+	if minSize == 0 {
+		minSize = minTitleSize
 	}
 
-	bgImg, _, err := image.Decode(bytes.NewReader(bgBuf))
+	_, top, _, bottom := p.titleRegion()
+	maxH := bottom - top
+	size := p.opts.TitleSize
 
-	if err != nil {
-		return fmt.Errorf("could not decode the background: %w", err)
+	for size > minSize {
+		h, err := p.measureTitle(size)
+
+		if err != nil {
+			return err
+		}
+
+		subtitleH, err := p.subtitleHeight(size)
+
+		if err != nil {
+			return err
+		}
+
+		h += subtitleH
+
+		font, err := p.loadFont(textFontSource(p.opts.TitleFont, p.opts.TitleWeight), size)
+
+		if err != nil {
+			return fmt.Errorf("could not load the title font: %w", err)
+		}
+
+		// the descenders of the last line hang below the measured height
+		if h+float64(font.Metrics().Descent)/64 <= maxH {
+			break
+		}
+
+		size = math.Max(size-titleSizeStep, minSize)
 	}
 
-	p.ctx.DrawImage(bgImg, 0, 0)
+	p.opts.TitleSize = size
 
 	return nil
 }
 
-func (p *Preview) drawForeground() error {
-	p.ctx.SetColor(color.RGBA{0, 0, 0, uint8(255.0 * p.opts.Opacity)})
-	p.ctx.DrawRectangle(margin, margin, float64(p.opts.CanvasW)-(margin*2), float64(p.opts.CanvasH)-(margin*2))
-	p.ctx.Fill()
+// pickContrastColors sets black or white text colors depending on the luminance of the area behind the title.
+// It must be called after the background and foreground are drawn.
+func (p *drawing) pickContrastColors() error {
+	box := p.layout.Title
+	area := image.Rect(int(box.X), int(box.Y), int(box.X+box.W), int(box.Y+box.H))
+
+	if luminance(p.ctx.Image(), area) > 0.5 {
+		p.opts.TitleColor = "#000000"
+		p.opts.AuthorColor = "#000000CC"
+		p.opts.MetaColor = "#00000099"
+		p.opts.SubtitleColor = "#000000B3"
+	} else {
+		p.opts.TitleColor = "#FFFFFF"
+		p.opts.AuthorColor = "#FFFFFFCC"
+		p.opts.MetaColor = "#FFFFFF99"
+		p.opts.SubtitleColor = "#FFFFFFB3"
+	}
 
 	return nil
 }
 
-func (p *Preview) drawAvatar(avaBuf []byte) error {
-	// draw the avatar border circle
-	avaX := padding + float64(p.opts.AvaD+border)/2
-	avaY := padding + float64(p.opts.AvaD+border)/2
+// drawLogo draws the logo image in its box.
+func (p *drawing) drawLogo(logoImg image.Image) {
+	p.drawImageFaded(logoImg, int(p.layout.Logo.X), int(p.layout.Logo.Y), p.opts.LogoOpacity)
+}
 
-	p.ctx.DrawCircle(avaX, avaY, float64((p.opts.AvaD+8)/2))
-	p.ctx.SetHexColor(avatarBorderColor)
-	p.ctx.Fill()
+// drawImageFaded draws the image with its top left corner at x, y blended with the opacity (see elementOpacity).
+func (p *drawing) drawImageFaded(img image.Image, x, y int, opacity float64) {
+	opacity = elementOpacity(opacity)
 
-	// draw the avatar itself (cropped to a circle)
-	avaBuf, err := resize(avaBuf, p.opts.AvaD, p.opts.AvaD)
+	if opacity == 1 {
+		p.ctx.DrawImage(img, x, y)
 
-	if err != nil {
-		return fmt.Errorf("could not resize the avatar: %w", err)
+		return
 	}
 
-	avaImg, _, err := image.Decode(bytes.NewReader(avaBuf))
+	// blend the image through a uniform alpha mask
+	mask := image.NewUniform(color.Alpha{A: uint8(opacity * 255)})
+	dst := p.ctx.Image().(draw.Image)
+	r := img.Bounds().Sub(img.Bounds().Min).Add(image.Pt(x, y))
 
-	if err != nil {
-		return fmt.Errorf("could not decode the avatar: %w", err)
+	draw.DrawMask(dst, r, img, img.Bounds().Min, mask, image.Point{}, draw.Over)
+}
+
+// elementOpacity returns the opacity of an element within 0-1: fully opaque if zero, hidden if negative.
+func elementOpacity(opacity float64) float64 {
+	if opacity == 0 {
+		return 1
 	}
 
-	avaImg = circle(avaImg)
+	return math.Max(math.Min(opacity, 1), 0)
+}
 
-	p.ctx.DrawImageAnchored(avaImg, int(avaX), int(avaY), 0.5, 0.5)
+// logoCorner validates LogoPosition and returns the corner the logo is placed in.
+func (p *drawing) logoCorner() (right, bottom bool, err error) {
+	switch p.opts.LogoPosition {
+	case "", LogoBottomRight:
+		return true, true, nil
+	case LogoBottomLeft:
+		return false, true, nil
+	case LogoTopRight:
+		return true, false, nil
+	case LogoTopLeft:
+		return false, false, nil
+	}
 
-	return nil
+	return false, false, fmt.Errorf("unknown logo position: %s", p.opts.LogoPosition)
 }
 
-func (p *Preview) drawAuthor() error {
-	if p.opts.Author == "" {
+// drawLabel draws LabelL and LabelR as a two-colored text logo to the left of the logo image (if any).
+func (p *drawing) drawLabel() error {
+	if p.opts.LabelL == "" && p.opts.LabelR == "" {
 		return nil
 	}
 
-	font, err := loadFont(p.opts.AuthorSize)
+	font, err := p.loadFont(FontSource{}, p.opts.LabelSize)
 
 	if err != nil {
 		return fmt.Errorf("could not load a font face: %w", err)
 	}
 
 	p.ctx.SetFontFace(font)
-	p.ctx.SetColor(color.RGBA{R: 255, G: 255, B: 255, A: 204})
 
-	authorX := padding + float64(p.opts.AvaD) + padding/2
-	authorY := padding + float64(p.opts.AvaD)/2
+	box := p.layout.Label
+	labelX, baseline := box.X+box.W, box.Y+box.H
+	labelRW, _ := p.ctx.MeasureString(p.opts.LabelR)
+
+	p.ctx.SetHexColor(labelRColor)
+	p.ctx.DrawStringAnchored(p.opts.LabelR, labelX, baseline, 1, 0)
+	p.ctx.SetHexColor(labelLColor)
+	p.ctx.DrawStringAnchored(p.opts.LabelL, labelX-labelRW, baseline, 1, 0)
+
+	return nil
+}
+
+// setColor sets a HEX-color or the fallback one if the HEX is empty.
+func (p *drawing) setColor(hex string, fallback color.Color) error {
+	if hex == "" {
+		p.ctx.SetColor(fallback)
 
-	p.ctx.DrawStringAnchored(p.opts.Author, authorX, authorY, 0, 0.5)
+		return nil
+	}
+
+	if !hexRe.MatchString(hex) {
+		return fmt.Errorf("not a HEX-color: %s", hex)
+	}
+
+	p.ctx.SetHexColor(hex)
 
 	return nil
 }
 
-func (p *Preview) drawTitle() error {
-	font, err := loadFont(p.opts.TitleSize)
+// parseHexColor converts a HEX-color (#RGB, #RRGGBB or #RRGGBBAA) to a color.
+func parseHexColor(hex string) (color.NRGBA, error) {
+	if !hexRe.MatchString(hex) {
+		return color.NRGBA{}, fmt.Errorf("not a HEX-color: %s", hex)
+	}
+
+	digits := hex[1:]
+
+	if len(digits) == 3 {
+		digits = string([]byte{digits[0], digits[0], digits[1], digits[1], digits[2], digits[2]})
+	}
+
+	if len(digits) == 6 {
+		digits += "FF"
+	}
+
+	v, err := strconv.ParseUint(digits, 16, 32)
 
 	if err != nil {
-		return fmt.Errorf("could not load a font face: %w", err)
+		return color.NRGBA{}, err
 	}
 
-	p.ctx.SetFontFace(font)
-	p.ctx.SetColor(color.White)
+	return color.NRGBA{R: uint8(v >> 24), G: uint8(v >> 16), B: uint8(v >> 8), A: uint8(v)}, nil
+}
 
-	titleX := padding
-	titleY := padding*2 + float64(p.opts.AvaD)
-	maxWidth := float64(p.opts.CanvasW) - padding - margin*2
-	title := p.opts.Title
+// loadImage loads an image into vips rotated upright according to its EXIF orientation.
+// Only the first frame of an animated GIF or WebP is loaded, so the animations are drawn as the stills.
+func loadImage(buf []byte) (*vips.ImageRef, error) {
+	params := vips.NewImportParams()
+	params.Page.Set(0)
+	params.NumPages.Set(1)
 
-	if utf8.RuneCountInString(title) > maxTitleLength {
-		title = string([]rune(title)[0:maxTitleLength]) + "…"
+	vipsImg, err := vips.LoadImageFromBuffer(buf, params)
+
+	if err != nil {
+		return nil, err
 	}
 
-	p.ctx.DrawStringWrapped(title, titleX, titleY, 0, 0, maxWidth, 1.2, gg.AlignLeft)
+	if err = vipsImg.AutoRotate(); err != nil {
+		vipsImg.Close()
 
-	return nil
+		return nil, fmt.Errorf("could not rotate an image: %w", err)
+	}
+
+	return vipsImg, nil
 }
 
-func (p *Preview) drawLogo(logoBuf []byte) error {
-	logoBuf, err := scale(logoBuf, p.opts.LogoH)
+// resize resizes an image to the specified width and height if it differs from them.
+// In case the aspect ratio of the source image differs from w/h parameters, it crops it to the area picked by crop.
+// A positive blur sigma blurs the resized image.
+// SVGs are rasterized at the target size first.
+func (p *Preview) resize(buf []byte, w, h int, blur float64, crop vips.Interesting) ([]byte, error) {
+	if isSVG(buf) {
+		var err error
+
+		if buf, err = p.rasterizeSVG(buf, w, h, crop); err != nil {
+			return nil, err
+		}
+	}
+
+	config, _, err := image.DecodeConfig(bytes.NewReader(buf))
 
 	if err != nil {
-		return fmt.Errorf("could not resize the logo: %w", err)
+		return nil, err
 	}
 
-	logoImg, _, err := image.Decode(bytes.NewReader(logoBuf))
+	// an image to be rotated is never of the same size
+	sameSize := config.Width == w && config.Height == h && exifOrientation(buf) == orientationUpright
+
+	if sameSize && blur <= 0 {
+		return buf, nil
+	}
+
+	vipsImg, err := loadImage(buf)
 
 	if err != nil {
-		return fmt.Errorf("could not decode the logo: %w", err)
+		return nil, err
 	}
 
-	logoX := p.opts.CanvasW - padding - logoImg.Bounds().Dx()
-	logoY := p.opts.CanvasH - padding - p.opts.LogoH
+	defer vipsImg.Close()
 
-	p.ctx.DrawImage(logoImg, logoX, logoY)
+	if !sameSize {
+		p.logger.Printf("Resizing an image to %dx%d px", w, h)
 
-	return nil
+		if err = vipsImg.Thumbnail(w, h, crop); err != nil {
+			return nil, err
+		}
+	}
+
+	if blur > 0 {
+		blur = math.Min(blur, maxBlurSigma)
+
+		p.logger.Printf("Blurring an image with sigma %.1f", blur)
+
+		if err = vipsImg.GaussianBlur(blur); err != nil {
+			return nil, err
+		}
+	}
+
+	buf, _, err = vipsImg.Export(vips.NewDefaultExportParams())
+
+	if err != nil {
+		return nil, err
+	}
+
+	return buf, nil
 }
 
-// resize resizes an image to the specified width and height if it differs from them.
-// In case the aspect ratio of the source image differs from w/h parameters, it crops it to the area of interest.
-func resize(buf []byte, w, h int) ([]byte, error) {
+// imageSize returns the size of an image, or the intrinsic size of an SVG.
+func imageSize(buf []byte) (w, h int, err error) {
+	if isSVG(buf) {
+		svgW, svgH := svgSize(buf)
+
+		if svgW <= 0 || svgH <= 0 {
+			return 0, 0, fmt.Errorf("the SVG has no intrinsic size")
+		}
+
+		return int(svgW + 0.5), int(svgH + 0.5), nil
+	}
+
+	config, _, err := image.DecodeConfig(bytes.NewReader(buf))
+
+	if err != nil {
+		return 0, 0, err
+	}
+
+	// the orientations from 5 on are rotated by 90 degrees
+	if exifOrientation(buf) >= 5 {
+		return config.Height, config.Width, nil
+	}
+
+	return config.Width, config.Height, nil
+}
+
+// stretch resizes an image to the specified width and height ignoring its aspect ratio.
+// SVGs are rasterized to fit the size first.
+func (p *Preview) stretch(buf []byte, w, h int) ([]byte, error) {
+	if isSVG(buf) {
+		var err error
+
+		if buf, err = p.rasterizeSVG(buf, w, h, vips.InterestingNone); err != nil {
+			return nil, err
+		}
+	}
+
+	config, _, err := image.DecodeConfig(bytes.NewReader(buf))
+
+	if err != nil {
+		return nil, err
+	}
+
+	if config.Width == w && config.Height == h && exifOrientation(buf) == orientationUpright {
+		return buf, nil
+	}
+
+	p.logger.Printf("Stretching an image to %dx%d px", w, h)
+
+	vipsImg, err := loadImage(buf)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer vipsImg.Close()
+
+	hScale := float64(w) / float64(vipsImg.Width())
+	vScale := float64(h) / float64(vipsImg.Height())
+
+	if err = vipsImg.ResizeWithVScale(hScale, vScale, vips.KernelAuto); err != nil {
+		return nil, err
+	}
+
+	buf, _, err = vipsImg.Export(vips.NewDefaultExportParams())
+
+	if err != nil {
+		return nil, err
+	}
+
+	return buf, nil
+}
+
+// cropAround resizes an image to cover the specified width and height keeping its aspect ratio
+// and crops it around the focus point as close to the center as the image edges allow.
+// SVGs are rasterized at the covering size first.
+func (p *Preview) cropAround(buf []byte, w, h int, focus Focus) ([]byte, error) {
+	if isSVG(buf) {
+		coverW, coverH := w, h
+
+		if svgW, svgH := svgSize(buf); svgW > 0 && svgH > 0 {
+			scale := math.Max(float64(w)/svgW, float64(h)/svgH)
+			coverW, coverH = int(math.Ceil(svgW*scale)), int(math.Ceil(svgH*scale))
+		}
+
+		var err error
+
+		if buf, err = p.rasterizeSVG(buf, coverW, coverH, vips.InterestingNone); err != nil {
+			return nil, err
+		}
+	}
+
 	config, _, err := image.DecodeConfig(bytes.NewReader(buf))
 
 	if err != nil {
 		return nil, err
 	}
 
-	if config.Width == w && config.Height == h {
+	if config.Width == w && config.Height == h && exifOrientation(buf) == orientationUpright {
 		return buf, nil
 	}
 
-	log.Printf("Resizing an image to %dx%d px", w, h)
+	p.logger.Printf("Cropping an image to %dx%d px around %.2f,%.2f", w, h, focus.X, focus.Y)
 
-	vipsImg, err := vips.NewImageFromBuffer(buf)
+	vipsImg, err := loadImage(buf)
 
 	if err != nil {
 		return nil, err
@@ -297,7 +2132,19 @@ func resize(buf []byte, w, h int) ([]byte, error) {
 
 	defer vipsImg.Close()
 
-	if err = vipsImg.Thumbnail(w, h, vips.InterestingAttention); err != nil {
+	if scale := math.Max(float64(w)/float64(vipsImg.Width()), float64(h)/float64(vipsImg.Height())); scale != 1 {
+		if err = vipsImg.Resize(scale, vips.KernelAuto); err != nil {
+			return nil, err
+		}
+	}
+
+	// the rounding may leave the scaled image a pixel short
+	imgW, imgH := vipsImg.Width(), vipsImg.Height()
+	cropW, cropH := int(math.Min(float64(w), float64(imgW))), int(math.Min(float64(h), float64(imgH)))
+	left := math.Max(0, math.Min(focus.X*float64(imgW)-float64(cropW)/2, float64(imgW-cropW)))
+	top := math.Max(0, math.Min(focus.Y*float64(imgH)-float64(cropH)/2, float64(imgH-cropH)))
+
+	if err = vipsImg.ExtractArea(int(left+0.5), int(top+0.5), cropW, cropH); err != nil {
 		return nil, err
 	}
 
@@ -311,20 +2158,25 @@ func resize(buf []byte, w, h int) ([]byte, error) {
 }
 
 // scale resizes an image to the specified height if it differs. Width of the image is auto.
-func scale(buf []byte, h int) ([]byte, error) {
+// SVGs are rasterized at the target height.
+func (p *Preview) scale(buf []byte, h int) ([]byte, error) {
+	if isSVG(buf) {
+		return p.rasterizeSVG(buf, 0, h, vips.InterestingAttention)
+	}
+
 	config, _, err := image.DecodeConfig(bytes.NewReader(buf))
 
 	if err != nil {
 		return nil, err
 	}
 
-	if config.Height == h {
+	if config.Height == h && exifOrientation(buf) == orientationUpright {
 		return buf, nil
 	}
 
-	log.Printf("Scaling an image to %dpx height", h)
+	p.logger.Printf("Scaling an image to %dpx height", h)
 
-	vipsImg, err := vips.NewImageFromBuffer(buf)
+	vipsImg, err := loadImage(buf)
 
 	if err != nil {
 		return nil, err
@@ -332,7 +2184,7 @@ func scale(buf []byte, h int) ([]byte, error) {
 
 	defer vipsImg.Close()
 
-	if err = vipsImg.Resize(float64(h)/float64(config.Height), vips.KernelAuto); err != nil {
+	if err = vipsImg.Resize(float64(h)/float64(vipsImg.Height()), vips.KernelAuto); err != nil {
 		return nil, err
 	}
 
@@ -345,25 +2197,47 @@ func scale(buf []byte, h int) ([]byte, error) {
 	return buf, nil
 }
 
-// circle crops circle out of a rectangle source image.
-func circle(src image.Image) image.Image {
-	log.Printf("Circling an image")
+// luminance returns the average relative luminance (0-1) of the image area.
+func luminance(img image.Image, area image.Rectangle) float64 {
+	area = area.Intersect(img.Bounds())
+
+	if area.Empty() {
+		return 0
+	}
 
-	r := int(math.Min(
-		float64(src.Bounds().Dx()),
-		float64(src.Bounds().Dy()),
-	) / 2)
+	sum := 0.0
 
-	p := image.Point{
-		X: src.Bounds().Dx() / 2,
-		Y: src.Bounds().Dy() / 2,
+	for y := area.Min.Y; y < area.Max.Y; y++ {
+		for x := area.Min.X; x < area.Max.X; x++ {
+			r, g, b, _ := img.At(x, y).RGBA()
+			sum += (0.2126*float64(r) + 0.7152*float64(g) + 0.0722*float64(b)) / 0xFFFF
+		}
 	}
 
+	return sum / float64(area.Dx()*area.Dy())
+}
+
+// maskShape crops the shape out of a rectangle source image.
+func (p *Preview) maskShape(src image.Image, shape string, radius float64) image.Image {
+	p.logger.Printf("Masking an image with a %s shape", shape)
+
 	mask := gg.NewContextForRGBA(image.NewRGBA(src.Bounds()))
 
-	mask.DrawCircle(float64(p.X), float64(p.Y), float64(r))
+	drawShape(mask, shape, 0, 0, float64(src.Bounds().Dx()), float64(src.Bounds().Dy()), radius)
 	mask.Clip()
 	mask.DrawImage(src, 0, 0)
 
 	return mask.Image()
 }
+
+// drawShape adds a circle, square or rounded square path inscribed in the rectangle to the context.
+func drawShape(ctx *gg.Context, shape string, x, y, w, h, radius float64) {
+	switch shape {
+	case ShapeSquare:
+		ctx.DrawRectangle(x, y, w, h)
+	case ShapeRounded:
+		ctx.DrawRoundedRectangle(x, y, w, h, radius)
+	default:
+		ctx.DrawCircle(x+w/2, y+h/2, math.Min(w, h)/2)
+	}
+}
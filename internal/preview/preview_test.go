@@ -0,0 +1,1847 @@
+package preview
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/gif"
+	"image/png"
+	"log"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/davidbyttow/govips/v2/vips"
+	"github.com/nDmitry/ogimgd/internal/remote"
+	"golang.org/x/image/font"
+)
+
+func TestMain(m *testing.M) {
+	vips.LoggingSettings(nil, vips.LogLevelError)
+	// the renders of all the tests run with the custom config
+	Startup(Config{VipsConcurrency: 2, VipsCacheMax: 50})
+
+	code := m.Run()
+
+	Shutdown()
+	os.Exit(code)
+}
+
+func testOptions() Options {
+	return Options{
+		CanvasW:   1200,
+		CanvasH:   630,
+		Bg:        "#000000",
+		Title:     "Test",
+		TitleSize: 76,
+		LabelSize: 40,
+		LogoH:     48,
+		Quality:   84,
+	}
+}
+
+// hasInk reports whether any pixel inside the rectangle is noticeably lighter than a black background.
+func hasInk(img image.Image, rect image.Rectangle) bool {
+	for y := rect.Min.Y; y < rect.Max.Y; y++ {
+		for x := rect.Min.X; x < rect.Max.X; x++ {
+			r, g, b, _ := img.At(x, y).RGBA()
+
+			if r>>8 > 64 || g>>8 > 64 || b>>8 > 64 {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// maxRGB returns the maximum value of each channel among the pixels inside the rectangle.
+func maxRGB(img image.Image, rect image.Rectangle) (uint32, uint32, uint32) {
+	var maxR, maxG, maxB uint32
+
+	for y := rect.Min.Y; y < rect.Max.Y; y++ {
+		for x := rect.Min.X; x < rect.Max.X; x++ {
+			r, g, b, _ := img.At(x, y).RGBA()
+
+			if r>>8 > maxR {
+				maxR = r >> 8
+			}
+
+			if g>>8 > maxG {
+				maxG = g >> 8
+			}
+
+			if b>>8 > maxB {
+				maxB = b >> 8
+			}
+		}
+	}
+
+	return maxR, maxG, maxB
+}
+
+func TestDrawLabel(t *testing.T) {
+	// logo.png is 349x48 px, so it occupies x 803..1152 at the bottom right
+	logoRect := image.Rect(803, 534, 1152, 582)
+	besideLogoRect := image.Rect(400, 520, 779, 600)
+
+	testCases := []struct {
+		name      string
+		logoURL   string
+		labelL    string
+		labelR    string
+		logoInk   bool
+		besideInk bool
+	}{{
+		name:      "label only",
+		labelL:    "Vyshka",
+		labelR:    "Club",
+		logoInk:   true,
+		besideInk: false,
+	}, {
+		name:      "logo only",
+		logoURL:   "logo.png",
+		logoInk:   true,
+		besideInk: false,
+	}, {
+		name:      "both",
+		logoURL:   "logo.png",
+		labelL:    "Vyshka",
+		labelR:    "Club",
+		logoInk:   true,
+		besideInk: true,
+	}}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := testOptions()
+			opts.LogoURL = tt.logoURL
+			opts.LabelL = tt.labelL
+			opts.LabelR = tt.labelR
+
+			img, err := New().Draw(context.Background(), opts)
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if hasInk(img, logoRect) != tt.logoInk {
+				t.Errorf("expected ink in the logo corner: %t", tt.logoInk)
+			}
+
+			if hasInk(img, besideLogoRect) != tt.besideInk {
+				t.Errorf("expected ink beside the logo: %t", tt.besideInk)
+			}
+		})
+	}
+}
+
+func TestDrawTextColors(t *testing.T) {
+	titleRect := image.Rect(48, 96, 600, 200)
+	authorRect := image.Rect(48, 20, 600, 90)
+
+	testCases := []struct {
+		name        string
+		titleColor  string
+		authorColor string
+		title       [3]uint32
+		author      [3]uint32
+	}{{
+		name:   "defaults",
+		title:  [3]uint32{255, 255, 255},
+		author: [3]uint32{204, 204, 204},
+	}, {
+		name:        "6-digit",
+		titleColor:  "#FF0000",
+		authorColor: "#00FF00",
+		title:       [3]uint32{255, 0, 0},
+		author:      [3]uint32{0, 255, 0},
+	}, {
+		name:        "8-digit author",
+		titleColor:  "#F00",
+		authorColor: "#00FF0080",
+		title:       [3]uint32{255, 0, 0},
+		author:      [3]uint32{0, 128, 0},
+	}}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := testOptions()
+			opts.Author = "@Tester"
+			opts.AuthorSize = 36
+			opts.TitleColor = tt.titleColor
+			opts.AuthorColor = tt.authorColor
+
+			img, err := New().Draw(context.Background(), opts)
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if r, g, b := maxRGB(img, titleRect); [3]uint32{r, g, b} != tt.title {
+				t.Errorf("unexpected title color: %d %d %d", r, g, b)
+			}
+
+			r, g, b := maxRGB(img, authorRect)
+
+			// allow some rounding for semi-transparent colors
+			for i, c := range [3]uint32{r, g, b} {
+				if c+2 < tt.author[i] || c > tt.author[i]+2 {
+					t.Errorf("unexpected author color: %d %d %d", r, g, b)
+					break
+				}
+			}
+		})
+	}
+
+	opts := testOptions()
+	opts.TitleColor = "red"
+
+	if _, err := New().Draw(context.Background(), opts); err == nil {
+		t.Error("expected an error for a non-HEX title color")
+	}
+}
+
+func TestDrawAutoContrast(t *testing.T) {
+	titleRect := image.Rect(48, 96, 600, 200)
+
+	testCases := []struct {
+		name    string
+		bg      string
+		opacity float64
+		dark    bool
+	}{{
+		name: "white",
+		bg:   "#FFFFFF",
+		dark: true,
+	}, {
+		name: "black",
+		bg:   "#000000",
+		dark: false,
+	}, {
+		name:    "white darkened by the foreground",
+		bg:      "#FFFFFF",
+		opacity: 0.8,
+		dark:    false,
+	}}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := testOptions()
+			opts.Bg = tt.bg
+			opts.Opacity = tt.opacity
+			opts.AutoContrast = true
+
+			img, err := New().Draw(context.Background(), opts)
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			// find the title glyphs as the pixels that differ the most from the backdrop
+			bgR, _, _, _ := img.At(titleRect.Min.X, titleRect.Min.Y).RGBA()
+			r, _, _ := maxRGB(img, titleRect)
+			darkest := minR(img, titleRect)
+
+			if tt.dark && darkest >= bgR>>8 {
+				t.Errorf("expected dark text, backdrop: %d, darkest: %d", bgR>>8, darkest)
+			}
+
+			if !tt.dark && r <= bgR>>8 {
+				t.Errorf("expected light text, backdrop: %d, lightest: %d", bgR>>8, r)
+			}
+		})
+	}
+}
+
+func TestDrawNoOverlay(t *testing.T) {
+	// a point of the red part of the background inside the overlay, clear of the text
+	at := image.Pt(1100, 560)
+
+	redAt := func(opacity float64) uint32 {
+		opts := testOptions()
+		opts.Bg = mostlyRed(t)
+		opts.RequireBg = true
+		opts.Opacity = opacity
+
+		img, err := New().Draw(context.Background(), opts)
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		r, _, _, _ := img.At(at.X, at.Y).RGBA()
+
+		return r >> 8
+	}
+
+	// zero stands for the default overlay over an image
+	if r := redAt(0); r > 230*(1-DefaultOpacity)+2 {
+		t.Errorf("expected the default overlay to darken the background, got red %d", r)
+	}
+
+	if r := redAt(NoOverlay); r < 228 {
+		t.Errorf("expected no overlay over the background, got red %d", r)
+	}
+}
+
+func minR(img image.Image, rect image.Rectangle) uint32 {
+	min := uint32(255)
+
+	for y := rect.Min.Y; y < rect.Max.Y; y++ {
+		for x := rect.Min.X; x < rect.Max.X; x++ {
+			if r, _, _, _ := img.At(x, y).RGBA(); r>>8 < min {
+				min = r >> 8
+			}
+		}
+	}
+
+	return min
+}
+
+func TestDrawAvatarBorder(t *testing.T) {
+	testCases := []struct {
+		name    string
+		borderW int
+	}{{
+		name:    "no border",
+		borderW: 0,
+	}, {
+		name:    "even",
+		borderW: 4,
+	}, {
+		name:    "odd",
+		borderW: 3,
+	}}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := testOptions()
+			opts.AvaURL = "avatar.png"
+			opts.AvaD = 64
+			opts.AvaBorderW = tt.borderW
+			opts.AvaBorderColor = "#FF00FF"
+
+			img, err := New().Draw(context.Background(), opts)
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			center := int(DefaultPadding) + opts.AvaD/2 + tt.borderW
+			ringR := opts.AvaD/2 + tt.borderW
+
+			// the middle of the ring on the left side of the circle
+			r, g, b, _ := img.At(center-opts.AvaD/2-(tt.borderW+1)/2, center).RGBA()
+
+			if tt.borderW > 0 && (r>>8 != 0xFF || g>>8 != 0 || b>>8 != 0xFF) {
+				t.Errorf("expected a ring pixel, got: %d %d %d", r>>8, g>>8, b>>8)
+			}
+
+			// just outside of the ring
+			if hasInk(img, image.Rect(center-ringR-3, center, center-ringR-1, center+1)) {
+				t.Error("expected nothing outside of the ring")
+			}
+
+			if tt.borderW == 0 {
+				for y := center - ringR - 3; y < center+ringR+3; y++ {
+					for x := center - ringR - 3; x < center+ringR+3; x++ {
+						if r, g, b, _ := img.At(x, y).RGBA(); r>>8 == 0xFF && g>>8 == 0 && b>>8 == 0xFF {
+							t.Fatalf("expected no ring pixels, found one at %d,%d", x, y)
+						}
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestMaskAvatar(t *testing.T) {
+	src := image.NewRGBA(image.Rect(0, 0, 64, 64))
+	draw.Draw(src, src.Bounds(), image.NewUniform(color.White), image.Point{}, draw.Src)
+
+	testCases := []struct {
+		shape       string
+		transparent bool
+	}{{
+		shape:       ShapeCircle,
+		transparent: true,
+	}, {
+		shape:       ShapeRounded,
+		transparent: true,
+	}, {
+		shape:       ShapeSquare,
+		transparent: false,
+	}}
+
+	for _, tt := range testCases {
+		t.Run(tt.shape, func(t *testing.T) {
+			img := New().maskShape(src, tt.shape, 16)
+
+			for _, c := range [][2]int{{0, 0}, {63, 0}, {0, 63}, {63, 63}} {
+				_, _, _, a := img.At(c[0], c[1]).RGBA()
+
+				if (a == 0) != tt.transparent {
+					t.Errorf("unexpected corner alpha at %v: %d", c, a)
+				}
+			}
+
+			if _, _, _, a := img.At(32, 32).RGBA(); a != 0xFFFF {
+				t.Errorf("expected an opaque center, got alpha: %d", a)
+			}
+		})
+	}
+}
+
+func TestDrawAvatarShape(t *testing.T) {
+	for _, shape := range []string{ShapeCircle, ShapeSquare, ShapeRounded} {
+		t.Run(shape, func(t *testing.T) {
+			opts := testOptions()
+			opts.AvaURL = "avatar.png"
+			opts.AvaD = 64
+			opts.AvaBorderW = 4
+			opts.AvaShape = shape
+			opts.AvaCornerRadius = 12
+
+			img, err := New().Draw(context.Background(), opts)
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			// the top left corner of the border follows the shape
+			corner := hasInk(img, image.Rect(int(DefaultPadding), int(DefaultPadding), int(DefaultPadding)+1, int(DefaultPadding)+1))
+
+			if corner != (shape == ShapeSquare) {
+				t.Errorf("unexpected border corner: %t", corner)
+			}
+		})
+	}
+
+	opts := testOptions()
+	opts.AvaURL = "avatar.png"
+	opts.AvaD = 64
+	opts.AvaShape = "hexagon"
+
+	if _, err := New().Draw(context.Background(), opts); err == nil {
+		t.Error("expected an error for an unknown shape")
+	}
+}
+
+func TestTruncateTitle(t *testing.T) {
+	testCases := []struct {
+		name     string
+		title    string
+		max      int
+		expected string
+	}{{
+		name:     "shorter than the limit",
+		title:    "The quick brown fox",
+		max:      90,
+		expected: "The quick brown fox",
+	}, {
+		name:     "exactly the limit",
+		title:    "The quick",
+		max:      9,
+		expected: "The quick",
+	}, {
+		name:     "cut in the middle of a word",
+		title:    "The quick brown fox",
+		max:      12,
+		expected: "The quick…",
+	}, {
+		name:     "cut right before a space",
+		title:    "The quick brown fox",
+		max:      9,
+		expected: "The quick…",
+	}, {
+		name:     "cut right after a space",
+		title:    "The quick brown fox",
+		max:      10,
+		expected: "The quick…",
+	}, {
+		name:     "trailing spaces before the cut",
+		title:    "The quick   brown fox",
+		max:      14,
+		expected: "The quick…",
+	}, {
+		name:     "single long word",
+		title:    "Supercalifragilisticexpialidocious",
+		max:      10,
+		expected: "Supercalif…",
+	}, {
+		name:     "CJK without spaces",
+		title:    "日本語のタイトルはスペースがありません",
+		max:      5,
+		expected: "日本語のタ…",
+	}, {
+		name:     "line breaks are not counted",
+		title:    "The\nquick\nbrown",
+		max:      13,
+		expected: "The\nquick\nbrown",
+	}, {
+		name:     "cut after a line break",
+		title:    "The quick\nbrown fox",
+		max:      12,
+		expected: "The quick…",
+	}}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			if actual := truncateTitle(tt.title, tt.max); actual != tt.expected {
+				t.Errorf("expected: %q, actual: %q", tt.expected, actual)
+			}
+		})
+	}
+}
+
+func TestMaxTitleLines(t *testing.T) {
+	opts := testOptions()
+	opts.Title = "The quick brown fox jumps over the lazy dog and keeps on running far away"
+	opts.MaxTitleLines = 2
+
+	d := New().newDrawing(opts.withDefaults())
+
+	if _, err := d.draw(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	lines := d.layout.TitleLines
+
+	if len(lines) != 2 || !strings.HasSuffix(lines[1], "…") {
+		t.Errorf("expected two lines ending with an ellipsis, got %q", lines)
+	}
+
+	if !d.stats.TitleTruncated {
+		t.Error("expected the title reported as truncated")
+	}
+
+	// the rune cap cuts a long title first when there is room for more lines
+	opts.Title = strings.Repeat("word ", 30)
+	opts.MaxTitleLines = 10
+	d = New().newDrawing(opts.withDefaults())
+
+	if _, err := d.draw(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	lines = d.layout.TitleLines
+
+	if len(lines) >= 10 || !strings.HasSuffix(lines[len(lines)-1], "…") {
+		t.Errorf("expected the rune cap to cut the title, got %q", lines)
+	}
+}
+
+func TestDrawAutoFitTitle(t *testing.T) {
+	opts := testOptions()
+	opts.Title = "The quick brown fox jumps over the lazy dog. Sphinx of black quartz, judge my vow!"
+	opts.TitleSize = 120
+	opts.LogoURL = "logo.png"
+	opts.AutoFitTitle = true
+
+	p := New().newDrawing(opts.withDefaults())
+	img, err := p.draw(context.Background())
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if p.opts.TitleSize >= opts.TitleSize {
+		t.Errorf("expected a smaller title size, got: %f", p.opts.TitleSize)
+	}
+
+	if p.opts.TitleSize < minTitleSize {
+		t.Errorf("expected the title size to stay above the min, got: %f", p.opts.TitleSize)
+	}
+
+	// nothing is drawn between the title box and the logo row
+	bottom := opts.CanvasH - int(DefaultPadding)*2 - opts.LogoH
+
+	if hasInk(img, image.Rect(0, bottom, 780, opts.CanvasH-int(DefaultPadding)-opts.LogoH)) {
+		t.Error("the title overflows its box")
+	}
+}
+
+func TestDrawTitleAlign(t *testing.T) {
+	// leftmost returns the first column with ink inside the rectangle
+	leftmost := func(img image.Image, rect image.Rectangle) int {
+		for x := rect.Min.X; x < rect.Max.X; x++ {
+			if hasInk(img, image.Rect(x, rect.Min.Y, x+1, rect.Max.Y)) {
+				return x
+			}
+		}
+
+		return -1
+	}
+
+	titleRect := image.Rect(0, 96, 1200, 200)
+	columns := map[string]int{}
+
+	for _, align := range []string{"", AlignLeft, AlignCenter, AlignRight} {
+		opts := testOptions()
+		opts.TitleAlign = align
+
+		img, err := New().Draw(context.Background(), opts)
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		columns[align] = leftmost(img, titleRect)
+	}
+
+	if columns[""] != columns[AlignLeft] {
+		t.Errorf("expected left alignment by default: %v", columns)
+	}
+
+	if !(columns[AlignLeft] < columns[AlignCenter] && columns[AlignCenter] < columns[AlignRight]) {
+		t.Errorf("unexpected title positions: %v", columns)
+	}
+
+	opts := testOptions()
+	opts.TitleAlign = "unknown"
+
+	if _, err := New().Draw(context.Background(), opts); !errors.Is(err, ErrInvalidOptions) {
+		t.Errorf("expected an unknown alignment to be invalid, got %v", err)
+	}
+}
+
+func TestDrawTitleVAlign(t *testing.T) {
+	// centroidY returns the average row of the ink pixels inside the rectangle
+	centroidY := func(img image.Image, rect image.Rectangle) float64 {
+		sum, n := 0, 0
+
+		for y := rect.Min.Y; y < rect.Max.Y; y++ {
+			for x := rect.Min.X; x < rect.Max.X; x++ {
+				if hasInk(img, image.Rect(x, y, x+1, y+1)) {
+					sum += y
+					n++
+				}
+			}
+		}
+
+		return float64(sum) / float64(n)
+	}
+
+	titleRect := image.Rect(0, 96, 1200, 534)
+	short := "Hello"
+	long := "The quick brown fox jumps over the lazy dog. Sphinx of black quartz, judge my vow"
+	centroids := map[string]float64{}
+
+	for _, valign := range []string{VAlignTop, VAlignMiddle} {
+		for _, title := range []string{short, long} {
+			opts := testOptions()
+			opts.Title = title
+			opts.TitleVAlign = valign
+
+			img, err := New().Draw(context.Background(), opts)
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			centroids[valign+title] = centroidY(img, titleRect)
+		}
+	}
+
+	if centroids[VAlignTop+long]-centroids[VAlignTop+short] < 50 {
+		t.Errorf("expected a long title to go lower when aligned to the top: %v", centroids)
+	}
+
+	if math.Abs(centroids[VAlignMiddle+long]-centroids[VAlignMiddle+short]) > 20 {
+		t.Errorf("expected both titles to be centered: %v", centroids)
+	}
+}
+
+func TestResizeBlur(t *testing.T) {
+	checkerboard := image.NewRGBA(image.Rect(0, 0, 64, 64))
+
+	for y := 0; y < 64; y++ {
+		for x := 0; x < 64; x++ {
+			if (x+y)%2 == 0 {
+				checkerboard.Set(x, y, color.White)
+			} else {
+				checkerboard.Set(x, y, color.Black)
+			}
+		}
+	}
+
+	buf := new(bytes.Buffer)
+
+	if err := png.Encode(buf, checkerboard); err != nil {
+		t.Fatal(err)
+	}
+
+	// variance returns the mean squared difference between horizontally adjacent pixels
+	variance := func(buf []byte) float64 {
+		img, _, err := image.Decode(bytes.NewReader(buf))
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		sum := 0.0
+
+		for y := 0; y < 64; y++ {
+			for x := 1; x < 64; x++ {
+				a, _, _, _ := img.At(x-1, y).RGBA()
+				b, _, _, _ := img.At(x, y).RGBA()
+				d := float64(a>>8) - float64(b>>8)
+				sum += d * d
+			}
+		}
+
+		return sum / (64 * 63)
+	}
+
+	sharp, err := New().resize(buf.Bytes(), 64, 64, 0, vips.InterestingAttention)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	blurred, err := New().resize(buf.Bytes(), 64, 64, 2, vips.InterestingAttention)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if variance(blurred) >= variance(sharp)/10 {
+		t.Errorf("expected the blur to reduce the variance, sharp: %f, blurred: %f", variance(sharp), variance(blurred))
+	}
+}
+
+func TestDrawBgFit(t *testing.T) {
+	// dominant returns the strongest channel of the pixel: 0 for red, 1 for green, 2 for blue
+	dominant := func(img image.Image, x, y int) int {
+		r, g, b, _ := img.At(x, y).RGBA()
+
+		switch {
+		case r > g && r > b:
+			return 0
+		case g > r && g > b:
+			return 1
+		}
+
+		return 2
+	}
+
+	testCases := []struct {
+		fit string
+		// the stripe in the middle row at x = 150 and whether the top row is padded
+		stripe int
+		padded bool
+	}{
+		// the middle half of the wide image is cropped, so the left quarter is the red-green boundary
+		{fit: FitCover, stripe: 1},
+		{fit: "", stripe: 1},
+		// the whole width is scaled down, so the left quarter is inside the red stripe
+		{fit: FitContain, stripe: 0, padded: true},
+		{fit: FitFill, stripe: 0},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.fit, func(t *testing.T) {
+			opts := testOptions()
+			opts.CanvasW = 600
+			opts.CanvasH = 315
+			opts.Bg = stripesDataURL(t, 1200, 315)
+			opts.RequireBg = true
+			opts.CropMode = CropCentre
+			opts.BgFit = tt.fit
+			opts.BgPadColor = "#FFFFFF"
+			opts.Title = " "
+
+			img, err := New().Draw(context.Background(), opts)
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if got := dominant(img, 150, opts.CanvasH/2); got != tt.stripe {
+				t.Errorf("expected the stripe %d in the middle row, got %d", tt.stripe, got)
+			}
+
+			// the top row is outside of the overlay
+			r, g, b, _ := img.At(opts.CanvasW/2, 2).RGBA()
+			padded := r>>8 == 0xFF && g>>8 == 0xFF && b>>8 == 0xFF
+
+			if padded != tt.padded {
+				t.Errorf("expected the top row padded: %v, got %d %d %d", tt.padded, r>>8, g>>8, b>>8)
+			}
+		})
+	}
+}
+
+func TestDrawBgGravity(t *testing.T) {
+	// a tall image with the bright top quarter
+	src := image.NewRGBA(image.Rect(0, 0, 300, 600))
+	draw.Draw(src, src.Bounds(), image.Black, image.Point{}, draw.Src)
+	draw.Draw(src, image.Rect(0, 0, 300, 150), image.White, image.Point{}, draw.Src)
+
+	buf := new(bytes.Buffer)
+
+	if err := png.Encode(buf, src); err != nil {
+		t.Fatal(err)
+	}
+
+	bg := "data:image/png;base64," + base64.StdEncoding.EncodeToString(buf.Bytes())
+
+	testCases := []struct {
+		name    string
+		gravity string
+		focus   *Focus
+		bright  bool
+	}{
+		{name: "north", gravity: GravityNorth, bright: true},
+		{name: "south", gravity: GravitySouth},
+		{name: "focus", gravity: GravitySouth, focus: &Focus{X: 0.5, Y: 0.1}, bright: true},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := testOptions()
+			opts.CanvasW = 600
+			opts.CanvasH = 315
+			opts.Bg = bg
+			opts.RequireBg = true
+			opts.BgGravity = tt.gravity
+			opts.BgFocus = tt.focus
+			opts.Title = " "
+
+			img, err := New().Draw(context.Background(), opts)
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			// the top row is outside of the overlay
+			if got := hasInk(img, image.Rect(0, 0, opts.CanvasW, 2)); got != tt.bright {
+				t.Errorf("expected the bright part kept: %v, got %v", tt.bright, got)
+			}
+		})
+	}
+}
+
+func TestDrawBgTile(t *testing.T) {
+	tile := image.NewNRGBA(image.Rect(0, 0, 2, 2))
+	tile.Set(0, 0, color.NRGBA{R: 255, A: 255})
+	tile.Set(1, 0, color.NRGBA{G: 255, A: 255})
+	tile.Set(0, 1, color.NRGBA{B: 255, A: 255})
+	tile.Set(1, 1, color.NRGBA{R: 255, G: 255, B: 255, A: 255})
+
+	buf := new(bytes.Buffer)
+
+	if err := png.Encode(buf, tile); err != nil {
+		t.Fatal(err)
+	}
+
+	opts := testOptions()
+	opts.Bg = "data:image/png;base64," + base64.StdEncoding.EncodeToString(buf.Bytes())
+	opts.RequireBg = true
+	opts.BgTile = true
+	opts.Title = " "
+
+	img, err := New().Draw(context.Background(), opts)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the pixels outside of the overlay repeat the tile, the canvas size is even
+	for _, pt := range []image.Point{{0, 0}, {2, 0}, {10, 6}, {opts.CanvasW - 2, 0}, {0, opts.CanvasH - 2}, {opts.CanvasW - 1, opts.CanvasH - 1}} {
+		want := tile.At(pt.X%2, pt.Y%2)
+		wr, wg, wb, _ := want.RGBA()
+
+		if r, g, b, _ := img.At(pt.X, pt.Y).RGBA(); r != wr || g != wg || b != wb {
+			t.Errorf("expected %v at %v, got %d %d %d", want, pt, r>>8, g>>8, b>>8)
+		}
+	}
+}
+
+func TestDrawAnimatedBg(t *testing.T) {
+	palette := color.Palette{color.RGBA{R: 255, A: 255}, color.RGBA{B: 255, A: 255}}
+	anim := &gif.GIF{Delay: []int{10, 10}}
+
+	for i := range palette {
+		frame := image.NewPaletted(image.Rect(0, 0, 64, 64), palette)
+
+		for j := range frame.Pix {
+			frame.Pix[j] = uint8(i)
+		}
+
+		anim.Image = append(anim.Image, frame)
+	}
+
+	buf := new(bytes.Buffer)
+
+	if err := gif.EncodeAll(buf, anim); err != nil {
+		t.Fatal(err)
+	}
+
+	opts := testOptions()
+	opts.Bg = "data:image/gif;base64," + base64.StdEncoding.EncodeToString(buf.Bytes())
+	opts.RequireBg = true
+	opts.Title = " "
+
+	img, err := New().Draw(context.Background(), opts)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the margin is outside of the overlay
+	if r, g, b, _ := img.At(2, 2).RGBA(); r>>8 < 0xF0 || g>>8 > 0x10 || b>>8 > 0x10 {
+		t.Errorf("expected the red first frame, got %d %d %d", r>>8, g>>8, b>>8)
+	}
+}
+
+func TestDrawOverlayColor(t *testing.T) {
+	opts := testOptions()
+	opts.Bg = "#FFFFFF"
+	opts.Opacity = 0.5
+	opts.OverlayColor = "#FF0000"
+
+	img, err := New().Draw(context.Background(), opts)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// white is shifted toward red inside the margin and stays intact outside of it
+	if r, g, b, _ := img.At(opts.CanvasW/2, opts.CanvasH/2).RGBA(); r>>8 != 0xFF || g>>8 > 0x81 || b>>8 > 0x81 {
+		t.Errorf("expected a reddish overlay, got: %d %d %d", r>>8, g>>8, b>>8)
+	}
+
+	if r, g, b, _ := img.At(int(DefaultMargin)-1, int(DefaultMargin)-1).RGBA(); r>>8 != 0xFF || g>>8 != 0xFF || b>>8 != 0xFF {
+		t.Errorf("expected no overlay on the DefaultMargin, got: %d %d %d", r>>8, g>>8, b>>8)
+	}
+
+	opts.OverlayColor = "crimson"
+
+	if _, err := New().Draw(context.Background(), opts); err == nil {
+		t.Error("expected an error for a non-HEX overlay color")
+	}
+}
+
+func TestDrawOverlayGradient(t *testing.T) {
+	opts := testOptions()
+	opts.Bg = "#FFFFFF"
+	opts.Title = " "
+	opts.Opacity = 0.8
+	opts.OverlayGradient = true
+
+	img, err := New().Draw(context.Background(), opts)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the overlay gets darker downward
+	prev := uint32(0xFF)
+
+	for y := int(DefaultMargin); y < opts.CanvasH-int(DefaultMargin); y += 50 {
+		r, _, _, _ := img.At(opts.CanvasW/2, y).RGBA()
+
+		if r>>8 > prev {
+			t.Errorf("expected the overlay alpha to increase downward at %d: %d > %d", y, r>>8, prev)
+		}
+
+		prev = r >> 8
+	}
+
+	if r, _, _, _ := img.At(opts.CanvasW/2, int(DefaultMargin)).RGBA(); r>>8 < 0xF0 {
+		t.Errorf("expected a clear top, got: %d", r>>8)
+	}
+
+	if r, _, _, _ := img.At(opts.CanvasW/2, opts.CanvasH-int(DefaultMargin)-1).RGBA(); r>>8 > 0x40 {
+		t.Errorf("expected a dark bottom, got: %d", r>>8)
+	}
+}
+
+func TestDrawMultipleAvatars(t *testing.T) {
+	testCases := []struct {
+		name    string
+		avatars int
+		rings   int
+		badge   bool
+	}{{
+		name:    "one",
+		avatars: 1,
+		rings:   1,
+	}, {
+		name:    "three",
+		avatars: 3,
+		rings:   3,
+	}, {
+		name:    "overflow",
+		avatars: 6,
+		rings:   5,
+		badge:   true,
+	}}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := testOptions()
+			opts.AvaURL = "avatar.png"
+			opts.AvaD = 64
+			opts.AvaBorderW = 4
+			opts.AvaBorderColor = "#FF00FF"
+
+			for i := 1; i < tt.avatars; i++ {
+				opts.AvaURLs = append(opts.AvaURLs, "avatar.png")
+			}
+
+			p := New().newDrawing(opts.withDefaults())
+			img, err := p.draw(context.Background())
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			rings := 0
+
+			// the top of every ring stays visible
+			for slot := 0; slot < 8; slot++ {
+				x, _ := p.avatarPosition(slot)
+
+				if r, g, b, _ := img.At(int(x), int(DefaultPadding)+2).RGBA(); r>>8 == 0xFF && g>>8 == 0 && b>>8 == 0xFF {
+					rings++
+				}
+			}
+
+			if rings != tt.rings {
+				t.Errorf("expected %d avatar rings, got: %d", tt.rings, rings)
+			}
+
+			x, y := p.avatarPosition(defaultMaxAvatars)
+			r, g, b, _ := img.At(int(x), int(y)+20).RGBA()
+			badge := r>>8 == 0x33 && g>>8 == 0x33 && b>>8 == 0x33
+
+			if badge != tt.badge {
+				t.Errorf("expected the badge: %t", tt.badge)
+			}
+
+			if tt.badge && !hasInk(img, image.Rect(int(x)-16, int(y)-10, int(x)+16, int(y)+10)) {
+				t.Error("expected the badge text")
+			}
+		})
+	}
+}
+
+func TestDrawAvatarFallback(t *testing.T) {
+	testCases := []struct {
+		name   string
+		avaURL string
+		author string
+		fill   color.NRGBA
+		text   bool
+	}{{
+		name:   "empty URL",
+		author: "Jane Doe",
+		fill:   color.NRGBA{0, 255, 0, 255},
+		text:   true,
+	}, {
+		name:   "fetch error",
+		avaURL: "missing.png",
+		author: "Jane Doe",
+		fill:   color.NRGBA{0, 255, 0, 255},
+		text:   true,
+	}, {
+		name:   "no author",
+		avaURL: "missing.png",
+		fill:   color.NRGBA{0x9E, 0x9E, 0x9E, 255},
+	}, {
+		// the avatar row is hidden
+		name: "no author and avatar",
+		fill: color.NRGBA{0, 0, 0, 255},
+	}}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := testOptions()
+			opts.AvaURL = tt.avaURL
+			opts.Author = tt.author
+			opts.AvaD = 64
+			opts.AvaFallbackColor = "#00FF00"
+			// the title takes the place of a hidden avatar row otherwise
+			opts.TitleVAlign = VAlignBottom
+
+			p := New()
+			img, err := p.Draw(context.Background(), opts)
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			// a point inside the avatar away from the initials
+			x, y := DefaultPadding+8, DefaultPadding+float64(opts.AvaD)/2
+			r, g, b, _ := img.At(int(x), int(y)).RGBA()
+			got := color.NRGBA{uint8(r >> 8), uint8(g >> 8), uint8(b >> 8), 255}
+
+			if got != tt.fill {
+				t.Errorf("expected the avatar to be filled with %v, got %v", tt.fill, got)
+			}
+
+			center := image.Rect(int(DefaultPadding)+24, int(DefaultPadding)+24, int(DefaultPadding)+40, int(DefaultPadding)+40)
+
+			// only the white initials have the red channel saturated
+			if maxR, _, _ := maxRGB(img, center); (maxR > 200) != tt.text {
+				t.Errorf("expected initials to be drawn: %v", tt.text)
+			}
+		})
+	}
+}
+
+func TestDrawAvatarClamp(t *testing.T) {
+	// greenBounds returns the bounding box of the fallback color
+	greenBounds := func(img image.Image) image.Rectangle {
+		var bounds image.Rectangle
+
+		b := img.Bounds()
+
+		for y := b.Min.Y; y < b.Max.Y; y++ {
+			for x := b.Min.X; x < b.Max.X; x++ {
+				r, g, b, _ := img.At(x, y).RGBA()
+
+				if g>>8 > 200 && r>>8 < 50 && b>>8 < 50 {
+					bounds = bounds.Union(image.Rect(x, y, x+1, y+1))
+				}
+			}
+		}
+
+		return bounds
+	}
+
+	testCases := []struct {
+		name string
+		avaD int
+		want int
+	}{
+		{"absurdly large", 5000, maxAvatarD(1200, 630, DefaultPadding)},
+		{"within the limit", 100, 100},
+		{"very small", 4, 4},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := testOptions()
+			opts.Author = "Jane Doe"
+			opts.AvaD = tt.avaD
+			opts.AvaFallbackColor = "#00FF00"
+
+			img, err := New().Draw(context.Background(), opts)
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got := greenBounds(img)
+			want := image.Rect(int(DefaultPadding), int(DefaultPadding), int(DefaultPadding)+tt.want, int(DefaultPadding)+tt.want)
+
+			if got.Empty() || !got.In(want) {
+				t.Errorf("expected the avatar within %v, got %v", want, got)
+			}
+		})
+	}
+
+	if d := maxAvatarD(120, 100, DefaultPadding); d != 1 {
+		t.Errorf("expected the smallest diameter of 1px for a tiny canvas, got %d", d)
+	}
+}
+
+func TestInitials(t *testing.T) {
+	testCases := []struct {
+		name string
+		want string
+	}{
+		{"Jane Doe", "JD"},
+		{"John Ronald Reuel Tolkien", "JT"},
+		{"@tester", "T"},
+		{"  ", ""},
+		{"élise ürban", "ÉÜ"},
+	}
+
+	for _, tt := range testCases {
+		if got := initials(tt.name); got != tt.want {
+			t.Errorf("initials(%q) = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+// fakeGetter returns the same buffer for every resource or fails with err.
+type fakeGetter struct {
+	buf []byte
+	err error
+}
+
+func (g fakeGetter) GetAll(_ context.Context, urlsOrPaths map[string]string) (map[string][]byte, error) {
+	if g.err != nil {
+		return nil, g.err
+	}
+
+	bufs := make(map[string][]byte, len(urlsOrPaths))
+
+	for key := range urlsOrPaths {
+		bufs[key] = g.buf
+	}
+
+	return bufs, nil
+}
+
+func TestDrawOptionalAssets(t *testing.T) {
+	corrupt := fakeGetter{buf: []byte("not an image")}
+	failing := fakeGetter{err: errors.New("connection reset")}
+
+	testCases := []struct {
+		name    string
+		remote  remote.Getter
+		logo    bool
+		bg      bool
+		require bool
+		wantErr bool
+	}{{
+		name:   "corrupt logo",
+		remote: corrupt,
+		logo:   true,
+	}, {
+		name:   "failing logo",
+		remote: failing,
+		logo:   true,
+	}, {
+		name:    "required corrupt logo",
+		remote:  corrupt,
+		logo:    true,
+		require: true,
+		wantErr: true,
+	}, {
+		name:   "corrupt background",
+		remote: corrupt,
+		bg:     true,
+	}, {
+		name:   "failing background",
+		remote: failing,
+		bg:     true,
+	}, {
+		name:    "required corrupt background",
+		remote:  corrupt,
+		bg:      true,
+		require: true,
+		wantErr: true,
+	}, {
+		name:    "required failing background",
+		remote:  failing,
+		bg:      true,
+		require: true,
+		wantErr: true,
+	}}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := testOptions()
+			opts.LabelL = "Label"
+
+			if tt.logo {
+				opts.LogoURL = "https://example.com/logo.png"
+				opts.RequireLogo = tt.require
+			}
+
+			if tt.bg {
+				opts.Bg = "https://example.com/bg.jpg"
+				opts.RequireBg = tt.require
+			}
+
+			p := New(WithGetter(tt.remote))
+			img, err := p.Draw(context.Background(), opts)
+
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("expected an error")
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !hasInk(img, img.Bounds()) {
+				t.Error("expected the preview to be rendered")
+			}
+
+			if !tt.bg {
+				return
+			}
+
+			opts.Bg = ""
+			want, err := New().Draw(context.Background(), opts)
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if img.At(0, 0) != want.At(0, 0) {
+				t.Errorf("expected the default background %v, got %v", want.At(0, 0), img.At(0, 0))
+			}
+		})
+	}
+}
+
+func TestDrawLogoPosition(t *testing.T) {
+	testCases := []struct {
+		position string
+		right    bool
+		bottom   bool
+		wantErr  bool
+	}{
+		{position: "", right: true, bottom: true},
+		{position: LogoBottomRight, right: true, bottom: true},
+		{position: LogoBottomLeft, right: false, bottom: true},
+		{position: LogoTopRight, right: true, bottom: false},
+		{position: LogoTopLeft, right: false, bottom: false},
+		{position: "center", wantErr: true},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.position, func(t *testing.T) {
+			opts := testOptions()
+			opts.Title = ""
+			opts.LogoURL = "logo.png"
+			opts.LogoPosition = tt.position
+
+			p := New()
+			img, err := p.Draw(context.Background(), opts)
+
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("expected an error")
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			w, h := img.Bounds().Dx(), img.Bounds().Dy()
+
+			for _, q := range []struct {
+				right, bottom bool
+			}{{false, false}, {true, false}, {false, true}, {true, true}} {
+				quadrant := image.Rect(0, 0, w/2, h/2)
+
+				if q.right {
+					quadrant = quadrant.Add(image.Pt(w/2, 0))
+				}
+
+				if q.bottom {
+					quadrant = quadrant.Add(image.Pt(0, h/2))
+				}
+
+				want := q.right == tt.right && q.bottom == tt.bottom
+
+				if got := hasInk(img, quadrant); got != want {
+					t.Errorf("quadrant %+v: expected the logo: %v, got %v", q, want, got)
+				}
+			}
+		})
+	}
+}
+
+func TestDrawLogoOpacity(t *testing.T) {
+	render := func(opacity float64) image.Image {
+		opts := testOptions()
+		opts.Title = ""
+		opts.LogoURL = "logo.png"
+		opts.LogoOpacity = opacity
+
+		img, err := New().Draw(context.Background(), opts)
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return img
+	}
+
+	logo := image.Rect(1200-DefaultPadding-349, 630-DefaultPadding-48, 1200-DefaultPadding, 630-DefaultPadding)
+	opaqueR, opaqueG, opaqueB := maxRGB(render(1), logo)
+	faintR, faintG, faintB := maxRGB(render(0.3), logo)
+
+	// the background is black, so a faint logo has darker pixels
+	if faintR+faintG+faintB >= opaqueR+opaqueG+opaqueB {
+		t.Errorf("expected the faint logo to be closer to the background: %d,%d,%d vs %d,%d,%d",
+			faintR, faintG, faintB, opaqueR, opaqueG, opaqueB)
+	}
+
+	if !hasInk(render(0.3), logo) {
+		t.Error("expected the faint logo to be visible")
+	}
+
+	if r, g, b := maxRGB(render(-1), logo); r+g+b != 0 {
+		t.Errorf("expected a negative opacity to hide the logo, got %d,%d,%d", r, g, b)
+	}
+}
+
+func TestDrawElementOpacity(t *testing.T) {
+	render := func(avaOpacity, authorOpacity float64) (image.Image, LayoutInfo) {
+		opts := testOptions()
+		opts.Title = ""
+		opts.Author = "Jane Doe"
+		opts.AvaURL = pngDataURL(t, 64, 64, color.RGBA{R: 255, A: 255})
+		opts.AvaOpacity = avaOpacity
+		opts.AuthorOpacity = authorOpacity
+
+		layout, err := New().Layout(opts)
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		img, err := New().Draw(context.Background(), opts)
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return img, layout
+	}
+
+	opaque, layout := render(0, 0)
+	faint, _ := render(0.5, 0.5)
+
+	ava := layout.Avatars[0]
+	cx, cy := int(ava.X+ava.W/2), int(ava.Y+ava.H/2)
+
+	// the background is black, so the red channel is the alpha of the red avatar
+	if r, _, _, _ := opaque.At(cx, cy).RGBA(); r>>8 != 255 {
+		t.Errorf("expected an opaque avatar, got the red of %d", r>>8)
+	}
+
+	if r, _, _, _ := faint.At(cx, cy).RGBA(); r>>8 < 120 || r>>8 > 135 {
+		t.Errorf("expected a half transparent avatar, got the red of %d", r>>8)
+	}
+
+	author := image.Rect(int(layout.Author.X), int(layout.Author.Y), int(layout.Author.X+layout.Author.W), int(layout.Author.Y+layout.Author.H))
+	opaqueR, _, _ := maxRGB(opaque, author)
+	faintR, _, _ := maxRGB(faint, author)
+
+	// the white author is 204 of alpha by default
+	if opaqueR < 200 || faintR < 95 || faintR > 110 {
+		t.Errorf("expected the author alpha halved, got %d vs %d", faintR, opaqueR)
+	}
+}
+
+func TestDrawDataURLAvatar(t *testing.T) {
+	src := image.NewRGBA(image.Rect(0, 0, 64, 64))
+	draw.Draw(src, src.Bounds(), image.NewUniform(color.RGBA{255, 0, 0, 255}), image.Point{}, draw.Src)
+
+	var buf bytes.Buffer
+
+	if err := png.Encode(&buf, src); err != nil {
+		t.Fatal(err)
+	}
+
+	opts := testOptions()
+	opts.AvaURL = "data:image/png;base64," + base64.StdEncoding.EncodeToString(buf.Bytes())
+	opts.AvaD = 64
+
+	img, err := New().Draw(context.Background(), opts)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the avatar is red, while the fallback would be blue-ish
+	r, g, b, _ := img.At(int(DefaultPadding)+32, int(DefaultPadding)+32).RGBA()
+
+	if r>>8 != 255 || g>>8 != 0 || b>>8 != 0 {
+		t.Errorf("expected the red avatar, got %d,%d,%d", r>>8, g>>8, b>>8)
+	}
+}
+
+// blockingGetter blocks until the context is done.
+type blockingGetter struct{}
+
+func (blockingGetter) GetAll(ctx context.Context, urlsOrPaths map[string]string) (map[string][]byte, error) {
+	<-ctx.Done()
+
+	for _, urlOrPath := range urlsOrPaths {
+		return nil, fmt.Errorf("could not get a resource by the url: %s: %w", urlOrPath, ctx.Err())
+	}
+
+	return nil, ctx.Err()
+}
+
+func TestDrawFetchTimeout(t *testing.T) {
+	opts := testOptions()
+	opts.Bg = "https://example.com/slow.jpg"
+	opts.RequireBg = true
+	opts.FetchTimeout = 20 * time.Millisecond
+
+	p := New(WithGetter(blockingGetter{}))
+	start := time.Now()
+	_, err := p.Draw(context.Background(), opts)
+
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected a deadline error, got %v", err)
+	}
+
+	if !strings.Contains(err.Error(), opts.Bg) {
+		t.Errorf("expected the error to mention the URL: %v", err)
+	}
+
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("expected the timeout to fire early, took %s", elapsed)
+	}
+}
+
+func TestDrawFetchTimeoutOptional(t *testing.T) {
+	opts := testOptions()
+	opts.LogoURL = "https://example.com/slow.png"
+	opts.FetchTimeout = 20 * time.Millisecond
+
+	p := New(WithGetter(blockingGetter{}))
+
+	if _, err := p.Draw(context.Background(), opts); err != nil {
+		t.Errorf("expected an optional logo timeout to be skipped, got %v", err)
+	}
+}
+
+func TestDrawMaxImageBytes(t *testing.T) {
+	bg := readAsset(t, "logo.png")
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(bg)
+	}))
+
+	defer ts.Close()
+
+	opts := testOptions()
+	opts.Bg = ts.URL + "/bg.png"
+	opts.RequireBg = true
+	opts.MaxImageBytes = int64(len(bg)) - 1
+
+	var tooLargeErr *remote.TooLargeError
+
+	if _, err := New().Draw(context.Background(), opts); !errors.As(err, &tooLargeErr) {
+		t.Errorf("expected a size error, got %v", err)
+	}
+
+	opts.MaxImageBytes = int64(len(bg))
+
+	if _, err := New().Draw(context.Background(), opts); err != nil {
+		t.Errorf("expected the background within the limit, got %v", err)
+	}
+}
+
+func TestDrawMaxImagePixels(t *testing.T) {
+	buf := new(bytes.Buffer)
+
+	if err := gif.Encode(buf, image.NewPaletted(image.Rect(0, 0, 1, 1), color.Palette{color.Black}), nil); err != nil {
+		t.Fatal(err)
+	}
+
+	// the logical screen size of the header claims 65535x65535 px
+	bomb := buf.Bytes()
+	binary.LittleEndian.PutUint16(bomb[6:], 0xFFFF)
+	binary.LittleEndian.PutUint16(bomb[8:], 0xFFFF)
+
+	opts := testOptions()
+	opts.Bg = "data:image/gif;base64," + base64.StdEncoding.EncodeToString(bomb)
+	opts.RequireBg = true
+
+	if _, err := New().Draw(context.Background(), opts); !errors.Is(err, ErrTooManyPixels) {
+		t.Errorf("expected a pixel budget error, got %v", err)
+	}
+
+	opts.Bg = stripesDataURL(t, 100, 100)
+	opts.MaxImagePixels = 100*100 - 1
+
+	if _, err := New().Draw(context.Background(), opts); !errors.Is(err, ErrTooManyPixels) {
+		t.Errorf("expected a pixel budget error, got %v", err)
+	}
+
+	opts.MaxImagePixels = 100 * 100
+
+	if _, err := New().Draw(context.Background(), opts); err != nil {
+		t.Errorf("expected the background within the budget, got %v", err)
+	}
+}
+
+func TestNewWithCache(t *testing.T) {
+	p := New(WithCache(8, time.Minute))
+
+	if _, ok := p.remote.(*remote.Cached); !ok {
+		t.Fatalf("expected a cached getter, got %T", p.remote)
+	}
+
+	opts := testOptions()
+	opts.LogoURL = "logo.png"
+
+	for i := 0; i < 2; i++ {
+		if _, err := p.Draw(context.Background(), opts); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if n := p.remote.(*remote.Cached).Len(); n != 1 {
+		t.Errorf("expected the logo to be cached, got %d entries", n)
+	}
+}
+
+// recordingGetter serves local assets and records the requested URLs.
+type recordingGetter struct {
+	mu   sync.Mutex
+	urls []string
+}
+
+func (g *recordingGetter) GetAll(ctx context.Context, urlsOrPaths map[string]string) (map[string][]byte, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	bufs := make(map[string][]byte, len(urlsOrPaths))
+
+	for key, urlOrPath := range urlsOrPaths {
+		g.urls = append(g.urls, urlOrPath)
+
+		buf, err := os.ReadFile("../remote/images/" + path.Base(urlOrPath))
+
+		if err != nil {
+			return nil, err
+		}
+
+		bufs[key] = buf
+	}
+
+	return bufs, nil
+}
+
+func TestNewWithGetter(t *testing.T) {
+	g := &recordingGetter{}
+	opts := testOptions()
+	opts.LogoURL = "https://example.com/logo.png"
+	opts.AvaURL = "https://example.com/avatar.png"
+	opts.AvaD = 64
+
+	if _, err := New(WithGetter(g)).Draw(context.Background(), opts); err != nil {
+		t.Fatal(err)
+	}
+
+	sort.Strings(g.urls)
+
+	if want := []string{opts.AvaURL, opts.LogoURL}; !reflect.DeepEqual(g.urls, want) {
+		t.Errorf("expected the getter to be asked for %v, got %v", want, g.urls)
+	}
+}
+
+func TestNewWithLogger(t *testing.T) {
+	var buf bytes.Buffer
+
+	opts := testOptions()
+	opts.LogoURL = "https://example.com/logo.png"
+
+	p := New(WithGetter(fakeGetter{buf: []byte("not an image")}), WithLogger(log.New(&buf, "", 0)))
+
+	if _, err := p.Draw(context.Background(), opts); err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.Contains(buf.String(), "skipping the logo") {
+		t.Errorf("expected the logger to be used, got %q", buf.String())
+	}
+}
+
+func TestNewWithFontDir(t *testing.T) {
+	symbola, err := fonts.ReadFile(emoji2Font)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	measure := func(p *Preview) float64 {
+		face, err := p.fonts.loadFont(FontSource{}, 40)
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return float64(font.MeasureString(face, "Hello, world"))
+	}
+
+	// the text font is replaced with Symbola, the rest falls back to the embedded fonts
+	dir := fstest.MapFS{"Ubuntu-Medium.ttf": {Data: symbola}}
+
+	if measure(New(WithFontDir(dir))) == measure(New()) {
+		t.Error("expected the font from the dir to be used")
+	}
+}
+
+func TestLogger(t *testing.T) {
+	opts := testOptions()
+	opts.AvaURL = "avatar.png"
+	opts.AvaD = 32
+
+	var std bytes.Buffer
+
+	log.SetOutput(&std)
+	defer log.SetOutput(os.Stderr)
+
+	if _, err := New(WithGetter(&recordingGetter{})).Draw(context.Background(), opts); err != nil {
+		t.Fatal(err)
+	}
+
+	if std.Len() > 0 {
+		t.Errorf("expected no output by default, got %q", std.String())
+	}
+
+	var buf bytes.Buffer
+
+	if _, err := New(WithGetter(&recordingGetter{}), WithLogger(log.New(&buf, "", 0))).Draw(context.Background(), opts); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, want := range []string{"Resizing an image to 32x32 px", "Masking an image with a circle shape"} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("expected %q to be logged, got %q", want, buf.String())
+		}
+	}
+}
+
+func TestPrepareAssets(t *testing.T) {
+	opts := testOptions()
+	opts.AvaD = 32
+	opts.LogoH = 24
+	p := New().newDrawing(opts)
+
+	bufs := map[string][]byte{
+		avaKey + "0": readAsset(t, "avatar.png"),
+		avaKey + "1": []byte("not an image"),
+		logoKey:      readAsset(t, "logo.png"),
+		bgKey:        []byte("not an image"),
+	}
+
+	a := p.prepareAssets(bufs)
+
+	// the failed images don't affect the others
+	if a.bg != nil || a.bgErr == nil {
+		t.Errorf("expected a background error, got %v", a.bgErr)
+	}
+
+	if a.avatars[0] == nil || a.avatars[0].Bounds().Dx() != 32 {
+		t.Errorf("expected the resized avatar, got %v (%v)", a.avatars[0], a.avatarErrs[0])
+	}
+
+	if a.avatars[1] != nil || a.avatarErrs[1] == nil {
+		t.Errorf("expected an avatar error, got %v", a.avatarErrs[1])
+	}
+
+	if a.logo == nil || a.logo.Bounds().Dy() != 24 {
+		t.Errorf("expected the scaled logo, got %v (%v)", a.logo, a.logoErr)
+	}
+}
+
+func readAsset(tb testing.TB, name string) []byte {
+	buf, err := os.ReadFile("../remote/images/" + name)
+
+	if err != nil {
+		tb.Fatal(err)
+	}
+
+	return buf
+}
+
+func BenchmarkPrepareAssets(b *testing.B) {
+	opts := testOptions()
+	opts.CanvasW, opts.CanvasH = 1000, 500
+	opts.AvaD = 128
+	opts.LogoH = 96
+	p := New().newDrawing(opts)
+
+	bg, err := os.ReadFile("../server/testdata/bg.jpg")
+
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	ava, logo := readAsset(b, "avatar.png"), readAsset(b, "logo.png")
+
+	b.Run("sequential", func(b *testing.B) {
+		for n := 0; n < b.N; n++ {
+			if _, err := p.prepareBackground(bg); err != nil {
+				b.Fatal(err)
+			}
+
+			if _, err := p.prepareAvatar(ava); err != nil {
+				b.Fatal(err)
+			}
+
+			if _, err := p.prepareLogo(logo); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+
+	b.Run("parallel", func(b *testing.B) {
+		bufs := map[string][]byte{bgKey: bg, avaKey + "0": ava, logoKey: logo}
+
+		for n := 0; n < b.N; n++ {
+			if a := p.prepareAssets(bufs); a.bgErr != nil || a.avatarErrs[0] != nil || a.logoErr != nil {
+				b.Fatal(a.bgErr, a.avatarErrs[0], a.logoErr)
+			}
+		}
+	})
+}
+
+func TestDrawConcurrent(t *testing.T) {
+	p := New()
+	var wg sync.WaitGroup
+
+	for i := 0; i < 16; i++ {
+		wg.Add(1)
+
+		go func(i int) {
+			defer wg.Done()
+
+			opts := testOptions()
+			opts.CanvasW = 600 + i*10
+			opts.CanvasH = 315 + i*5
+			opts.Title = fmt.Sprintf("Title #%d", i)
+			opts.Author = fmt.Sprintf("Author #%d", i)
+			opts.AuthorSize = 36
+			opts.AvaURL = "avatar.png"
+			opts.AvaD = 32 + i%3*16
+			opts.LogoURL = "logo.png"
+			opts.AutoFitTitle = i%2 == 0
+
+			img, err := p.Draw(context.Background(), opts)
+
+			if err != nil {
+				t.Error(err)
+				return
+			}
+
+			if size := img.Bounds().Size(); size.X != opts.CanvasW || size.Y != opts.CanvasH {
+				t.Errorf("expected a %dx%d preview, got %v", opts.CanvasW, opts.CanvasH, size)
+			}
+		}(i)
+	}
+
+	wg.Wait()
+}
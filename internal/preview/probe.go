@@ -0,0 +1,131 @@
+package preview
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+)
+
+// svgContentType is reported for the SVGs sniffed by isSVG as http.DetectContentType sees them as text.
+const svgContentType = "image/svg+xml"
+
+// ProbeResult lists the images of the Options checked by Probe.
+type ProbeResult struct {
+	// The background, the avatars and the logo images, only the ones set in the Options
+	Images []ImageProbe
+}
+
+// ImageProbe tells whether an image of the Options can be rendered.
+type ImageProbe struct {
+	// Key of the image: bg, avatar0, avatar1... or logo
+	Key string
+	// URL or path of the image
+	URL string
+	// MIME type sniffed from the contents, empty if the image couldn't be fetched
+	ContentType string
+	// Size of the image in pixels, zero if unknown, e.g. for an SVG without an intrinsic size
+	Width, Height int
+	// Renderable is set if the image can be drawn
+	Renderable bool
+	// Why the image can't be drawn, matches ErrFetch or ErrDecode
+	Err error
+}
+
+// CanRender reports whether every image of the Options can be drawn.
+func (r ProbeResult) CanRender() bool {
+	for _, img := range r.Images {
+		if !img.Renderable {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Probe fetches the images of the Options and checks they can be drawn without drawing the preview,
+// e.g. to reject bad inputs before queueing a draw. The images are fetched the way Draw fetches them,
+// so a caching getter serves them to the following Draw. The error is returned for invalid Options only,
+// the problems with the images are reported in the result.
+func (p *Preview) Probe(ctx context.Context, opts Options) (ProbeResult, error) {
+	opts, err := p.prepareOptions(opts)
+
+	if err != nil {
+		return ProbeResult{}, err
+	}
+
+	d := &drawing{Preview: p, opts: &opts}
+
+	return d.probe(ctx), nil
+}
+
+// probe checks the images of the drawing concurrently.
+func (p *drawing) probe(ctx context.Context) ProbeResult {
+	var images []ImageProbe
+
+	if bgURL := bgImageURL(p.opts.Bg); bgURL != "" {
+		images = append(images, ImageProbe{Key: bgKey, URL: bgURL})
+	}
+
+	avaURLs := p.avatarURLs()
+
+	for i := 0; i < len(avaURLs) && i < p.maxAvatars(); i++ {
+		images = append(images, ImageProbe{Key: avaKey + strconv.Itoa(i), URL: avaURLs[i]})
+	}
+
+	if p.opts.LogoURL != "" {
+		images = append(images, ImageProbe{Key: logoKey, URL: p.opts.LogoURL})
+	}
+
+	fetchCtx, cancel := p.fetchContext(ctx)
+	defer cancel()
+
+	var wg sync.WaitGroup
+
+	wg.Add(len(images))
+
+	for i := range images {
+		go func(img *ImageProbe) {
+			defer wg.Done()
+
+			p.probeImage(fetchCtx, img)
+		}(&images[i])
+	}
+
+	wg.Wait()
+
+	return ProbeResult{Images: images}
+}
+
+// probeImage fetches the image, sniffs its type and size and checks it can be decoded within the pixel budget.
+func (p *drawing) probeImage(ctx context.Context, img *ImageProbe) {
+	got, err := p.getter().GetAll(ctx, map[string]string{img.Key: img.URL})
+
+	if err != nil {
+		img.Err = withKind(ErrFetch, fmt.Errorf("could not get the image: %w", err))
+		return
+	}
+
+	buf := got[img.Key]
+
+	if isSVG(buf) {
+		w, h := svgSize(buf)
+		img.ContentType = svgContentType
+		img.Width, img.Height = int(math.Ceil(w)), int(math.Ceil(h))
+	} else {
+		img.ContentType = http.DetectContentType(buf)
+
+		if config, err := imageConfig(buf); err == nil {
+			img.Width, img.Height = config.Width, config.Height
+		}
+	}
+
+	if err := p.checkPixels(buf); err != nil {
+		img.Err = withKind(ErrDecode, fmt.Errorf("could not load the image: %w", err))
+		return
+	}
+
+	img.Renderable = true
+}
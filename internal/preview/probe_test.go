@@ -0,0 +1,79 @@
+package preview
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"image"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestProbe(t *testing.T) {
+	logo := readAsset(t, "logo.png")
+	config, _, err := image.DecodeConfig(bytes.NewReader(logo))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/logo.png" {
+			w.Write(logo)
+			return
+		}
+
+		w.Write([]byte("<!DOCTYPE html><html><body>Not an image</body></html>"))
+	}))
+
+	defer ts.Close()
+
+	opts := testOptions()
+	opts.Bg = ts.URL + "/page.html"
+	opts.LogoURL = ts.URL + "/logo.png"
+
+	result, err := New().Probe(context.Background(), opts)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(result.Images) != 2 {
+		t.Fatalf("expected the background and the logo probed, got %+v", result.Images)
+	}
+
+	bg, logoProbe := result.Images[0], result.Images[1]
+
+	if bg.Key != bgKey || bg.Renderable || !errors.Is(bg.Err, ErrDecode) {
+		t.Errorf("expected the page not renderable as the background, got %+v", bg)
+	}
+
+	if bg.ContentType != "text/html; charset=utf-8" {
+		t.Errorf("expected the page sniffed as HTML, got %q", bg.ContentType)
+	}
+
+	if logoProbe.Key != logoKey || !logoProbe.Renderable || logoProbe.Err != nil {
+		t.Errorf("expected the logo renderable, got %+v", logoProbe)
+	}
+
+	if logoProbe.ContentType != "image/png" || logoProbe.Width != config.Width || logoProbe.Height != config.Height {
+		t.Errorf("expected a %dx%d PNG logo, got %+v", config.Width, config.Height, logoProbe)
+	}
+
+	if result.CanRender() {
+		t.Error("expected the options not renderable with a broken background")
+	}
+
+	opts.Bg = ""
+
+	if result, err = New().Probe(context.Background(), opts); err != nil || !result.CanRender() {
+		t.Errorf("expected the options renderable without the background, got %+v, %v", result, err)
+	}
+
+	opts.CanvasW = 0
+
+	if _, err := New().Probe(context.Background(), opts); !errors.Is(err, ErrInvalidOptions) {
+		t.Errorf("expected an invalid options error, got %v", err)
+	}
+}
@@ -0,0 +1,143 @@
+package preview
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"strings"
+
+	"github.com/davidbyttow/govips/v2/vips"
+	"github.com/fogleman/gg"
+)
+
+// defaultShadowColor is a semi-transparent black
+var defaultShadowColor = color.RGBA{A: 153}
+
+const (
+	// elementShadowBlur is the blur sigma of the avatar and logo shadows
+	elementShadowBlur = 8
+	// elementShadowOffset moves the avatar and logo shadows down
+	elementShadowOffset = 4
+)
+
+// drawTitleShadow draws the wrapped title in the shadow color offset and blurred under the title.
+// The shadow is drawn off-screen first, so it can be blurred on its own.
+func (p *drawing) drawTitleShadow(x, y, maxWidth float64, align gg.Align, style textStyle) error {
+	main := p.ctx
+	scratch, release := p.scratchContext()
+	p.ctx = scratch
+
+	defer func() {
+		p.ctx = main
+		release()
+	}()
+
+	p.ctx.SetFontFace(style.face)
+
+	if err := p.setColor(p.opts.TitleShadowColor, defaultShadowColor); err != nil {
+		return fmt.Errorf("invalid title shadow color: %w", err)
+	}
+
+	err := p.drawStringWrapped(strings.Join(p.layout.TitleLines, "\n"), x+p.opts.TitleShadowX, y+p.opts.TitleShadowY, maxWidth, p.opts.TitleLineHeight, align, style)
+
+	if err != nil {
+		return err
+	}
+
+	var shadow image.Image = p.ctx.Image()
+
+	if p.opts.TitleShadowBlur > 0 {
+		if shadow, err = p.blur(shadow, p.opts.TitleShadowBlur); err != nil {
+			return fmt.Errorf("could not blur the title shadow: %w", err)
+		}
+	}
+
+	main.DrawImage(shadow, 0, 0)
+
+	return nil
+}
+
+// drawAvatarShadows draws the element shadow under every avatar slot, borders included, when ElementShadow is set.
+func (p *drawing) drawAvatarShadows() error {
+	if !p.opts.ElementShadow || len(p.layout.Avatars) == 0 || elementOpacity(p.opts.AvaOpacity) == 0 {
+		return nil
+	}
+
+	shape, err := p.avatarShape()
+
+	if err != nil {
+		return err
+	}
+
+	return p.drawElementShadow(func(ctx *gg.Context) {
+		r := float64(p.opts.AvaD)/2 + float64(p.opts.AvaBorderW)
+		radius := float64(p.opts.AvaCornerRadius + p.opts.AvaBorderW)
+
+		for slot := range p.layout.Avatars {
+			x, y := p.avatarCenter(slot)
+			drawShape(ctx, shape, x-r, y-r+elementShadowOffset, r*2, r*2, radius)
+			ctx.Fill()
+		}
+	})
+}
+
+// drawLogoShadow draws the element shadow under the logo when ElementShadow is set,
+// the shadow follows the logo's transparency.
+func (p *drawing) drawLogoShadow(logoImg image.Image) error {
+	if !p.opts.ElementShadow || elementOpacity(p.opts.LogoOpacity) == 0 {
+		return nil
+	}
+
+	return p.drawElementShadow(func(ctx *gg.Context) {
+		dst := ctx.Image().(draw.Image)
+		b := logoImg.Bounds()
+		at := image.Pt(int(p.layout.Logo.X), int(p.layout.Logo.Y)+elementShadowOffset)
+
+		draw.DrawMask(dst, b.Sub(b.Min).Add(at), image.NewUniform(defaultShadowColor), image.Point{}, logoImg, b.Min, draw.Over)
+	})
+}
+
+// drawElementShadow fills the silhouette off-screen in the shadow color, blurs it and draws it on the canvas.
+func (p *drawing) drawElementShadow(silhouette func(ctx *gg.Context)) error {
+	scratch, release := p.scratchContext()
+	defer release()
+
+	scratch.SetColor(defaultShadowColor)
+	silhouette(scratch)
+
+	shadow, err := p.blur(scratch.Image(), elementShadowBlur)
+
+	if err != nil {
+		return fmt.Errorf("could not blur the element shadow: %w", err)
+	}
+
+	p.ctx.DrawImage(shadow, 0, 0)
+
+	return nil
+}
+
+// blur applies the Gaussian blur of the sigma to the image via vips.
+func (p *drawing) blur(img image.Image, sigma float64) (image.Image, error) {
+	var buf bytes.Buffer
+
+	if err := pngEncoder.Encode(&buf, img); err != nil {
+		return nil, err
+	}
+
+	size := img.Bounds().Size()
+	blurred, err := p.resize(buf.Bytes(), size.X, size.Y, sigma, vips.InterestingNone)
+
+	if err != nil {
+		return nil, err
+	}
+
+	img, _, err = image.Decode(bytes.NewReader(blurred))
+
+	if err != nil {
+		return nil, err
+	}
+
+	return img, nil
+}
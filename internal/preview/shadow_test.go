@@ -0,0 +1,110 @@
+package preview
+
+import (
+	"context"
+	"image"
+	"testing"
+)
+
+// darkPixels counts the pixels inside the rectangle darker than the threshold in every channel.
+func darkPixels(img image.Image, rect image.Rectangle, threshold uint32) int {
+	n := 0
+
+	for y := rect.Min.Y; y < rect.Max.Y; y++ {
+		for x := rect.Min.X; x < rect.Max.X; x++ {
+			r, g, b, _ := img.At(x, y).RGBA()
+
+			if r>>8 < threshold && g>>8 < threshold && b>>8 < threshold {
+				n++
+			}
+		}
+	}
+
+	return n
+}
+
+func TestDrawTitleShadow(t *testing.T) {
+	titleRect := image.Rect(0, DefaultPadding*2, 1200, DefaultPadding*2+120)
+
+	draw := func(shadow bool, blur float64) image.Image {
+		opts := testOptions()
+		opts.Bg = "#808080"
+		opts.Title = "Shadow"
+		opts.TitleShadow = shadow
+		opts.TitleShadowColor = "#000000"
+		opts.TitleShadowBlur = blur
+		opts.TitleShadowX = 4
+		opts.TitleShadowY = 4
+
+		img, err := New().Draw(context.Background(), opts)
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return img
+	}
+
+	if n := darkPixels(draw(false, 0), titleRect, 0x70); n != 0 {
+		t.Errorf("expected no pixels darker than the background without a shadow, got %d", n)
+	}
+
+	sharp := darkPixels(draw(true, 0), titleRect, 0x20)
+
+	if sharp == 0 {
+		t.Error("expected the shadow pixels next to the glyphs")
+	}
+
+	// the blur spreads the shadow into more but lighter pixels
+	if blurred := darkPixels(draw(true, 3), titleRect, 0x70); blurred <= sharp {
+		t.Errorf("expected the blurred shadow to cover more pixels, got %d, sharp %d", blurred, sharp)
+	}
+
+	opts := testOptions()
+	opts.TitleShadow = true
+	opts.TitleShadowColor = "black"
+
+	if _, err := New().Draw(context.Background(), opts); err == nil {
+		t.Error("expected an invalid shadow color error")
+	}
+}
+
+func TestDrawElementShadow(t *testing.T) {
+	opts := testOptions()
+	opts.Bg = "#FFFFFF"
+	opts.Author = "Author"
+	opts.AvaD = 100
+	opts.AvaBorderW = 4
+
+	layout, err := New().Layout(opts)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// a band just below the ring, to the right of its center
+	ava := layout.Avatars[0]
+	bottom := int(ava.Y+ava.H) + opts.AvaBorderW
+	below := image.Rect(int(ava.X+ava.W/2), bottom+1, int(ava.X+ava.W), bottom+8)
+
+	draw := func(shadow bool) image.Image {
+		opts := opts
+		opts.ElementShadow = shadow
+
+		img, err := New().Draw(context.Background(), opts)
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return img
+	}
+
+	if n := darkPixels(draw(false), below, 0xF8); n != 0 {
+		t.Errorf("expected no pixels darker than the background without a shadow, got %d", n)
+	}
+
+	if n := darkPixels(draw(true), below, 0xF8); n < below.Dx()*below.Dy()/2 {
+		t.Errorf("expected the shadow below the avatar ring, got %d dark pixels of %d", n, below.Dx()*below.Dy())
+	}
+}
@@ -0,0 +1,89 @@
+package preview
+
+import (
+	"context"
+	"fmt"
+	"image"
+	"sort"
+
+	"github.com/davidbyttow/govips/v2/vips"
+)
+
+// DrawSizes draws a preview once at the Options canvas size and downscales it to each of the widths
+// preserving the aspect ratio, which is cheaper than drawing it per width. The canvas has to be at least
+// as wide as the largest width. It returns the JPEG of each width encoded with Options.Quality.
+func (p *Preview) DrawSizes(ctx context.Context, opts Options, widths []int) (map[int][]byte, error) {
+	if len(widths) == 0 {
+		return nil, fmt.Errorf("no widths to draw the preview at")
+	}
+
+	sorted := append([]int(nil), widths...)
+	sort.Ints(sorted)
+
+	if sorted[0] < 1 {
+		return nil, fmt.Errorf("width must be positive, got %d", sorted[0])
+	}
+
+	if largest := sorted[len(sorted)-1]; largest > opts.CanvasW {
+		return nil, fmt.Errorf("width %d exceeds the canvas width %d", largest, opts.CanvasW)
+	}
+
+	quality, err := resolveQuality(opts.Quality)
+
+	if err != nil {
+		return nil, err
+	}
+
+	var full *vips.ImageRef
+
+	// vips has its own copy of the image, so the canvas can be reused
+	err = p.drawPooled(ctx, opts, func(img image.Image) error {
+		var err error
+		full, err = toVips(img)
+
+		return err
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer full.Close()
+
+	bufs := make(map[int][]byte, len(sorted))
+
+	for _, w := range sorted {
+		if _, exists := bufs[w]; exists {
+			continue
+		}
+
+		buf, err := exportWidth(full, w, quality, opts)
+
+		if err != nil {
+			return nil, fmt.Errorf("could not export the preview %dpx wide: %w", w, err)
+		}
+
+		bufs[w] = buf
+	}
+
+	return bufs, nil
+}
+
+// exportWidth downscales a copy of the drawn preview to the width and exports it to JPEG.
+func exportWidth(full *vips.ImageRef, w, quality int, opts Options) ([]byte, error) {
+	vipsImg, err := full.Copy()
+
+	if err != nil {
+		return nil, withKind(ErrEncode, fmt.Errorf("could not copy the preview: %w", err))
+	}
+
+	defer vipsImg.Close()
+
+	if w != vipsImg.Width() {
+		if err = vipsImg.Resize(float64(w)/float64(vipsImg.Width()), vips.KernelLanczos3); err != nil {
+			return nil, withKind(ErrEncode, fmt.Errorf("could not resize the preview: %w", err))
+		}
+	}
+
+	return export(vipsImg, FormatJPEG, quality, opts)
+}
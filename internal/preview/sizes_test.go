@@ -0,0 +1,41 @@
+package preview
+
+import (
+	"bytes"
+	"context"
+	"image/jpeg"
+	"testing"
+)
+
+func TestDrawSizes(t *testing.T) {
+	widths := []int{1200, 320, 640}
+
+	bufs, err := New().DrawSizes(context.Background(), testOptions(), widths)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(bufs) != len(widths) {
+		t.Fatalf("expected %d buffers, got %d", len(widths), len(bufs))
+	}
+
+	for _, w := range widths {
+		cfg, err := jpeg.DecodeConfig(bytes.NewReader(bufs[w]))
+
+		if err != nil {
+			t.Fatalf("could not decode the %dpx preview: %v", w, err)
+		}
+
+		// the aspect ratio of the 1200x630 canvas is preserved within the rounding
+		if h := w * 630 / 1200; cfg.Width != w || cfg.Height < h-1 || cfg.Height > h+1 {
+			t.Errorf("expected the preview of %dx%d, got %dx%d", w, h, cfg.Width, cfg.Height)
+		}
+	}
+}
+
+func TestDrawSizes_TooWide(t *testing.T) {
+	if _, err := New().DrawSizes(context.Background(), testOptions(), []int{640, 1600}); err == nil {
+		t.Error("expected an error for a width exceeding the canvas")
+	}
+}
@@ -0,0 +1,21 @@
+package preview
+
+import (
+	"time"
+)
+
+// Stats describes how a preview was drawn.
+type Stats struct {
+	// Time spent fetching the images
+	Fetch time.Duration
+	// Time spent resizing and decoding the fetched images
+	Prepare time.Duration
+	// Time spent laying out and drawing the elements
+	Draw time.Duration
+	// Number of bytes fetched by the image URL or filename
+	FetchedBytes map[string]int
+	// The title was cut to the max length or to MaxTitleLines
+	TitleTruncated bool
+	// Title font size the title was drawn with, smaller than Options.TitleSize if AutoFitTitle has shrunk it
+	TitleSize float64
+}
@@ -0,0 +1,49 @@
+package preview
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestDrawWithStats(t *testing.T) {
+	logo := readAsset(t, "logo.png")
+	opts := testOptions()
+	opts.LogoURL = "https://example.com/logo.png"
+	opts.Title = strings.Repeat("The quick brown fox jumps over the lazy dog ", 3)
+	opts.AutoFitTitle = true
+	opts.TitleSize = 200
+
+	img, stats, err := New(WithGetter(fakeGetter{buf: logo})).DrawWithStats(context.Background(), opts)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if img == nil {
+		t.Fatal("expected an image")
+	}
+
+	if stats.Fetch <= 0 || stats.Prepare <= 0 || stats.Draw <= 0 {
+		t.Errorf("expected the phase durations, got %+v", stats)
+	}
+
+	if got := stats.FetchedBytes[opts.LogoURL]; got != len(logo) {
+		t.Errorf("expected %d logo bytes, got %d", len(logo), got)
+	}
+
+	if !stats.TitleTruncated {
+		t.Error("expected the title truncated")
+	}
+
+	if stats.TitleSize <= 0 || stats.TitleSize >= opts.TitleSize {
+		t.Errorf("expected the title size fit below %g, got %g", opts.TitleSize, stats.TitleSize)
+	}
+
+	opts.Title = "Short"
+	opts.AutoFitTitle = false
+
+	if _, stats, _ = New(WithGetter(fakeGetter{buf: logo})).DrawWithStats(context.Background(), opts); stats.TitleTruncated || stats.TitleSize != opts.TitleSize {
+		t.Errorf("expected the title intact, got %+v", stats)
+	}
+}
@@ -0,0 +1,39 @@
+package preview
+
+import (
+	"fmt"
+	"image/color"
+	"math"
+	"strings"
+
+	"github.com/fogleman/gg"
+)
+
+// defaultStrokeColor is a semi-transparent black
+var defaultStrokeColor = color.RGBA{A: 204}
+
+// drawTitleStroke draws the wrapped title in the stroke color offset in rings around its position,
+// so the title drawn on top gets an outline of the stroke width.
+func (p *drawing) drawTitleStroke(x, y, maxWidth float64, align gg.Align, style textStyle) error {
+	if err := p.setColor(p.opts.TitleStrokeColor, defaultStrokeColor); err != nil {
+		return fmt.Errorf("invalid title stroke color: %w", err)
+	}
+
+	width := p.opts.TitleStrokeWidth
+
+	// the rings are 1px apart to leave no gaps next to the thin glyph parts
+	for r := width; r > 0; r-- {
+		steps := int(math.Max(8, math.Ceil(2*math.Pi*r)))
+
+		for i := 0; i < steps; i++ {
+			angle := 2 * math.Pi * float64(i) / float64(steps)
+			dx, dy := r*math.Cos(angle), r*math.Sin(angle)
+
+			if err := p.drawStringWrapped(strings.Join(p.layout.TitleLines, "\n"), x+dx, y+dy, maxWidth, p.opts.TitleLineHeight, align, style); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,68 @@
+package preview
+
+import (
+	"context"
+	"image"
+	"testing"
+)
+
+func TestDrawTitleStroke(t *testing.T) {
+	titleRect := image.Rect(0, DefaultPadding*2, 1200, DefaultPadding*2+120)
+
+	draw := func(width float64) image.Image {
+		opts := testOptions()
+		opts.Bg = "#FFFFFF"
+		opts.Title = "III"
+		opts.TitleColor = "#FFFFFF"
+		opts.TitleStrokeWidth = width
+		opts.TitleStrokeColor = "#000000"
+
+		img, err := New().Draw(context.Background(), opts)
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return img
+	}
+
+	if n := darkPixels(draw(0), titleRect, 0x80); n != 0 {
+		t.Errorf("expected no outline without a stroke width, got %d dark pixels", n)
+	}
+
+	img := draw(3)
+
+	if darkPixels(img, titleRect, 0x80) == 0 {
+		t.Fatal("expected the outline pixels")
+	}
+
+	// some row crosses a stem: the outline, the white fill and the outline again
+	surrounded := false
+
+	for y := titleRect.Min.Y; y < titleRect.Max.Y && !surrounded; y++ {
+		var pattern []bool
+
+		for x := titleRect.Min.X; x < titleRect.Max.X; x++ {
+			dark := darkPixels(img, image.Rect(x, y, x+1, y+1), 0x80) == 1
+
+			if len(pattern) == 0 || pattern[len(pattern)-1] != dark {
+				pattern = append(pattern, dark)
+			}
+		}
+
+		// light, dark, light, dark, light
+		surrounded = len(pattern) >= 5
+	}
+
+	if !surrounded {
+		t.Error("expected the outline around the glyph fill")
+	}
+
+	opts := testOptions()
+	opts.TitleStrokeWidth = 2
+	opts.TitleStrokeColor = "black"
+
+	if _, err := New().Draw(context.Background(), opts); err == nil {
+		t.Error("expected an invalid stroke color error")
+	}
+}
@@ -0,0 +1,133 @@
+package preview
+
+import (
+	"fmt"
+	"image/color"
+	"strings"
+	"unicode"
+
+	"golang.org/x/image/font"
+)
+
+// subtitleSpacing is the space between the title descenders and the subtitle relative to the subtitle size.
+const subtitleSpacing = 0.25
+
+// defaultSubtitleColor is a white dimmer than the title but brighter than the meta
+var defaultSubtitleColor = color.NRGBA{R: 255, G: 255, B: 255, A: 179}
+
+// subtitleStyle returns the style the subtitle is drawn with the face.
+func (p *drawing) subtitleStyle(face font.Face) textStyle {
+	return textStyle{face: face, colorEmoji: p.opts.ColorEmoji, reorder: hasRTL(p.opts.Subtitle)}
+}
+
+// subtitleGap returns the space between the bottom of the title of the size and the subtitle.
+func (p *drawing) subtitleGap(titleSize float64) (float64, error) {
+	font, err := p.loadFont(textFontSource(p.opts.TitleFont, p.opts.TitleWeight), titleSize)
+
+	if err != nil {
+		return 0, fmt.Errorf("could not load the title font: %w", err)
+	}
+
+	// the subtitle leaves room for the title descent
+	return float64(font.Metrics().Descent)/64 + p.opts.SubtitleSize*subtitleSpacing, nil
+}
+
+// subtitleHeight returns the height the subtitle takes below the title of the size, zero without a subtitle.
+func (p *drawing) subtitleHeight(titleSize float64) (float64, error) {
+	if p.opts.Subtitle == "" {
+		return 0, nil
+	}
+
+	gap, err := p.subtitleGap(titleSize)
+
+	if err != nil {
+		return 0, err
+	}
+
+	_, h, err := p.subtitleLines()
+
+	if err != nil {
+		return 0, err
+	}
+
+	return gap + h, nil
+}
+
+// subtitleLines returns the subtitle wrapped to the title width and cut to SubtitleMaxLines
+// with an ellipsis ending the last line, and the height of the lines.
+func (p *drawing) subtitleLines() ([]string, float64, error) {
+	font, err := p.loadFont(FontSource{}, p.opts.SubtitleSize)
+
+	if err != nil {
+		return nil, 0, fmt.Errorf("could not load the subtitle font: %w", err)
+	}
+
+	p.ctx.SetFontFace(font)
+
+	_, _, maxWidth, _ := p.titleRegion()
+	style := p.subtitleStyle(font)
+	subtitle := strings.TrimRightFunc(strings.ReplaceAll(p.opts.Subtitle, "\r\n", "\n"), unicode.IsSpace)
+	lines := p.wordWrap(subtitle, maxWidth, style)
+
+	if max := p.opts.SubtitleMaxLines; len(lines) > max {
+		lines = lines[:max]
+		lines[max-1] = p.ellipsize(lines[max-1], maxWidth, style)
+	}
+
+	_, h := p.ctx.MeasureMultilineString(strings.Join(lines, "\n"), DefaultLineHeight)
+
+	return lines, h, nil
+}
+
+// ellipsize drops the trailing words of the line until it fits the width with an ellipsis appended.
+// It drops single runes of a line without whitespace to break at.
+func (p *drawing) ellipsize(line string, width float64, style textStyle) string {
+	line = strings.TrimRightFunc(line, unicode.IsSpace)
+
+	for line != "" && p.measureString(line+"…", style) > width {
+		runes := []rune(line)
+		cut := len(runes) - 1
+
+		for cut > 0 && !unicode.IsSpace(runes[cut]) {
+			cut--
+		}
+
+		if cut == 0 {
+			cut = len(runes) - 1
+		}
+
+		line = strings.TrimRightFunc(string(runes[:cut]), unicode.IsSpace)
+	}
+
+	return line + "…"
+}
+
+// drawSubtitle draws the subtitle lines in their box aligned like the title.
+func (p *drawing) drawSubtitle() error {
+	if p.opts.Subtitle == "" {
+		return nil
+	}
+
+	font, err := p.loadFont(FontSource{}, p.opts.SubtitleSize)
+
+	if err != nil {
+		return fmt.Errorf("could not load the subtitle font: %w", err)
+	}
+
+	p.ctx.SetFontFace(font)
+
+	if err := p.setColor(p.opts.SubtitleColor, defaultSubtitleColor); err != nil {
+		return fmt.Errorf("invalid subtitle color: %w", err)
+	}
+
+	titleFont, err := p.loadFont(textFontSource(p.opts.TitleFont, p.opts.TitleWeight), p.opts.TitleSize)
+
+	if err != nil {
+		return fmt.Errorf("could not load the title font: %w", err)
+	}
+
+	box := p.layout.Subtitle
+	align := p.titleAlign(p.titleStyle(titleFont))
+
+	return p.drawStringWrapped(strings.Join(p.layout.SubtitleLines, "\n"), box.X, box.Y, box.W, DefaultLineHeight, align, p.subtitleStyle(font))
+}
@@ -0,0 +1,56 @@
+package preview
+
+import (
+	"context"
+	"image"
+	"strings"
+	"testing"
+)
+
+func TestDrawSubtitle(t *testing.T) {
+	opts := testOptions()
+	opts.Title = "A title long enough to be wrapped into two lines on the card"
+	opts.Subtitle = strings.Repeat("A description that goes on and on ", 10)
+	opts.SubtitleMaxLines = 2
+
+	d := New().newDrawing(opts.withDefaults())
+	img, err := d.draw(context.Background())
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	title, subtitle := d.layout.Title, d.layout.Subtitle
+
+	if len(d.layout.TitleLines) < 2 || subtitle.Y < title.Y+title.H {
+		t.Errorf("expected the subtitle %+v below the wrapped title %+v", subtitle, title)
+	}
+
+	lines := d.layout.SubtitleLines
+
+	if len(lines) != 2 || !strings.HasSuffix(lines[1], "…") {
+		t.Errorf("expected the subtitle cut to 2 lines with an ellipsis, got %q", lines)
+	}
+
+	if !hasInk(img, image.Rect(int(subtitle.X), int(subtitle.Y), int(subtitle.X+subtitle.W), int(subtitle.Y+subtitle.H))) {
+		t.Errorf("expected the subtitle drawn within %+v", subtitle)
+	}
+
+	// nothing is drawn below the cut lines
+	below := image.Rect(0, int(subtitle.Y+subtitle.H)+16, opts.CanvasW, opts.CanvasH)
+
+	if hasInk(img, below) {
+		t.Errorf("expected no subtitle lines below %+v", subtitle)
+	}
+
+	opts.Subtitle = ""
+	d = New().newDrawing(opts.withDefaults())
+
+	if _, err := d.draw(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if d.layout.Subtitle != (Rect{}) || d.layout.SubtitleLines != nil {
+		t.Errorf("expected no subtitle, got %+v", d.layout.Subtitle)
+	}
+}
@@ -0,0 +1,112 @@
+package preview
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strconv"
+
+	"github.com/davidbyttow/govips/v2/vips"
+)
+
+// svgSniffLen is how many leading bytes are looked through for the root element.
+const svgSniffLen = 1024
+
+var (
+	svgSniffRe   = regexp.MustCompile(`<svg[\s>]`)
+	svgRootRe    = regexp.MustCompile(`<svg(?:\s[^>]*)?>`)
+	svgWidthRe   = regexp.MustCompile(`\swidth="\s*([0-9.]+)(?:px)?\s*"`)
+	svgHeightRe  = regexp.MustCompile(`\sheight="\s*([0-9.]+)(?:px)?\s*"`)
+	svgViewBoxRe = regexp.MustCompile(`\sviewBox="\s*[-0-9.]+[\s,]+[-0-9.]+[\s,]+([0-9.]+)[\s,]+([0-9.]+)\s*"`)
+)
+
+// isSVG sniffs the beginning of the buffer for an SVG root element.
+func isSVG(buf []byte) bool {
+	if len(buf) > svgSniffLen {
+		buf = buf[:svgSniffLen]
+	}
+
+	return svgSniffRe.Match(buf)
+}
+
+// svgSize returns the intrinsic size of an SVG from the width and height attributes of its root element
+// falling back to the viewBox. Zero values are returned for an SVG without an intrinsic size.
+func svgSize(buf []byte) (w, h float64) {
+	root := svgRootRe.Find(buf)
+
+	if m := svgWidthRe.FindSubmatch(root); m != nil {
+		w, _ = strconv.ParseFloat(string(m[1]), 64)
+	}
+
+	if m := svgHeightRe.FindSubmatch(root); m != nil {
+		h, _ = strconv.ParseFloat(string(m[1]), 64)
+	}
+
+	m := svgViewBoxRe.FindSubmatch(root)
+
+	if m == nil || (w > 0 && h > 0) {
+		return w, h
+	}
+
+	vw, _ := strconv.ParseFloat(string(m[1]), 64)
+	vh, _ := strconv.ParseFloat(string(m[2]), 64)
+
+	if vw <= 0 || vh <= 0 {
+		return w, h
+	}
+
+	// a single dimension keeps the viewBox aspect ratio
+	switch {
+	case w > 0:
+		h = w * vh / vw
+	case h > 0:
+		w = h * vw / vh
+	default:
+		w, h = vw, vh
+	}
+
+	return w, h
+}
+
+// rasterizeSVG renders an SVG via vips into a PNG buffer of w*h px, cropped like a thumbnail.
+// A zero width is derived from the SVG aspect ratio. An SVG without an intrinsic size
+// is rendered at the requested dimensions.
+func (p *Preview) rasterizeSVG(buf []byte, w, h int, crop vips.Interesting) ([]byte, error) {
+	svgW, svgH := svgSize(buf)
+
+	if svgW <= 0 || svgH <= 0 {
+		if w == 0 {
+			w = h
+		}
+
+		// give the root element the requested size
+		root := svgRootRe.Find(buf)
+
+		if root == nil {
+			return nil, fmt.Errorf("could not find the SVG root element")
+		}
+
+		sized := append([]byte(fmt.Sprintf(`<svg width="%d" height="%d"`, w, h)), root[len("<svg"):]...)
+		buf = bytes.Replace(buf, root, sized, 1)
+	} else if w == 0 {
+		w = int(float64(h)*svgW/svgH + 0.5)
+	}
+
+	p.logger.Printf("Rasterizing an SVG to %dx%d px", w, h)
+
+	vipsImg, err := vips.NewThumbnailFromBuffer(buf, w, h, crop)
+
+	if err != nil {
+		return nil, fmt.Errorf("could not rasterize an SVG: %w", err)
+	}
+
+	defer vipsImg.Close()
+
+	buf, _, err = vipsImg.ExportPng(vips.NewPngExportParams())
+
+	if err != nil {
+		return nil, err
+	}
+
+	return buf, nil
+}
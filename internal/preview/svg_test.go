@@ -0,0 +1,82 @@
+package preview
+
+import (
+	"bytes"
+	"image"
+	"testing"
+
+	"github.com/davidbyttow/govips/v2/vips"
+)
+
+func TestScaleSVG(t *testing.T) {
+	testCases := []struct {
+		name  string
+		svg   string
+		wantW int
+	}{{
+		name:  "intrinsic size",
+		svg:   `<svg xmlns="http://www.w3.org/2000/svg" width="200" height="50"><rect width="200" height="50" fill="#fff"/></svg>`,
+		wantW: 192,
+	}, {
+		name:  "viewBox only",
+		svg:   `<?xml version="1.0"?><svg xmlns="http://www.w3.org/2000/svg" viewBox="0 0 100 50"><rect width="100" height="50" fill="#fff"/></svg>`,
+		wantW: 96,
+	}, {
+		name:  "no intrinsic size",
+		svg:   `<svg xmlns="http://www.w3.org/2000/svg"><circle cx="50%" cy="50%" r="50%" fill="#fff"/></svg>`,
+		wantW: 48,
+	}}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			buf, err := New().scale([]byte(tt.svg), 48)
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			img, format, err := image.Decode(bytes.NewReader(buf))
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if format != "png" {
+				t.Errorf("expected a PNG, got %s", format)
+			}
+
+			if img.Bounds().Dy() != 48 || img.Bounds().Dx() != tt.wantW {
+				t.Errorf("expected a %dx48 raster, got %dx%d", tt.wantW, img.Bounds().Dx(), img.Bounds().Dy())
+			}
+		})
+	}
+}
+
+func TestResizeSVG(t *testing.T) {
+	svg := `<svg xmlns="http://www.w3.org/2000/svg" viewBox="0 0 10 10"><rect width="10" height="10" fill="#fff"/></svg>`
+	buf, err := New().resize([]byte(svg), 64, 64, 0, vips.InterestingAttention)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	config, _, err := image.DecodeConfig(bytes.NewReader(buf))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if config.Width != 64 || config.Height != 64 {
+		t.Errorf("expected a 64x64 raster, got %dx%d", config.Width, config.Height)
+	}
+}
+
+func TestIsSVG(t *testing.T) {
+	if !isSVG([]byte(`<?xml version="1.0"?>` + "\n" + `<svg viewBox="0 0 1 1"></svg>`)) {
+		t.Error("expected an SVG to be detected")
+	}
+
+	if isSVG([]byte("\x89PNG\r\n")) || isSVG([]byte("<svgfoo>")) {
+		t.Error("expected a non-SVG not to be detected")
+	}
+}
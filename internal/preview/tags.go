@@ -0,0 +1,94 @@
+package preview
+
+import (
+	"fmt"
+	"image/color"
+)
+
+const (
+	tagSize = 24.0
+	// space in px between the tag text and the chip edges
+	tagPadX = 14.0
+	tagPadY = 6.0
+	// space in px between the chips and their rows
+	tagGap = 10.0
+)
+
+// defaultTagColor is the accent color of the label
+var defaultTagColor = color.RGBA{R: 0xFF, G: 0xB8, A: 0xFF}
+
+// tagBoxes lays the tag chips out in rows of the max width starting at the top left corner of 0,0.
+// A chip that doesn't fit the row starts a new one, a chip wider than the max width takes a row of its own.
+func (p *drawing) tagBoxes(maxWidth float64) ([]Rect, error) {
+	font, err := p.loadFont(FontSource{}, tagSize)
+
+	if err != nil {
+		return nil, fmt.Errorf("could not load the tag font: %w", err)
+	}
+
+	p.ctx.SetFontFace(font)
+
+	h := p.ctx.FontHeight() + tagPadY*2
+	x, y := 0.0, 0.0
+	boxes := make([]Rect, 0, len(p.opts.Tags))
+
+	for _, tag := range p.opts.Tags {
+		w, _ := p.ctx.MeasureString(tag)
+		w += tagPadX * 2
+
+		if x > 0 && x+w > maxWidth {
+			x = 0
+			y += h + tagGap
+		}
+
+		boxes = append(boxes, Rect{X: x, Y: y, W: w, H: h})
+		x += w + tagGap
+	}
+
+	return boxes, nil
+}
+
+// tagsHeight returns the height the tag rows take above the title including the space below them.
+func (p *drawing) tagsHeight(boxes []Rect) float64 {
+	if len(boxes) == 0 {
+		return 0
+	}
+
+	last := boxes[len(boxes)-1]
+
+	return last.Y + last.H + p.opts.Padding/2
+}
+
+// drawTags draws the tag chips in the tag color with the tag text centered in them.
+func (p *drawing) drawTags() error {
+	if len(p.layout.Tags) == 0 {
+		return nil
+	}
+
+	font, err := p.loadFont(FontSource{}, tagSize)
+
+	if err != nil {
+		return fmt.Errorf("could not load the tag font: %w", err)
+	}
+
+	p.ctx.SetFontFace(font)
+
+	ascent := float64(font.Metrics().Ascent) / 64
+
+	for i, box := range p.layout.Tags {
+		if err := p.setColor(p.opts.TagColor, defaultTagColor); err != nil {
+			return fmt.Errorf("invalid tag color: %w", err)
+		}
+
+		p.ctx.DrawRoundedRectangle(box.X, box.Y, box.W, box.H, box.H/2)
+		p.ctx.Fill()
+
+		if err := p.setColor(p.opts.TagTextColor, color.Black); err != nil {
+			return fmt.Errorf("invalid tag text color: %w", err)
+		}
+
+		p.ctx.DrawString(p.opts.Tags[i], box.X+tagPadX, box.Y+tagPadY+ascent)
+	}
+
+	return nil
+}
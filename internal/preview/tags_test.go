@@ -0,0 +1,95 @@
+package preview
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestDrawTags(t *testing.T) {
+	opts := testOptions()
+	opts.Title = "The quick brown fox"
+	opts.Tags = []string{"Engineering", "Go", "Images"}
+	opts.TagColor = "#00FF00"
+
+	layout, err := New().Layout(opts)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(layout.Tags) != 3 {
+		t.Fatalf("expected 3 chips, got %v", layout.Tags)
+	}
+
+	for i, box := range layout.Tags {
+		if box.Y != layout.Tags[0].Y {
+			t.Errorf("expected the chips in a single row, got %v", layout.Tags)
+		}
+
+		if i > 0 && box.X <= layout.Tags[i-1].X+layout.Tags[i-1].W {
+			t.Errorf("expected the chips side by side, got %v", layout.Tags)
+		}
+	}
+
+	if last := layout.Tags[2]; layout.Title.Y <= last.Y+last.H {
+		t.Errorf("expected the title below the chips, got %v and %v", layout.Title, last)
+	}
+
+	noTags := opts
+	noTags.Tags = nil
+	plain, err := New().Layout(noTags)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if layout.Title.Y <= plain.Title.Y {
+		t.Errorf("expected the title to shift down from %v, got %v", plain.Title.Y, layout.Title.Y)
+	}
+
+	img, err := New().Draw(context.Background(), opts)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, box := range layout.Tags {
+		if r, g, b, _ := img.At(int(box.X)+4, int(box.Y+box.H/2)).RGBA(); r>>8 > 0x10 || g>>8 < 0xF0 || b>>8 > 0x10 {
+			t.Errorf("expected a green chip at %v, got %d,%d,%d", box, r>>8, g>>8, b>>8)
+		}
+	}
+}
+
+func TestDrawTags_Wrap(t *testing.T) {
+	opts := testOptions()
+	opts.Tags = strings.Fields("Engineering Design Product Marketing Infrastructure Security Research Operations Community")
+
+	layout, err := New().Layout(opts)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(layout.Tags) != len(opts.Tags) {
+		t.Fatalf("expected %d chips, got %d", len(opts.Tags), len(layout.Tags))
+	}
+
+	rows := map[float64]bool{}
+
+	for _, box := range layout.Tags {
+		rows[box.Y] = true
+
+		if box.X < layout.Title.X || box.X+box.W > layout.Title.X+layout.Title.W {
+			t.Errorf("expected the chip %v within the title width", box)
+		}
+	}
+
+	if len(rows) < 2 {
+		t.Errorf("expected the chips to wrap, got %v", layout.Tags)
+	}
+
+	if _, err := New().Draw(context.Background(), opts); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,245 @@
+package preview
+
+import (
+	"image"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/fogleman/gg"
+	xdraw "golang.org/x/image/draw"
+	"golang.org/x/image/font"
+)
+
+// textStyle is how a text is laid out and drawn glyph by glyph.
+type textStyle struct {
+	face font.Face
+	// extra px between the glyphs, negative values tighten them
+	tracking float64
+	// draw the emoji having an image in the emoji dir as the image
+	colorEmoji bool
+	// reorder the lines having right-to-left letters to be drawn left to right
+	reorder bool
+	// the text is a right-to-left paragraph
+	rtl bool
+}
+
+// plain reports whether the text can be drawn by gg as is.
+func (s textStyle) plain() bool {
+	return s.tracking == 0 && !s.colorEmoji && !s.reorder
+}
+
+// measureString returns the width of the string with the tracking applied between its glyphs.
+func (p *drawing) measureString(s string, style textStyle) float64 {
+	w, _ := p.ctx.MeasureString(s)
+
+	if n := utf8.RuneCountInString(s); n > 1 {
+		w += style.tracking * float64(n-1)
+	}
+
+	return w
+}
+
+// wordWrap wraps the string like gg's WordWrap measuring the lines with the tracking.
+func (p *drawing) wordWrap(s string, width float64, style textStyle) []string {
+	if style.tracking == 0 {
+		return p.ctx.WordWrap(s, width)
+	}
+
+	var lines []string
+
+	for _, line := range strings.Split(s, "\n") {
+		fields := splitOnSpace(line)
+
+		if len(fields)%2 == 1 {
+			fields = append(fields, "")
+		}
+
+		x := ""
+
+		for i := 0; i < len(fields); i += 2 {
+			if p.measureString(x+fields[i], style) > width {
+				// a word wider than the line takes the whole line
+				if x == "" {
+					lines = append(lines, fields[i])
+					continue
+				}
+
+				lines = append(lines, x)
+				x = ""
+			}
+
+			x += fields[i] + fields[i+1]
+		}
+
+		if x != "" {
+			lines = append(lines, x)
+		}
+	}
+
+	for i, line := range lines {
+		lines[i] = strings.TrimSpace(line)
+	}
+
+	return lines
+}
+
+// splitOnSpace splits the string into the alternating runs of non-whitespace and whitespace.
+func splitOnSpace(s string) []string {
+	var fields []string
+
+	start := 0
+	wasSpace := false
+
+	for i, r := range s {
+		isSpace := unicode.IsSpace(r)
+
+		if isSpace != wasSpace && i > 0 {
+			fields = append(fields, s[start:i])
+			start = i
+		}
+
+		wasSpace = isSpace
+	}
+
+	return append(fields, s[start:])
+}
+
+// drawString draws the string at the baseline and returns the x the next glyph would go at.
+func (p *drawing) drawString(s string, x, baseline float64, style textStyle) (float64, error) {
+	if style.reorder {
+		s = visualOrder(s, style.rtl)
+	}
+
+	if style.plain() {
+		w, _ := p.ctx.MeasureString(s)
+		p.ctx.DrawString(s, x, baseline)
+
+		return x + w, nil
+	}
+
+	if !style.colorEmoji {
+		return p.drawTracked(s, x, baseline, style), nil
+	}
+
+	metrics := style.face.Metrics()
+	ascent := float64(metrics.Ascent) / 64
+	side := float64(metrics.Ascent+metrics.Descent) / 64
+
+	for _, run := range splitEmoji(s) {
+		var img image.Image
+
+		if run.emoji {
+			var err error
+
+			if img, err = p.fonts.emojiImage(run.text); err != nil {
+				return 0, err
+			}
+
+			if img == nil {
+				img = p.emoji[run.text]
+			}
+
+			if img == nil {
+				p.logger.Printf("No color image for the emoji %q, drawing the glyph", run.text)
+			}
+		}
+
+		if img == nil {
+			x = p.drawTracked(run.text, x, baseline, style)
+			continue
+		}
+
+		// the emoji keep their glyph advance, so the text is laid out the same way as without the images
+		advance := p.measureString(run.text, style)
+		scaled := image.NewRGBA(image.Rect(0, 0, int(side+0.5), int(side+0.5)))
+		xdraw.CatmullRom.Scale(scaled, scaled.Bounds(), img, img.Bounds(), xdraw.Src, nil)
+		p.ctx.DrawImage(scaled, int(x+(advance-side)/2+0.5), int(baseline-ascent+0.5))
+
+		x += advance + style.tracking
+	}
+
+	return x, nil
+}
+
+// drawTracked draws the string glyph by glyph adding the tracking to the advances
+// and returns the x the next glyph would go at.
+func (p *drawing) drawTracked(s string, x, baseline float64, style textStyle) float64 {
+	if style.tracking == 0 {
+		w, _ := p.ctx.MeasureString(s)
+		p.ctx.DrawString(s, x, baseline)
+
+		return x + w
+	}
+
+	prev := rune(-1)
+
+	for _, r := range s {
+		if prev >= 0 {
+			x += float64(style.face.Kern(prev, r)) / 64
+		}
+
+		glyph := string(r)
+		w, _ := p.ctx.MeasureString(glyph)
+		p.ctx.DrawString(glyph, x, baseline)
+
+		x += w + style.tracking
+		prev = r
+	}
+
+	return x
+}
+
+// drawStringAnchored draws the string like gg's DrawStringAnchored with the style applied.
+func (p *drawing) drawStringAnchored(s string, x, y, ax, ay float64, style textStyle) error {
+	if style.plain() {
+		p.ctx.DrawStringAnchored(s, x, y, ax, ay)
+
+		return nil
+	}
+
+	x -= ax * p.measureString(s, style)
+	y += ay * p.ctx.FontHeight()
+
+	_, err := p.drawString(s, x, y, style)
+
+	return err
+}
+
+// drawStringWrapped draws the string like gg's DrawStringWrapped (with ax = 0 and ay = 0) with the style applied.
+func (p *drawing) drawStringWrapped(s string, x, y, width, lineSpacing float64, align gg.Align, style textStyle) error {
+	if style.plain() {
+		var ax float64
+
+		switch align {
+		case gg.AlignCenter:
+			ax = 0.5
+		case gg.AlignRight:
+			ax = 1
+		}
+
+		p.ctx.DrawStringWrapped(s, x+width*ax, y, ax, 0, width, lineSpacing, align)
+
+		return nil
+	}
+
+	fontHeight := p.ctx.FontHeight()
+
+	for i, line := range p.wordWrap(s, width, style) {
+		lineX := x
+		baseline := y + fontHeight + float64(i)*fontHeight*lineSpacing
+
+		switch align {
+		case gg.AlignCenter:
+			lineX += (width - p.measureString(line, style)) / 2
+		case gg.AlignRight:
+			lineX += width - p.measureString(line, style)
+		}
+
+		if _, err := p.drawString(line, lineX, baseline, style); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
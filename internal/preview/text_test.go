@@ -0,0 +1,179 @@
+package preview
+
+import (
+	"context"
+	"image"
+	"reflect"
+	"testing"
+)
+
+// inkWidth returns the distance between the leftmost and the rightmost columns with ink inside the rectangle.
+func inkWidth(img image.Image, rect image.Rectangle) int {
+	left, right := -1, -1
+
+	for x := rect.Min.X; x < rect.Max.X; x++ {
+		if hasInk(img, image.Rect(x, rect.Min.Y, x+1, rect.Max.Y)) {
+			if left < 0 {
+				left = x
+			}
+
+			right = x
+		}
+	}
+
+	return right - left
+}
+
+func TestDrawTracking(t *testing.T) {
+	const avaD = 64
+
+	authorRect := image.Rect(0, 0, 1200, DefaultPadding+avaD)
+	titleRect := image.Rect(0, DefaultPadding*2+avaD, 1200, DefaultPadding*2+avaD+100)
+
+	widths := func(tracking float64) (title, author int) {
+		opts := testOptions()
+		opts.Title = "Tracking"
+		opts.Author = "@Tester"
+		opts.AvaD = avaD
+		opts.TitleTracking = tracking
+		opts.AuthorTracking = tracking
+
+		img, err := New().Draw(context.Background(), opts)
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return inkWidth(img, titleRect), inkWidth(img, authorRect)
+	}
+
+	title, author := widths(0)
+	looseTitle, looseAuthor := widths(10)
+	tightTitle, tightAuthor := widths(-3)
+
+	if !(tightTitle < title && title < looseTitle) {
+		t.Errorf("expected the title width to grow with the tracking, got %d, %d, %d", tightTitle, title, looseTitle)
+	}
+
+	if !(tightAuthor < author && author < looseAuthor) {
+		t.Errorf("expected the author width to grow with the tracking, got %d, %d, %d", tightAuthor, author, looseAuthor)
+	}
+
+	// 7 gaps between the 8 glyphs of the title
+	if got := looseTitle - title; got < 65 || got > 75 {
+		t.Errorf("expected the title to grow by about 70px, got %d", got)
+	}
+}
+
+func TestWordWrapTracking(t *testing.T) {
+	d := New().newDrawing(testOptions())
+	defer d.release()
+
+	face, err := d.loadFont(FontSource{}, 76)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	d.ctx.SetFontFace(face)
+
+	s := "Lorem ipsum dolor sit amet\nconsectetur adipiscing elit"
+	want := d.ctx.WordWrap(s, 600)
+
+	if got := d.wordWrap(s, 600, textStyle{face: face, tracking: 0.001}); !reflect.DeepEqual(got, want) {
+		t.Errorf("expected the same lines as gg for a negligible tracking, got %q, want %q", got, want)
+	}
+
+	if got := d.wordWrap(s, 600, textStyle{face: face, tracking: 20}); len(got) <= len(want) {
+		t.Errorf("expected more lines with a loose tracking, got %q", got)
+	}
+}
+
+func TestDrawTitleLineHeight(t *testing.T) {
+	// inkHeight returns the distance between the top and the bottom rows with ink
+	inkHeight := func(img image.Image) int {
+		top, bottom := -1, -1
+
+		for y := 0; y < 500; y++ {
+			if hasInk(img, image.Rect(0, y, 1200, y+1)) {
+				if top < 0 {
+					top = y
+				}
+
+				bottom = y
+			}
+		}
+
+		return bottom - top
+	}
+
+	heights := map[float64]int{}
+
+	for _, lineHeight := range []float64{0, 1, 1.2, 2} {
+		opts := testOptions()
+		opts.Title = "The quick brown fox jumps over the lazy dog and keeps running"
+		opts.TitleLineHeight = lineHeight
+
+		img, err := New().Draw(context.Background(), opts)
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		heights[lineHeight] = inkHeight(img)
+	}
+
+	if heights[0] != heights[DefaultLineHeight] {
+		t.Errorf("expected the default line height for zero, got %v", heights)
+	}
+
+	if !(heights[1] < heights[1.2] && heights[1.2] < heights[2]) {
+		t.Errorf("expected the two-line title to grow with the line height, got %v", heights)
+	}
+}
+
+func TestDrawTitleLineBreaks(t *testing.T) {
+	// lines counts the bands of the rows with ink above the label row,
+	// the titles are in capitals not to have the dots and the descenders apart
+	lines := func(img image.Image) int {
+		n := 0
+		inLine := false
+
+		for y := 0; y < 500; y++ {
+			ink := hasInk(img, image.Rect(0, y, 1200, y+1))
+
+			if ink && !inLine {
+				n++
+			}
+
+			inLine = ink
+		}
+
+		return n
+	}
+
+	testCases := []struct {
+		title string
+		want  int
+	}{
+		{"FIRST SECOND", 1},
+		{"FIRST\nSECOND", 2},
+		{"FIRST\r\nSECOND\n", 2},
+		{"FIRST\nTHE QUICK BROWN FOX JUMPS OVER THE LAZY DOG", 3},
+	}
+
+	for _, tt := range testCases {
+		opts := testOptions()
+		opts.Title = tt.title
+
+		img, err := New().Draw(context.Background(), opts)
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if got := lines(img); got != tt.want {
+			t.Errorf("expected %d lines for %q, got %d", tt.want, tt.title, got)
+		}
+	}
+}
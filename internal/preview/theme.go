@@ -0,0 +1,153 @@
+package preview
+
+// Theme bundles the look of the previews: the colors, sizes, fonts, spacing and overlay.
+// Options.ApplyTheme fills the unset Options fields from it, the zero Theme fields are left unset.
+type Theme struct {
+	// Background HEX-color, gradient or image (see Options.Bg)
+	Bg string
+	// Foreground overlay HEX-color and opacity
+	OverlayColor string
+	Opacity      float64
+	// Fade the overlay in from the top
+	OverlayGradient bool
+	// Text HEX-colors
+	TitleColor    string
+	SubtitleColor string
+	AuthorColor   string
+	MetaColor     string
+	// Tag chip and text HEX-colors
+	TagColor     string
+	TagTextColor string
+	// Avatar border and card frame HEX-colors
+	AvaBorderColor  string
+	CardBorderColor string
+	// Font sizes
+	TitleSize    float64
+	SubtitleSize float64
+	AuthorSize   float64
+	LabelSize    float64
+	// Fonts and weights (see Options.TitleFont and Options.TitleWeight)
+	FontSet      *FontSet
+	TitleFont    FontSource
+	TitleWeight  string
+	AuthorFont   FontSource
+	AuthorWeight string
+	// Spacing
+	Margin  float64
+	Padding float64
+	// Card corner radius
+	CardRadius int
+	// Title horizontal alignment
+	TitleAlign string
+}
+
+// Built-in themes
+var (
+	// ThemeDark is light text on a dark slate
+	ThemeDark = Theme{
+		Bg:            "#0F172A",
+		OverlayColor:  "#000000",
+		TitleColor:    "#F8FAFC",
+		SubtitleColor: "#CBD5E1",
+		AuthorColor:   "#E2E8F0",
+		MetaColor:     "#94A3B8",
+		TagColor:      "#1E293B",
+		TagTextColor:  "#E2E8F0",
+	}
+	// ThemeLight is dark text on a light gray, an image background is lightened by a white overlay
+	ThemeLight = Theme{
+		Bg:             "#F8FAFC",
+		OverlayColor:   "#FFFFFF",
+		Opacity:        0.7,
+		TitleColor:     "#0F172A",
+		SubtitleColor:  "#334155",
+		AuthorColor:    "#1E293B",
+		MetaColor:      "#64748B",
+		TagColor:       "#E2E8F0",
+		TagTextColor:   "#0F172A",
+		AvaBorderColor: "#FFFFFF",
+	}
+	// ThemeMinimal is black text on white with more air around a smaller title
+	ThemeMinimal = Theme{
+		Bg:            "#FFFFFF",
+		OverlayColor:  "#FFFFFF",
+		TitleColor:    "#111111",
+		SubtitleColor: "#555555",
+		AuthorColor:   "#333333",
+		MetaColor:     "#777777",
+		TagColor:      "#F2F2F2",
+		TagTextColor:  "#111111",
+		TitleSize:     64,
+		Padding:       72,
+	}
+)
+
+// ApplyTheme returns a copy of Options with the unset fields filled from the theme, so the fields set explicitly
+// win over the theme. A background set as BackgroundSource counts as set Bg. The theme can only turn
+// OverlayGradient on as false is the unset value.
+func (o Options) ApplyTheme(t Theme) Options {
+	if o.Bg == "" && o.BackgroundSource == nil {
+		o.Bg = t.Bg
+	}
+
+	for _, f := range []struct {
+		field *string
+		theme string
+	}{
+		{&o.OverlayColor, t.OverlayColor},
+		{&o.TitleColor, t.TitleColor},
+		{&o.SubtitleColor, t.SubtitleColor},
+		{&o.AuthorColor, t.AuthorColor},
+		{&o.MetaColor, t.MetaColor},
+		{&o.TagColor, t.TagColor},
+		{&o.TagTextColor, t.TagTextColor},
+		{&o.AvaBorderColor, t.AvaBorderColor},
+		{&o.CardBorderColor, t.CardBorderColor},
+		{&o.TitleWeight, t.TitleWeight},
+		{&o.AuthorWeight, t.AuthorWeight},
+		{&o.TitleAlign, t.TitleAlign},
+	} {
+		if *f.field == "" {
+			*f.field = f.theme
+		}
+	}
+
+	for _, f := range []struct {
+		field *float64
+		theme float64
+	}{
+		{&o.Opacity, t.Opacity},
+		{&o.TitleSize, t.TitleSize},
+		{&o.SubtitleSize, t.SubtitleSize},
+		{&o.AuthorSize, t.AuthorSize},
+		{&o.LabelSize, t.LabelSize},
+		{&o.Margin, t.Margin},
+		{&o.Padding, t.Padding},
+	} {
+		if *f.field == 0 {
+			*f.field = f.theme
+		}
+	}
+
+	if !o.OverlayGradient {
+		o.OverlayGradient = t.OverlayGradient
+	}
+
+	if o.FontSet == nil {
+		o.FontSet = t.FontSet
+	}
+
+	if o.TitleFont.Path == "" && len(o.TitleFont.Data) == 0 {
+		o.TitleFont = t.TitleFont
+	}
+
+	if o.AuthorFont.Path == "" && len(o.AuthorFont.Data) == 0 {
+		o.AuthorFont = t.AuthorFont
+	}
+
+	if o.CardRadius == 0 {
+		o.CardRadius = t.CardRadius
+	}
+
+	return o
+}
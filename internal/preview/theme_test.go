@@ -0,0 +1,57 @@
+package preview
+
+import (
+	"context"
+	"image/color"
+	"testing"
+)
+
+func TestApplyTheme(t *testing.T) {
+	opts := Options{CanvasW: 1200, CanvasH: 630, Title: "Test"}.ApplyTheme(ThemeLight)
+
+	if opts.Bg != "#F8FAFC" || opts.TitleColor != "#0F172A" || opts.TagColor != "#E2E8F0" || opts.Opacity != 0.7 {
+		t.Errorf("expected the light theme colors, got %+v", opts)
+	}
+
+	img, err := New().Draw(context.Background(), opts)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := color.RGBAModel.Convert(img.At(5, 5)).(color.RGBA); got != (color.RGBA{R: 0xF8, G: 0xFA, B: 0xFC, A: 0xFF}) {
+		t.Errorf("expected the light theme background, got %v", got)
+	}
+
+	// the explicit fields win over the theme
+	opts = Options{
+		Bg:         "#FF0000",
+		TitleColor: "#00FF00",
+		TitleSize:  40,
+		Padding:    10,
+	}.ApplyTheme(ThemeMinimal)
+
+	if opts.Bg != "#FF0000" || opts.TitleColor != "#00FF00" || opts.TitleSize != 40 || opts.Padding != 10 {
+		t.Errorf("expected the explicit fields kept, got %+v", opts)
+	}
+
+	if opts.AuthorColor != ThemeMinimal.AuthorColor {
+		t.Errorf("expected the unset author color from the theme, got %s", opts.AuthorColor)
+	}
+
+	for name, theme := range map[string]Theme{"dark": ThemeDark, "light": ThemeLight, "minimal": ThemeMinimal} {
+		opts := testOptions()
+		opts.Bg = ""
+		opts.Author = "@Tester"
+		opts.Tags = []string{"Go"}
+
+		if _, err := New().Draw(context.Background(), opts.ApplyTheme(theme)); err != nil {
+			t.Errorf("%s: %v", name, err)
+		}
+	}
+
+	// a typed background is an explicit background too
+	if opts = (Options{BackgroundSource: HexBackground("#00FF00")}).ApplyTheme(ThemeDark); opts.Bg != "" {
+		t.Errorf("expected the background source kept, got Bg %s", opts.Bg)
+	}
+}
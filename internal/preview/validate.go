@@ -0,0 +1,263 @@
+package preview
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// The sane range of the title line height
+const (
+	minLineHeight = 0.5
+	maxLineHeight = 3.0
+)
+
+// ValidationError lists all the problems found in Options.
+type ValidationError struct {
+	Problems []string
+}
+
+func (e *ValidationError) Error() string {
+	return "invalid options: " + strings.Join(e.Problems, "; ")
+}
+
+// Is makes errors.Is match ErrInvalidOptions.
+func (e *ValidationError) Is(target error) bool {
+	return target == ErrInvalidOptions
+}
+
+// Validate checks Options for nonsensical values and returns a *ValidationError listing all of them.
+func (o Options) Validate() error {
+	var problems []string
+
+	if o.CanvasW <= 0 || o.CanvasH <= 0 {
+		problems = append(problems, fmt.Sprintf("canvas size must be positive, got %dx%d", o.CanvasW, o.CanvasH))
+	} else if o.CanvasW > MaxCanvasSide || o.CanvasH > MaxCanvasSide || o.CanvasW*o.CanvasH > MaxCanvasPixels {
+		problems = append(problems, fmt.Sprintf("canvas size must be within %dx%d px and %d px in total, got %dx%d", MaxCanvasSide, MaxCanvasSide, MaxCanvasPixels, o.CanvasW, o.CanvasH))
+	}
+
+	if o.Margin < 0 || o.Padding < 0 {
+		problems = append(problems, fmt.Sprintf("margin and padding must not be negative, got %g and %g", o.Margin, o.Padding))
+	}
+
+	if o.AvaD < 0 {
+		problems = append(problems, fmt.Sprintf("avatar diameter must not be negative, got %d", o.AvaD))
+	}
+
+	for name, pct := range map[string]float64{"title size": o.TitleSizePct, "author size": o.AuthorSizePct, "label size": o.LabelSizePct, "avatar diameter": o.AvaDPct} {
+		if pct < 0 || pct > 100 {
+			problems = append(problems, fmt.Sprintf("relative %s must be within 0-100%%, got %g", name, pct))
+		}
+	}
+
+	if o.CardRadius < 0 {
+		problems = append(problems, fmt.Sprintf("card radius must not be negative, got %d", o.CardRadius))
+	}
+
+	if o.CardBorderW < 0 {
+		problems = append(problems, fmt.Sprintf("card border width must not be negative, got %d", o.CardBorderW))
+	}
+
+	colors := []struct{ name, hex string }{
+		{"overlay", o.OverlayColor},
+		{"avatar border", o.AvaBorderColor},
+		{"avatar fallback", o.AvaFallbackColor},
+		{"title", o.TitleColor},
+		{"title stroke", o.TitleStrokeColor},
+		{"title band", o.TitleBandColor},
+		{"title shadow", o.TitleShadowColor},
+		{"subtitle", o.SubtitleColor},
+		{"author", o.AuthorColor},
+		{"meta", o.MetaColor},
+		{"tag", o.TagColor},
+		{"tag text", o.TagTextColor},
+		{"background pad", o.BgPadColor},
+		{"duotone dark", o.DuotoneDark},
+		{"duotone light", o.DuotoneLight},
+		{"card border", o.CardBorderColor},
+	}
+
+	for _, c := range colors {
+		if c.hex != "" && !hexRe.MatchString(c.hex) {
+			problems = append(problems, fmt.Sprintf("invalid %s color: %s", c.name, c.hex))
+		}
+	}
+
+	switch o.AvaShape {
+	case "", ShapeCircle, ShapeSquare, ShapeRounded:
+	default:
+		problems = append(problems, fmt.Sprintf("unknown avatar shape: %s", o.AvaShape))
+	}
+
+	switch o.TitleAlign {
+	case "", AlignLeft, AlignCenter, AlignRight:
+	default:
+		problems = append(problems, fmt.Sprintf("unknown title alignment: %s", o.TitleAlign))
+	}
+
+	switch o.TitleVAlign {
+	case "", VAlignTop, VAlignMiddle, VAlignBottom:
+	default:
+		problems = append(problems, fmt.Sprintf("unknown title vertical alignment: %s", o.TitleVAlign))
+	}
+
+	switch o.TitleDirection {
+	case "", DirectionAuto, DirectionLTR, DirectionRTL:
+	default:
+		problems = append(problems, fmt.Sprintf("unknown title direction: %s", o.TitleDirection))
+	}
+
+	switch o.LogoPosition {
+	case "", LogoBottomRight, LogoBottomLeft, LogoTopRight, LogoTopLeft:
+	default:
+		problems = append(problems, fmt.Sprintf("unknown logo position: %s", o.LogoPosition))
+	}
+
+	if o.Opacity != NoOverlay && (o.Opacity < 0 || o.Opacity > 1) {
+		problems = append(problems, fmt.Sprintf("opacity must be within 0-1, got %g", o.Opacity))
+	}
+
+	switch o.Format {
+	case "", FormatJPEG, FormatPNG, FormatWebP, FormatAVIF:
+	default:
+		problems = append(problems, fmt.Sprintf("unknown output format: %s", o.Format))
+	}
+
+	if o.AvifSpeed < 0 || o.AvifSpeed > maxAvifSpeed {
+		problems = append(problems, fmt.Sprintf("AVIF speed must be within 0-%d, got %d", maxAvifSpeed, o.AvifSpeed))
+	}
+
+	if o.Grain < 0 || o.Grain > 1 {
+		problems = append(problems, fmt.Sprintf("grain must be within 0-1, got %g", o.Grain))
+	}
+
+	if o.Quality < 0 || o.Quality > 100 {
+		problems = append(problems, fmt.Sprintf("quality must be within 0-100, got %d", o.Quality))
+	}
+
+	if o.TitleLineHeight != 0 && (o.TitleLineHeight < minLineHeight || o.TitleLineHeight > maxLineHeight) {
+		problems = append(problems, fmt.Sprintf("title line height must be within %g-%g, got %g", minLineHeight, maxLineHeight, o.TitleLineHeight))
+	}
+
+	for prefix := range o.FetchHeaders {
+		if u, err := url.Parse(prefix); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			problems = append(problems, fmt.Sprintf("fetch headers must be keyed by an absolute HTTP(S) URL prefix, got %q", prefix))
+		}
+	}
+
+	if o.MaxImageBytes < 0 {
+		problems = append(problems, fmt.Sprintf("max image bytes must not be negative, got %d", o.MaxImageBytes))
+	}
+
+	if o.MaxImagePixels < 0 {
+		problems = append(problems, fmt.Sprintf("max image pixels must not be negative, got %d", o.MaxImagePixels))
+	}
+
+	if _, exists := cropModes[o.CropMode]; !exists {
+		problems = append(problems, fmt.Sprintf("unknown crop mode: %s", o.CropMode))
+	}
+
+	switch o.BgFit {
+	case "", FitCover, FitContain, FitFill:
+	default:
+		problems = append(problems, fmt.Sprintf("unknown background fit: %s", o.BgFit))
+	}
+
+	switch o.BgFilter {
+	case "", BgFilterNone, BgFilterGrayscale, BgFilterDuotone:
+	default:
+		problems = append(problems, fmt.Sprintf("unknown background filter: %s", o.BgFilter))
+	}
+
+	if _, exists := gravityFocus[o.BgGravity]; o.BgGravity != "" && !exists {
+		problems = append(problems, fmt.Sprintf("unknown background gravity: %s", o.BgGravity))
+	}
+
+	if f := o.BgFocus; f != nil && (f.X < 0 || f.X > 1 || f.Y < 0 || f.Y > 1) {
+		problems = append(problems, fmt.Sprintf("background focus must be within 0-1, got %g,%g", f.X, f.Y))
+	}
+
+	switch bg := o.bg(); {
+	case bg == "" || hexRe.MatchString(bg):
+	case bg == dominantPrefix:
+		problems = append(problems, "invalid background: no image to pick the dominant color of")
+	case isGradient(bg):
+		if _, err := parseGradient(bg, float64(o.CanvasW), float64(o.CanvasH)); err != nil {
+			problems = append(problems, fmt.Sprintf("invalid background: %s", err))
+		}
+	default:
+		if err := validateURL(bgImageURL(bg)); err != nil {
+			problems = append(problems, fmt.Sprintf("invalid background: %s", err))
+		}
+	}
+
+	for _, ava := range append([]string{o.AvaURL}, o.AvaURLs...) {
+		if err := validateURL(ava); ava != "" && err != nil {
+			problems = append(problems, fmt.Sprintf("invalid avatar URL: %s", err))
+		}
+	}
+
+	if err := validateURL(o.LogoURL); o.LogoURL != "" && err != nil {
+		problems = append(problems, fmt.Sprintf("invalid logo URL: %s", err))
+	}
+
+	if o.MaxTitleLines < 0 {
+		problems = append(problems, fmt.Sprintf("max title lines must not be negative, got %d", o.MaxTitleLines))
+	}
+
+	if o.SubtitleSize < 0 || o.SubtitleMaxLines < 0 {
+		problems = append(problems, fmt.Sprintf("subtitle size and max lines must not be negative, got %g and %d", o.SubtitleSize, o.SubtitleMaxLines))
+	}
+
+	switch o.AuthorPosition {
+	case "", AlignLeft, AlignRight:
+	default:
+		problems = append(problems, fmt.Sprintf("unknown author position: %s", o.AuthorPosition))
+	}
+
+	for name, f := range map[string]FontSource{"title": o.TitleFont, "author": o.AuthorFont} {
+		if f.Path != "" && len(f.Data) > 0 {
+			problems = append(problems, fmt.Sprintf("%s font must have either a path or data, not both", name))
+		}
+	}
+
+	for name, weight := range map[string]string{"title": o.TitleWeight, "author": o.AuthorWeight} {
+		if _, exists := weightFonts[weight]; !exists {
+			problems = append(problems, fmt.Sprintf("unknown %s font weight: %s", name, weight))
+		}
+	}
+
+	if len(problems) > 0 {
+		return &ValidationError{Problems: problems}
+	}
+
+	return nil
+}
+
+// validateURL checks that an image reference is either an absolute HTTP(S) URL, a data URL or a filename.
+func validateURL(urlOrPath string) error {
+	u, err := url.Parse(urlOrPath)
+
+	if err != nil {
+		return fmt.Errorf("%q: %w", urlOrPath, err)
+	}
+
+	switch strings.ToLower(u.Scheme) {
+	case "":
+		return nil
+	case "data":
+		if !strings.Contains(u.Opaque, ",") {
+			return fmt.Errorf("malformed data URL: no comma separating the payload")
+		}
+
+		return nil
+	case "http", "https":
+		if u.Host == "" {
+			return fmt.Errorf("%q: missing host", urlOrPath)
+		}
+
+		return nil
+	}
+
+	return fmt.Errorf("%q: unsupported scheme %s", urlOrPath, u.Scheme)
+}
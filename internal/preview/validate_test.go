@@ -0,0 +1,337 @@
+package preview
+
+import (
+	"context"
+	"errors"
+	"image"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestOptionsValidate(t *testing.T) {
+	testCases := []struct {
+		name   string
+		modify func(*Options)
+		want   []string
+	}{{
+		name:   "valid",
+		modify: func(o *Options) {},
+	}, {
+		name: "valid references",
+		modify: func(o *Options) {
+			o.Bg = "https://example.com/bg.jpg"
+			o.AvaURL = "avatar.png"
+			o.LogoURL = "data:image/png;base64,iVBORw0KGgo="
+		},
+	}, {
+		name:   "zero canvas",
+		modify: func(o *Options) { o.CanvasW = 0 },
+		want:   []string{"canvas size"},
+	}, {
+		name:   "canvas too wide",
+		modify: func(o *Options) { o.CanvasW = MaxCanvasSide + 1 },
+		want:   []string{"canvas size must be within"},
+	}, {
+		name:   "canvas too large",
+		modify: func(o *Options) { o.CanvasW, o.CanvasH = MaxCanvasSide, MaxCanvasSide },
+		want:   []string{"canvas size must be within"},
+	}, {
+		name:   "negative padding",
+		modify: func(o *Options) { o.Padding = -1 },
+		want:   []string{"padding"},
+	}, {
+		name:   "negative avatar diameter",
+		modify: func(o *Options) { o.AvaD = -1 },
+		want:   []string{"avatar diameter"},
+	}, {
+		name:   "negative card radius",
+		modify: func(o *Options) { o.CardRadius = -1 },
+		want:   []string{"card radius"},
+	}, {
+		name:   "non-HEX tag color",
+		modify: func(o *Options) { o.TagColor = "green" },
+		want:   []string{"tag color"},
+	}, {
+		name:   "non-HEX card border color",
+		modify: func(o *Options) { o.CardBorderW, o.CardBorderColor = 2, "red" },
+		want:   []string{"card border color"},
+	}, {
+		name:   "opacity out of range",
+		modify: func(o *Options) { o.Opacity = 1.5 },
+		want:   []string{"opacity"},
+	}, {
+		name:   "no overlay",
+		modify: func(o *Options) { o.Opacity = NoOverlay },
+	}, {
+		name:   "AVIF speed out of range",
+		modify: func(o *Options) { o.AvifSpeed = 9 },
+		want:   []string{"AVIF speed"},
+	}, {
+		name: "fetch headers keyed by a relative prefix",
+		modify: func(o *Options) {
+			o.FetchHeaders = map[string]http.Header{"/private/": {"Authorization": {"Bearer token"}}}
+		},
+		want: []string{"fetch headers"},
+	}, {
+		name:   "relative title size out of range",
+		modify: func(o *Options) { o.TitleSizePct = 120 },
+		want:   []string{"relative title size"},
+	}, {
+		name:   "grain out of range",
+		modify: func(o *Options) { o.Grain = 2 },
+		want:   []string{"grain"},
+	}, {
+		name:   "quality out of range",
+		modify: func(o *Options) { o.Quality = 101 },
+		want:   []string{"quality"},
+	}, {
+		name:   "title line height out of range",
+		modify: func(o *Options) { o.TitleLineHeight = 0.1 },
+		want:   []string{"title line height"},
+	}, {
+		name:   "unknown background fit",
+		modify: func(o *Options) { o.BgFit = "stretch" },
+		want:   []string{"unknown background fit"},
+	}, {
+		name:   "unknown background filter",
+		modify: func(o *Options) { o.BgFilter = "sepia" },
+		want:   []string{"unknown background filter"},
+	}, {
+		name: "non-HEX duotone colors",
+		modify: func(o *Options) {
+			o.DuotoneDark = "navy"
+			o.DuotoneLight = "#FFFF"
+		},
+		want: []string{"invalid duotone dark color", "invalid duotone light color"},
+	}, {
+		name:   "non-HEX background pad color",
+		modify: func(o *Options) { o.BgPadColor = "black" },
+		want:   []string{"background pad color"},
+	}, {
+		name:   "unknown background gravity",
+		modify: func(o *Options) { o.BgGravity = "up" },
+		want:   []string{"unknown background gravity"},
+	}, {
+		name:   "background focus out of range",
+		modify: func(o *Options) { o.BgFocus = &Focus{X: 0.5, Y: 1.5} },
+		want:   []string{"background focus"},
+	}, {
+		name:   "negative max image bytes",
+		modify: func(o *Options) { o.MaxImageBytes = -1 },
+		want:   []string{"max image bytes"},
+	}, {
+		name:   "negative max image pixels",
+		modify: func(o *Options) { o.MaxImagePixels = -1 },
+		want:   []string{"max image pixels"},
+	}, {
+		name:   "unknown crop mode",
+		modify: func(o *Options) { o.CropMode = "smart" },
+		want:   []string{"unknown crop mode"},
+	}, {
+		name:   "malformed gradient",
+		modify: func(o *Options) { o.Bg = "gradient:45,#FF0000" },
+		want:   []string{"invalid background"},
+	}, {
+		name:   "malformed background source",
+		modify: func(o *Options) { o.BackgroundSource = GradientBackground{Colors: []string{"#FF0000"}} },
+		want:   []string{"invalid background"},
+	}, {
+		name:   "dominant color without an image",
+		modify: func(o *Options) { o.BackgroundSource = ColorFromImage("") },
+		want:   []string{"invalid background"},
+	}, {
+		name:   "unsupported background scheme",
+		modify: func(o *Options) { o.Bg = "ftp://example.com/bg.jpg" },
+		want:   []string{"invalid background"},
+	}, {
+		name:   "avatar URL without a host",
+		modify: func(o *Options) { o.AvaURL = "https:///avatar.png" },
+		want:   []string{"invalid avatar URL"},
+	}, {
+		name:   "malformed co-author URL",
+		modify: func(o *Options) { o.AvaURLs = []string{"http://%zz"} },
+		want:   []string{"invalid avatar URL"},
+	}, {
+		name:   "malformed logo data URL",
+		modify: func(o *Options) { o.LogoURL = "data:image/png;base64" },
+		want:   []string{"invalid logo URL"},
+	}, {
+		name:   "ambiguous font",
+		modify: func(o *Options) { o.TitleFont = FontSource{Path: "fonts/Symbola.ttf", Data: []byte{0}} },
+		want:   []string{"title font"},
+	}, {
+		name:   "unknown avatar shape",
+		modify: func(o *Options) { o.AvaShape = "star" },
+		want:   []string{"unknown avatar shape: star"},
+	}, {
+		name:   "unknown title alignment",
+		modify: func(o *Options) { o.TitleAlign = "justify" },
+		want:   []string{"unknown title alignment: justify"},
+	}, {
+		name:   "unknown title vertical alignment",
+		modify: func(o *Options) { o.TitleVAlign = "center" },
+		want:   []string{"unknown title vertical alignment: center"},
+	}, {
+		name:   "unknown title direction",
+		modify: func(o *Options) { o.TitleDirection = "ttb" },
+		want:   []string{"unknown title direction: ttb"},
+	}, {
+		name:   "unknown logo position",
+		modify: func(o *Options) { o.LogoPosition = "middle" },
+		want:   []string{"unknown logo position: middle"},
+	}, {
+		name: "known enum values",
+		modify: func(o *Options) {
+			o.AvaShape, o.TitleAlign, o.TitleVAlign = ShapeRounded, AlignCenter, VAlignBottom
+			o.TitleDirection, o.LogoPosition = DirectionRTL, LogoTopLeft
+		},
+	}, {
+		name: "invalid text colors",
+		modify: func(o *Options) {
+			o.TitleColor, o.AuthorColor, o.MetaColor = "white", "#12", "#GGGGGG"
+		},
+		want: []string{"invalid title color: white", "invalid author color: #12", "invalid meta color: #GGGGGG"},
+	}, {
+		name: "invalid title effect colors",
+		modify: func(o *Options) {
+			o.TitleStrokeColor, o.TitleShadowColor, o.TitleBandColor = "black", "black", "black"
+		},
+		want: []string{"invalid title stroke color", "invalid title shadow color", "invalid title band color"},
+	}, {
+		name: "invalid overlay and avatar colors",
+		modify: func(o *Options) {
+			o.OverlayColor, o.AvaBorderColor, o.AvaFallbackColor = "rgb(0,0,0)", "#FFFFF", "red"
+		},
+		want: []string{"invalid overlay color", "invalid avatar border color", "invalid avatar fallback color"},
+	}, {
+		name: "valid colors",
+		modify: func(o *Options) {
+			o.TitleColor, o.AuthorColor, o.MetaColor, o.OverlayColor = "#FFF", "#FFFFFFB3", "#ffffff80", "#000000"
+		},
+	}, {
+		name: "all problems joined",
+		modify: func(o *Options) {
+			o.CanvasH = -1
+			o.Opacity = -0.1
+			o.LogoURL = "file:///etc/passwd"
+		},
+		want: []string{"canvas size", "opacity", "invalid logo URL"},
+	}}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := testOptions()
+			tt.modify(&opts)
+			err := opts.Validate()
+
+			if len(tt.want) == 0 {
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				return
+			}
+
+			var validationErr *ValidationError
+
+			if !errors.As(err, &validationErr) {
+				t.Fatalf("expected a validation error, got %v", err)
+			}
+
+			if len(validationErr.Problems) != len(tt.want) {
+				t.Errorf("expected %d problems, got %v", len(tt.want), validationErr.Problems)
+			}
+
+			for _, want := range tt.want {
+				if !strings.Contains(err.Error(), want) {
+					t.Errorf("expected %q in %q", want, err)
+				}
+			}
+		})
+	}
+}
+
+func TestDrawValidates(t *testing.T) {
+	opts := testOptions()
+	opts.CanvasW = 0
+
+	var validationErr *ValidationError
+
+	if _, err := New().Draw(context.Background(), opts); !errors.As(err, &validationErr) {
+		t.Errorf("expected a validation error, got %v", err)
+	}
+}
+
+func TestOptionsWithDefaults(t *testing.T) {
+	strip, keep := true, false
+	got := Options{AvaURL: "avatar.png", LogoURL: "logo.png", Bg: "bg.jpg"}.withDefaults()
+	want := Options{
+		AvaURL:          "avatar.png",
+		LogoURL:         "logo.png",
+		Bg:              "bg.jpg",
+		TitleSize:       DefaultTitleSize,
+		TitleLineHeight: DefaultLineHeight,
+		AuthorSize:      DefaultAuthorSize,
+		LabelSize:       DefaultLabelSize,
+		AvaD:            DefaultAvaD,
+		LogoH:           DefaultLogoH,
+		Opacity:         DefaultOpacity,
+		Quality:         DefaultQuality,
+		Margin:          DefaultMargin,
+		Padding:         DefaultPadding,
+		StripMetadata:   &strip,
+	}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %+v, got %+v", want, got)
+	}
+
+	explicit := Options{TitleSize: 10, TitleLineHeight: 1.5, AuthorSize: 11, LabelSize: 12, AvaD: 13, LogoH: 14, Opacity: 0.1, Quality: 15, Margin: 16, Padding: 17, StripMetadata: &keep}
+
+	if got := explicit.withDefaults(); !reflect.DeepEqual(got, explicit) {
+		t.Errorf("expected explicit values to be kept, got %+v", got)
+	}
+
+	// zero values of the elements that are not drawn are kept not to change the layout
+	if got := (Options{Bg: "#000000"}).withDefaults(); got.AvaD != 0 || got.LogoH != 0 || got.Opacity != 0 {
+		t.Errorf("expected zero avatar, logo and opacity, got %+v", got)
+	}
+}
+
+func TestDrawAlmostEmptyOptions(t *testing.T) {
+	opts := Options{
+		CanvasW: 1200,
+		CanvasH: 630,
+		Title:   "The quick brown fox",
+		Author:  "@Tester",
+		AvaURL:  "avatar.png",
+		LogoURL: "logo.png",
+	}
+
+	img, err := New().Draw(context.Background(), opts)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the title is drawn below the default sized avatar row
+	titleTop := int(DefaultPadding*2) + DefaultAvaD
+	titleRect := image.Rect(int(DefaultPadding), titleTop, 600, titleTop+int(DefaultTitleSize))
+
+	// the white title stands out of the default background darkened by the default overlay
+	if r, g, b := maxRGB(img, titleRect); r < 250 || g < 250 || b < 250 {
+		t.Errorf("expected the white title, got %d,%d,%d", r, g, b)
+	}
+
+	if r := minR(img, titleRect); r > 128 {
+		t.Errorf("expected a dark background behind the title, got %d", r)
+	}
+
+	logoRect := image.Rect(1200-int(DefaultPadding)-349, 630-int(DefaultPadding)-DefaultLogoH, 1200-int(DefaultPadding), 630-int(DefaultPadding))
+
+	if !hasInk(img, logoRect) {
+		t.Error("expected the default sized logo")
+	}
+}
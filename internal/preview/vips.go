@@ -0,0 +1,62 @@
+package preview
+
+import (
+	"sync"
+
+	"github.com/davidbyttow/govips/v2/vips"
+)
+
+var (
+	startOnce    sync.Once
+	shutdownOnce sync.Once
+)
+
+// Config configures libvips for all the previews of the process.
+type Config struct {
+	// Number of threads each vips operation runs in, the govips default (1) if zero
+	VipsConcurrency int
+	// Max number of vips operations kept in the cache, the govips default (100) if zero, a negative value disables the cache
+	VipsCacheMax int
+}
+
+// Startup starts libvips with the Config. It's only needed for a custom Config and must be called before New,
+// as otherwise libvips is started with the default Config on the first use. The subsequent calls do nothing.
+// Call Shutdown once all the previews are drawn, libvips can't be started again after that.
+func Startup(config Config) {
+	startOnce.Do(func() {
+		// the negative values keep the govips defaults
+		vipsConfig := &vips.Config{
+			ConcurrencyLevel: -1,
+			MaxCacheFiles:    -1,
+			MaxCacheMem:      -1,
+			MaxCacheSize:     -1,
+		}
+
+		if config.VipsConcurrency > 0 {
+			vipsConfig.ConcurrencyLevel = config.VipsConcurrency
+		}
+
+		if config.VipsCacheMax > 0 {
+			vipsConfig.MaxCacheSize = config.VipsCacheMax
+		} else if config.VipsCacheMax < 0 {
+			vipsConfig.MaxCacheSize = 0
+		}
+
+		vips.Startup(vipsConfig)
+	})
+}
+
+// startVips starts libvips with the default Config unless it's already started.
+func startVips() {
+	Startup(Config{})
+}
+
+// Close drops the operations cached by libvips to free the memory, the previews can still be drawn after it.
+func Close() {
+	vips.ClearCache()
+}
+
+// Shutdown shuts libvips down for good, nothing can be drawn after it. The subsequent calls do nothing.
+func Shutdown() {
+	shutdownOnce.Do(vips.Shutdown)
+}
@@ -0,0 +1,27 @@
+package preview
+
+import (
+	"context"
+	"testing"
+)
+
+func TestClose(t *testing.T) {
+	p := New()
+	opts := testOptions()
+	opts.LogoURL = "logo.png"
+
+	if _, err := p.DrawJPEG(context.Background(), opts); err != nil {
+		t.Fatal(err)
+	}
+
+	Close()
+
+	// neither the Preview nor libvips is closed for good
+	if _, err := p.DrawJPEG(context.Background(), opts); err != nil {
+		t.Errorf("expected a preview drawn after Close, got %v", err)
+	}
+
+	if _, err := New().DrawJPEG(context.Background(), opts); err != nil {
+		t.Errorf("expected a preview of a new Preview drawn after Close, got %v", err)
+	}
+}
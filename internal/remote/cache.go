@@ -0,0 +1,142 @@
+package remote
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"time"
+)
+
+// Getter obtains resources by their URLs or filenames.
+type Getter interface {
+	GetAll(ctx context.Context, urlsOrPaths map[string]string) (map[string][]byte, error)
+}
+
+// Cached is a Getter keeping the most recently used resources in memory.
+type Cached struct {
+	inner      Getter
+	maxEntries int
+	ttl        time.Duration
+	now        func() time.Time
+
+	mu      sync.Mutex
+	lru     *list.List
+	entries map[string]*list.Element
+}
+
+type cacheEntry struct {
+	urlOrPath string
+	buf       []byte
+	expires   time.Time
+}
+
+// NewCached wraps the inner Getter with an LRU cache of up to maxEntries resources keyed by URL.
+// Entries expire after the TTL, a zero TTL means they are only evicted by newer ones.
+func NewCached(inner Getter, maxEntries int, ttl time.Duration) *Cached {
+	return &Cached{
+		inner:      inner,
+		maxEntries: maxEntries,
+		ttl:        ttl,
+		now:        time.Now,
+		lru:        list.New(),
+		entries:    make(map[string]*list.Element),
+	}
+}
+
+// GetAll returns the cached resources and fetches the rest using the inner Getter.
+func (c *Cached) GetAll(ctx context.Context, urlsOrPaths map[string]string) (map[string][]byte, error) {
+	bufs := make(map[string][]byte, len(urlsOrPaths))
+	missing := make(map[string]string)
+
+	maxBytes := maxBytesFromContext(ctx)
+
+	for key, urlOrPath := range urlsOrPaths {
+		// a resource cached under a greater limit is fetched again to fail on the limit of the context,
+		// and a resource fetched with headers may differ from the cached one
+		if buf, ok := c.get(urlOrPath); ok && (maxBytes <= 0 || int64(len(buf)) <= maxBytes) && headersFor(ctx, urlOrPath) == nil {
+			bufs[key] = buf
+		} else {
+			missing[key] = urlOrPath
+		}
+	}
+
+	if len(missing) == 0 {
+		return bufs, nil
+	}
+
+	fetched, err := c.inner.GetAll(ctx, missing)
+
+	if err != nil {
+		return nil, err
+	}
+
+	for key, buf := range fetched {
+		bufs[key] = buf
+
+		// the resources behind auth are not served to the callers without it
+		if headersFor(ctx, missing[key]) == nil {
+			c.add(missing[key], buf)
+		}
+	}
+
+	return bufs, nil
+}
+
+// Len returns the number of cached resources including the expired ones not evicted yet.
+func (c *Cached) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.lru.Len()
+}
+
+func (c *Cached) get(urlOrPath string) ([]byte, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	el, ok := c.entries[urlOrPath]
+
+	if !ok {
+		return nil, false
+	}
+
+	entry := el.Value.(*cacheEntry)
+
+	if c.ttl > 0 && !c.now().Before(entry.expires) {
+		c.lru.Remove(el)
+		delete(c.entries, urlOrPath)
+
+		return nil, false
+	}
+
+	c.lru.MoveToFront(el)
+
+	return entry.buf, true
+}
+
+func (c *Cached) add(urlOrPath string, buf []byte) {
+	if c.maxEntries <= 0 {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry := &cacheEntry{urlOrPath: urlOrPath, buf: buf, expires: c.now().Add(c.ttl)}
+
+	if el, ok := c.entries[urlOrPath]; ok {
+		el.Value = entry
+		c.lru.MoveToFront(el)
+
+		return
+	}
+
+	c.entries[urlOrPath] = c.lru.PushFront(entry)
+
+	for c.lru.Len() > c.maxEntries {
+		oldest := c.lru.Back()
+
+		c.lru.Remove(oldest)
+		delete(c.entries, oldest.Value.(*cacheEntry).urlOrPath)
+	}
+}
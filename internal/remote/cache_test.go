@@ -0,0 +1,101 @@
+package remote
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+// countingGetter returns the URL as the resource and counts fetches per URL.
+type countingGetter struct {
+	mu      sync.Mutex
+	fetches map[string]int
+}
+
+func (g *countingGetter) GetAll(_ context.Context, urlsOrPaths map[string]string) (map[string][]byte, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	bufs := make(map[string][]byte, len(urlsOrPaths))
+
+	for key, urlOrPath := range urlsOrPaths {
+		g.fetches[urlOrPath]++
+		bufs[key] = []byte(urlOrPath)
+	}
+
+	return bufs, nil
+}
+
+func TestCached(t *testing.T) {
+	inner := &countingGetter{fetches: map[string]int{}}
+	c := NewCached(inner, 2, time.Minute)
+	now := time.Now()
+	c.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	get := func(urlOrPath string) {
+		t.Helper()
+
+		bufs, err := c.GetAll(ctx, map[string]string{"key": urlOrPath})
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if string(bufs["key"]) != urlOrPath {
+			t.Fatalf("expected %q, got %q", urlOrPath, bufs["key"])
+		}
+	}
+
+	get("a")
+	get("a")
+
+	if inner.fetches["a"] != 1 {
+		t.Errorf("expected the second fetch to hit the cache, got %d fetches", inner.fetches["a"])
+	}
+
+	// "b" and "c" evict the least recently used "a"
+	get("b")
+	get("c")
+	get("a")
+
+	if inner.fetches["a"] != 2 {
+		t.Errorf("expected an evicted entry to be re-fetched, got %d fetches", inner.fetches["a"])
+	}
+
+	if c.Len() != 2 {
+		t.Errorf("expected 2 entries, got %d", c.Len())
+	}
+
+	now = now.Add(time.Minute)
+	get("a")
+
+	if inner.fetches["a"] != 3 {
+		t.Errorf("expected an expired entry to be re-fetched, got %d fetches", inner.fetches["a"])
+	}
+}
+
+func TestCachedConcurrent(t *testing.T) {
+	inner := &countingGetter{fetches: map[string]int{}}
+	c := NewCached(inner, 4, time.Minute)
+	var wg sync.WaitGroup
+
+	for i := 0; i < 32; i++ {
+		wg.Add(1)
+
+		go func(i int) {
+			defer wg.Done()
+
+			if _, err := c.GetAll(context.Background(), map[string]string{"ava": "a", "logo": string(rune('a' + i%8))}); err != nil {
+				t.Error(err)
+			}
+		}(i)
+	}
+
+	wg.Wait()
+
+	if c.Len() > 4 {
+		t.Errorf("expected at most 4 entries, got %d", c.Len())
+	}
+}
@@ -0,0 +1,83 @@
+package remote
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+const dataScheme = "data:"
+
+// dataMediaTypes lists the media types accepted in data URLs.
+var dataMediaTypes = map[string]bool{
+	"image/png":     true,
+	"image/jpeg":    true,
+	"image/gif":     true,
+	"image/webp":    true,
+	"image/heic":    true,
+	"image/heif":    true,
+	"image/avif":    true,
+	"image/svg+xml": true,
+}
+
+// isDataURL reports whether the resource is embedded into a data: URL.
+func isDataURL(urlOrPath string) bool {
+	return len(urlOrPath) >= len(dataScheme) && strings.EqualFold(urlOrPath[:len(dataScheme)], dataScheme)
+}
+
+// decodeDataURL returns the payload of a data URL like data:image/png;base64,iVBORw0...
+// Only image media types are accepted, the payload is either base64 or percent-encoded.
+func decodeDataURL(dataURL string) ([]byte, error) {
+	header, payload, found := cut(dataURL[len(dataScheme):], ",")
+
+	if !found {
+		return nil, fmt.Errorf("malformed data URL: no comma separating the payload")
+	}
+
+	params := strings.Split(header, ";")
+	mediaType := strings.ToLower(strings.TrimSpace(params[0]))
+	isBase64 := false
+
+	for _, param := range params[1:] {
+		if strings.EqualFold(strings.TrimSpace(param), "base64") {
+			isBase64 = true
+		}
+	}
+
+	if !dataMediaTypes[mediaType] {
+		return nil, fmt.Errorf("unsupported data URL media type: %q", mediaType)
+	}
+
+	if !isBase64 {
+		buf, err := url.PathUnescape(payload)
+
+		if err != nil {
+			return nil, fmt.Errorf("could not unescape a data URL payload: %w", err)
+		}
+
+		return []byte(buf), nil
+	}
+
+	// tolerate both padded and unpadded payloads
+	buf, err := base64.StdEncoding.DecodeString(payload)
+
+	if err != nil {
+		buf, err = base64.RawStdEncoding.DecodeString(payload)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("could not decode a base64 data URL payload: %w", err)
+	}
+
+	return buf, nil
+}
+
+// cut slices s around the first instance of sep like strings.Cut.
+func cut(s, sep string) (before, after string, found bool) {
+	if i := strings.Index(s, sep); i >= 0 {
+		return s[:i], s[i+len(sep):], true
+	}
+
+	return s, "", false
+}
@@ -0,0 +1,80 @@
+package remote
+
+import (
+	"bytes"
+	"context"
+	"testing"
+)
+
+func TestGetDataURL(t *testing.T) {
+	testCases := []struct {
+		name    string
+		dataURL string
+		want    []byte
+		wantErr bool
+	}{{
+		name:    "base64",
+		dataURL: "data:image/png;base64,iVBORw0KGgo=",
+		want:    []byte("\x89PNG\r\n\x1a\n"),
+	}, {
+		name:    "unpadded base64",
+		dataURL: "DATA:image/png;base64,iVBORw0KGgo",
+		want:    []byte("\x89PNG\r\n\x1a\n"),
+	}, {
+		name:    "percent-encoded",
+		dataURL: "data:image/svg+xml,%3Csvg%3E%3C%2Fsvg%3E",
+		want:    []byte("<svg></svg>"),
+	}, {
+		name:    "HEIC",
+		dataURL: "data:image/heic;base64,AAAAGGZ0eXBoZWlj",
+		want:    []byte("\x00\x00\x00\x18ftypheic"),
+	}, {
+		name:    "HEIF",
+		dataURL: "data:image/heif;base64,AAAAGGZ0eXBoZWlj",
+		want:    []byte("\x00\x00\x00\x18ftypheic"),
+	}, {
+		name:    "AVIF",
+		dataURL: "data:image/avif;base64,AAAAHGZ0eXBhdmlm",
+		want:    []byte("\x00\x00\x00\x1cftypavif"),
+	}, {
+		name:    "invalid base64",
+		dataURL: "data:image/png;base64,!!!",
+		wantErr: true,
+	}, {
+		name:    "unsupported media type",
+		dataURL: "data:text/html;base64,PGgxPg==",
+		wantErr: true,
+	}, {
+		name:    "no media type",
+		dataURL: "data:,hello",
+		wantErr: true,
+	}, {
+		name:    "no payload",
+		dataURL: "data:image/png;base64",
+		wantErr: true,
+	}}
+
+	r := New()
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			buf, err := r.Get(context.Background(), tt.dataURL)
+
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("expected an error")
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !bytes.Equal(buf, tt.want) {
+				t.Errorf("expected %q, got %q", tt.want, buf)
+			}
+		})
+	}
+}
@@ -0,0 +1,178 @@
+package remote
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// ErrForbiddenHost is returned for the URLs the Remote is not allowed to fetch.
+var ErrForbiddenHost = errors.New("forbidden host")
+
+// ErrTooManyRedirects is returned when a fetch is redirected more times than allowed (see WithMaxRedirects).
+var ErrTooManyRedirects = errors.New("too many redirects")
+
+// DefaultMaxRedirects is the number of redirects followed unless set with WithMaxRedirects.
+const DefaultMaxRedirects = 10
+
+// privateNetworks are the loopback, private (RFC 1918), carrier-grade NAT, link-local (including the cloud
+// metadata address 169.254.169.254), IETF protocol assignments, benchmarking, unique-local, unspecified
+// and NAT64 address ranges. The NAT64 ones embed IPv4 addresses the IPv4 ranges can't be checked against.
+// The IPv4-mapped IPv6 addresses (::ffff:0:0/96) are checked against the IPv4 ranges, as net.IP holds them
+// the same way as the IPv4 ones.
+var privateNetworks = parseCIDRs(
+	"0.0.0.0/8", "10.0.0.0/8", "100.64.0.0/10", "127.0.0.0/8", "169.254.0.0/16", "172.16.0.0/12", "192.0.0.0/24",
+	"192.168.0.0/16", "198.18.0.0/15",
+	"::/128", "::1/128", "64:ff9b::/96", "64:ff9b:1::/48", "fc00::/7", "fe80::/10",
+)
+
+// Option configures a Remote.
+type Option func(*Remote)
+
+// WithAllowedHosts restricts the fetched URLs to the hosts and their subdomains.
+// The allowed hosts are connected to even if they resolve to private addresses.
+func WithAllowedHosts(hosts ...string) Option {
+	return func(r *Remote) {
+		for _, host := range hosts {
+			r.allowedHosts = append(r.allowedHosts, strings.ToLower(strings.TrimSuffix(host, ".")))
+		}
+	}
+}
+
+// WithPrivateNetworksDenied makes the Remote refuse to connect to the loopback, private, link-local,
+// unique-local and the other special-purpose addresses (see privateNetworks), so the URLs can't reach
+// the internal services and the cloud metadata.
+func WithPrivateNetworksDenied() Option {
+	return func(r *Remote) {
+		r.denyPrivate = true
+	}
+}
+
+// WithMaxRedirects sets the number of redirects a fetch follows, DefaultMaxRedirects by default.
+// Zero makes the redirects fail.
+func WithMaxRedirects(n int) Option {
+	return func(r *Remote) {
+		r.maxRedirects = n
+	}
+}
+
+// checkRedirect stops the redirects over the limit and checks every hop like the requested URL,
+// so a redirect can't lead to a host the Remote is not allowed to fetch.
+func (r *Remote) checkRedirect(req *http.Request, via []*http.Request) error {
+	if len(via) > r.maxRedirects {
+		return fmt.Errorf("stopped after %d redirects: %w", r.maxRedirects, ErrTooManyRedirects)
+	}
+
+	host := req.URL.Hostname()
+
+	if err := r.checkHost(host); err != nil {
+		return err
+	}
+
+	// the hosts by name are checked once resolved by dialContext
+	if ip := net.ParseIP(host); r.denyPrivate && ip != nil && isPrivateIP(ip) && !r.isAllowedHost(host) {
+		return fmt.Errorf("redirected to the private address %s: %w", ip, ErrForbiddenHost)
+	}
+
+	return nil
+}
+
+// checkHost returns ErrForbiddenHost if there is an allowlist and the host is not on it.
+func (r *Remote) checkHost(host string) error {
+	if len(r.allowedHosts) == 0 || r.isAllowedHost(host) {
+		return nil
+	}
+
+	return fmt.Errorf("%s is not allowed: %w", host, ErrForbiddenHost)
+}
+
+func (r *Remote) isAllowedHost(host string) bool {
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+
+	for _, allowed := range r.allowedHosts {
+		if host == allowed || strings.HasSuffix(host, "."+allowed) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// dialContext resolves the host and connects to its address unless it's a private one.
+// The resolved address is dialed directly, so the host can't be re-resolved to another one in between.
+func (r *Remote) dialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	dialer := &net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second}
+	host, port, err := net.SplitHostPort(addr)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if r.isAllowedHost(host) {
+		return dialer.DialContext(ctx, network, addr)
+	}
+
+	ips, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+
+	if err != nil {
+		return nil, err
+	}
+
+	for _, ip := range ips {
+		if isPrivateIP(ip.IP) {
+			return nil, fmt.Errorf("%s resolves to the private address %s: %w", host, ip.IP, ErrForbiddenHost)
+		}
+	}
+
+	if len(ips) == 0 {
+		return nil, fmt.Errorf("no addresses found for %s", host)
+	}
+
+	return dialer.DialContext(ctx, network, net.JoinHostPort(ips[0].IP.String(), port))
+}
+
+// newTransport returns the default transport connecting through dialContext.
+func (r *Remote) newTransport() *http.Transport {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DialContext = r.dialContext
+	// a proxy would connect to the private addresses on behalf of the Remote
+	transport.Proxy = nil
+
+	return transport
+}
+
+// isPrivateIP reports whether the address is in the private networks or is not a global unicast one at all,
+// e.g. a multicast or the broadcast address.
+func isPrivateIP(ip net.IP) bool {
+	if !ip.IsGlobalUnicast() {
+		return true
+	}
+
+	for _, network := range privateNetworks {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func parseCIDRs(cidrs ...string) []*net.IPNet {
+	networks := make([]*net.IPNet, 0, len(cidrs))
+
+	for _, cidr := range cidrs {
+		_, network, err := net.ParseCIDR(cidr)
+
+		if err != nil {
+			panic(err)
+		}
+
+		networks = append(networks, network)
+	}
+
+	return networks
+}
@@ -0,0 +1,181 @@
+package remote
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestGetForbiddenHosts(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("image"))
+	}))
+
+	defer ts.Close()
+
+	testCases := []struct {
+		name      string
+		options   []Option
+		url       string
+		forbidden bool
+	}{{
+		name: "no protection by default",
+		url:  ts.URL,
+	}, {
+		name:      "cloud metadata",
+		options:   []Option{WithPrivateNetworksDenied()},
+		url:       "http://169.254.169.254/latest/meta-data/",
+		forbidden: true,
+	}, {
+		name:      "loopback",
+		options:   []Option{WithPrivateNetworksDenied()},
+		url:       ts.URL,
+		forbidden: true,
+	}, {
+		name:      "unique-local",
+		options:   []Option{WithPrivateNetworksDenied()},
+		url:       "http://[fd00::1]/logo.png",
+		forbidden: true,
+	}, {
+		name:    "explicitly allowed loopback",
+		options: []Option{WithPrivateNetworksDenied(), WithAllowedHosts("127.0.0.1")},
+		url:     ts.URL,
+	}, {
+		name:      "not on the allowlist",
+		options:   []Option{WithAllowedHosts("example.com")},
+		url:       "https://example.org/logo.png",
+		forbidden: true,
+	}, {
+		name:      "lookalike of an allowed host",
+		options:   []Option{WithAllowedHosts("example.com")},
+		url:       "https://badexample.com/logo.png",
+		forbidden: true,
+	}}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			buf, err := New(tt.options...).Get(WithRetries(context.Background(), 3), tt.url)
+
+			if tt.forbidden {
+				if !errors.Is(err, ErrForbiddenHost) {
+					t.Errorf("expected a forbidden host error, got %v", err)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if string(buf) != "image" {
+				t.Errorf("unexpected body: %q", buf)
+			}
+		})
+	}
+}
+
+func TestIsPrivateIP(t *testing.T) {
+	for addr, want := range map[string]bool{
+		"8.8.8.8":                false,
+		"2001:4860:4860::8888":   false,
+		"127.0.0.1":              true,
+		"169.254.169.254":        true,
+		"100.64.0.1":             true,
+		"192.0.0.170":            true,
+		"198.18.0.1":             true,
+		"198.19.255.255":         true,
+		"224.0.0.1":              true,
+		"255.255.255.255":        true,
+		"::ffff:127.0.0.1":       true,
+		"::ffff:169.254.169.254": true,
+		"::ffff:8.8.8.8":         false,
+		"64:ff9b::a9fe:a9fe":     true,
+		"64:ff9b:1::a00:1":       true,
+		"ff02::1":                true,
+		"fd00::1":                true,
+	} {
+		if got := isPrivateIP(net.ParseIP(addr)); got != want {
+			t.Errorf("%s: expected %v, got %v", addr, want, got)
+		}
+	}
+}
+
+func TestIsAllowedHost(t *testing.T) {
+	r := New(WithAllowedHosts("Example.com."))
+
+	for host, want := range map[string]bool{
+		"example.com":      true,
+		"cdn.EXAMPLE.com":  true,
+		"example.com.":     true,
+		"badexample.com":   false,
+		"example.com.evil": false,
+	} {
+		if got := r.isAllowedHost(host); got != want {
+			t.Errorf("%s: expected %v, got %v", host, want, got)
+		}
+	}
+}
+
+func TestGetForbiddenRedirect(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "http://169.254.169.254/latest/meta-data/", http.StatusFound)
+	}))
+
+	defer ts.Close()
+
+	_, err := New(WithAllowedHosts("127.0.0.1")).Get(context.Background(), ts.URL)
+
+	if !errors.Is(err, ErrForbiddenHost) {
+		t.Errorf("expected a forbidden host error, got %v", err)
+	}
+}
+
+func TestGetRedirectLimit(t *testing.T) {
+	// /n redirects n times before responding with the image
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n, _ := strconv.Atoi(strings.TrimPrefix(r.URL.Path, "/"))
+
+		if n > 0 {
+			http.Redirect(w, r, "/"+strconv.Itoa(n-1), http.StatusFound)
+			return
+		}
+
+		w.Write([]byte("image"))
+	}))
+
+	defer ts.Close()
+
+	r := New(WithMaxRedirects(3))
+
+	if buf, err := r.Get(context.Background(), ts.URL+"/3"); err != nil || string(buf) != "image" {
+		t.Errorf("expected the redirects within the limit followed, got %q, %v", buf, err)
+	}
+
+	if _, err := r.Get(WithRetries(context.Background(), 3), ts.URL+"/4"); !errors.Is(err, ErrTooManyRedirects) {
+		t.Errorf("expected a too many redirects error, got %v", err)
+	}
+}
+
+func TestGetRedirectToPrivateNetwork(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "http://10.0.0.1/admin", http.StatusFound)
+	}))
+
+	defer ts.Close()
+
+	r := New(WithPrivateNetworksDenied())
+	// the loopback test server is dialed by the default transport, so only the redirect check stops the hop
+	r.httpClient.Transport = http.DefaultTransport
+
+	_, err := r.Get(context.Background(), ts.URL)
+
+	if !errors.Is(err, ErrForbiddenHost) {
+		t.Errorf("expected a forbidden host error, got %v", err)
+	}
+}
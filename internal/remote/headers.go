@@ -0,0 +1,62 @@
+package remote
+
+import (
+	"context"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+type headersKey struct{}
+
+// WithHeaders returns a context making Get send the headers (e.g. Authorization) with the requests
+// to the URLs starting with the prefix they are keyed by, e.g. "https://cdn.example.com/private/".
+// The headers of a longer prefix override the ones of a shorter prefix. The resources fetched with headers
+// are neither cached nor shared with the other callers, and the headers are never logged.
+func WithHeaders(ctx context.Context, headers map[string]http.Header) context.Context {
+	return context.WithValue(ctx, headersKey{}, headers)
+}
+
+// headersFor returns the headers of the context for the URL, nil if there are none.
+func headersFor(ctx context.Context, rawURL string) http.Header {
+	headers, _ := ctx.Value(headersKey{}).(map[string]http.Header)
+	prefixes := make([]string, 0, len(headers))
+
+	for prefix := range headers {
+		if hasURLPrefix(rawURL, prefix) {
+			prefixes = append(prefixes, prefix)
+		}
+	}
+
+	if len(prefixes) == 0 {
+		return nil
+	}
+
+	sort.Slice(prefixes, func(i, j int) bool {
+		return len(prefixes[i]) < len(prefixes[j])
+	})
+
+	merged := make(http.Header)
+
+	for _, prefix := range prefixes {
+		for name, values := range headers[prefix] {
+			merged[http.CanonicalHeaderKey(name)] = values
+		}
+	}
+
+	return merged
+}
+
+// hasURLPrefix reports whether the URL starts with the prefix ending at a URL part boundary,
+// so "https://example.com" doesn't match "https://example.com.evil.net".
+func hasURLPrefix(rawURL, prefix string) bool {
+	if prefix == "" || !strings.HasPrefix(rawURL, prefix) {
+		return false
+	}
+
+	if strings.HasSuffix(prefix, "/") || len(rawURL) == len(prefix) {
+		return true
+	}
+
+	return strings.ContainsRune("/?#", rune(rawURL[len(prefix)]))
+}
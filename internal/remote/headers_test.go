@@ -0,0 +1,75 @@
+package remote
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestGetWithHeaders(t *testing.T) {
+	const token = "Bearer s3cr3t"
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != token {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		w.Write([]byte("image"))
+	}))
+	defer srv.Close()
+
+	var logs bytes.Buffer
+
+	log.SetOutput(&logs)
+	defer log.SetOutput(os.Stderr)
+
+	r := New()
+	url := srv.URL + "/private/avatar.png"
+
+	var statusErr *StatusError
+
+	if _, err := r.Get(context.Background(), url); !errors.As(err, &statusErr) || statusErr.StatusCode != http.StatusUnauthorized {
+		t.Errorf("expected 401 without the header, got %v", err)
+	}
+
+	// the prefix must end at a URL part boundary
+	ctx := WithHeaders(context.Background(), map[string]http.Header{srv.URL + "/priv": {"Authorization": {token}}})
+
+	if _, err := r.Get(ctx, url); err == nil {
+		t.Error("expected the header not to be sent for a partial path match")
+	}
+
+	ctx = WithHeaders(context.Background(), map[string]http.Header{srv.URL + "/private/": {"Authorization": {token}}})
+	buf, err := r.Get(ctx, url)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(buf) != "image" {
+		t.Errorf("unexpected body: %q", buf)
+	}
+
+	if strings.Contains(logs.String(), "s3cr3t") {
+		t.Errorf("expected the header not to be logged, got %q", logs.String())
+	}
+
+	// the resource fetched with the header is not served from the cache without it
+	cached := NewCached(r, 10, time.Minute)
+
+	if _, err := cached.GetAll(ctx, map[string]string{"ava": url}); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := cached.GetAll(context.Background(), map[string]string{"ava": url}); err == nil {
+		t.Error("expected the cache to skip the resource fetched with the header")
+	}
+}
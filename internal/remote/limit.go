@@ -0,0 +1,53 @@
+package remote
+
+import (
+	"context"
+	"fmt"
+)
+
+// DefaultMaxBytes is the size limit of a fetched resource unless set with WithDefaultMaxBytes or WithMaxBytes.
+const DefaultMaxBytes = 10 * 1024 * 1024
+
+type maxBytesKey struct{}
+
+// TooLargeError is returned when a resource exceeds the size limit.
+type TooLargeError struct {
+	URL   string
+	Limit int64
+}
+
+func (e *TooLargeError) Error() string {
+	return fmt.Sprintf("could not get a resource by the url: %s: larger than %d bytes", e.URL, e.Limit)
+}
+
+// WithMaxBytes returns a context making Get fail on the resources larger than n bytes
+// instead of the limit of the Remote.
+func WithMaxBytes(ctx context.Context, n int64) context.Context {
+	return context.WithValue(ctx, maxBytesKey{}, n)
+}
+
+// WithDefaultMaxBytes sets the size limit of the resources fetched by the Remote, DefaultMaxBytes by default.
+func WithDefaultMaxBytes(n int64) Option {
+	return func(r *Remote) {
+		r.maxBytes = n
+	}
+}
+
+func maxBytesFromContext(ctx context.Context) int64 {
+	n, _ := ctx.Value(maxBytesKey{}).(int64)
+
+	return n
+}
+
+// limit returns the size limit of a resource fetched with the context.
+func (r *Remote) limit(ctx context.Context) int64 {
+	if n := maxBytesFromContext(ctx); n > 0 {
+		return n
+	}
+
+	if r.maxBytes > 0 {
+		return r.maxBytes
+	}
+
+	return DefaultMaxBytes
+}
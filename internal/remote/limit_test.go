@@ -0,0 +1,118 @@
+package remote
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+// endlessReader streams zeros forever.
+type endlessReader struct{}
+
+func (endlessReader) Read(p []byte) (int, error) {
+	for i := range p {
+		p[i] = 0
+	}
+
+	return len(p), nil
+}
+
+// sizedTransport responds with the body of the size, an endless one if negative, declaring the length.
+type sizedTransport struct {
+	size          int
+	contentLength int64
+}
+
+func (t sizedTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	body := ioutil.NopCloser(endlessReader{})
+
+	if t.size >= 0 {
+		body = ioutil.NopCloser(strings.NewReader(strings.Repeat("x", t.size)))
+	}
+
+	return &http.Response{StatusCode: http.StatusOK, Body: body, ContentLength: t.contentLength, Request: req}, nil
+}
+
+func TestGetMaxBytes(t *testing.T) {
+	testCases := []struct {
+		name      string
+		transport sizedTransport
+		remoteMax int64
+		ctxMax    int64
+		wantErr   bool
+	}{{
+		name:      "endless body",
+		transport: sizedTransport{size: -1, contentLength: -1},
+		ctxMax:    1024,
+		wantErr:   true,
+	}, {
+		name:      "endless body under the default limit",
+		transport: sizedTransport{size: -1, contentLength: -1},
+		wantErr:   true,
+	}, {
+		name:      "declared length over the limit",
+		transport: sizedTransport{size: 10, contentLength: 2048},
+		ctxMax:    1024,
+		wantErr:   true,
+	}, {
+		name:      "exactly the limit",
+		transport: sizedTransport{size: 1024, contentLength: -1},
+		ctxMax:    1024,
+	}, {
+		name:      "remote limit",
+		transport: sizedTransport{size: 1025, contentLength: -1},
+		remoteMax: 1024,
+		wantErr:   true,
+	}, {
+		name:      "context limit overrides the remote one",
+		transport: sizedTransport{size: 1025, contentLength: -1},
+		remoteMax: 1024,
+		ctxMax:    2048,
+	}}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			r := New(WithDefaultMaxBytes(tt.remoteMax))
+			r.httpClient.Transport = tt.transport
+			ctx := WithRetries(context.Background(), 3)
+
+			if tt.ctxMax > 0 {
+				ctx = WithMaxBytes(ctx, tt.ctxMax)
+			}
+
+			buf, err := r.Get(ctx, "https://example.com/bg.jpg")
+
+			if !tt.wantErr {
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				if len(buf) != tt.transport.size {
+					t.Errorf("expected %d bytes, got %d", tt.transport.size, len(buf))
+				}
+
+				return
+			}
+
+			var tooLargeErr *TooLargeError
+
+			if !errors.As(err, &tooLargeErr) {
+				t.Fatalf("expected a size error, got %v", err)
+			}
+		})
+	}
+}
+
+func TestGetMaxBytesDataURL(t *testing.T) {
+	ctx := WithMaxBytes(context.Background(), 4)
+	_, err := New().Get(ctx, "data:image/png,12345")
+
+	var tooLargeErr *TooLargeError
+
+	if !errors.As(err, &tooLargeErr) {
+		t.Errorf("expected a size error, got %v", err)
+	}
+}
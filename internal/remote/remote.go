@@ -12,29 +12,56 @@ import (
 	"path/filepath"
 	"strings"
 	"sync"
+	"time"
 )
 
-const bodyLimit = 10 * 1024 * 1024
-
 //go:embed images/*
 var images embed.FS
 
 // Remote can obtain remote resources to use in the preview.
 type Remote struct {
-	httpClient *http.Client
+	httpClient   *http.Client
+	allowedHosts []string
+	denyPrivate  bool
+	maxBytes     int64
+	maxRedirects int
 }
 
 // New returns an initialized Remote.
-func New() *Remote {
-	return &Remote{
-		httpClient: &http.Client{
-			Transport: http.DefaultTransport,
-		},
+func New(options ...Option) *Remote {
+	r := &Remote{maxRedirects: DefaultMaxRedirects}
+
+	for _, option := range options {
+		option(r)
+	}
+
+	r.httpClient = &http.Client{
+		Transport:     http.DefaultTransport,
+		CheckRedirect: r.checkRedirect,
+	}
+
+	if r.denyPrivate {
+		r.httpClient.Transport = r.newTransport()
 	}
+
+	return r
 }
 
 // Get fetches a remote resource using an URL or try to read it from the disk when a filename is specified.
+// Resources embedded into data: URLs are decoded in place.
+// Transient HTTP failures are retried if requested with WithRetries, the headers set with WithHeaders are sent.
+// The resources larger than the limit (see WithMaxBytes) fail with a *TooLargeError.
 func (r *Remote) Get(ctx context.Context, urlOrPath string) (buf []byte, err error) {
+	if isDataURL(urlOrPath) {
+		log.Printf("getting a resource from a data URL\n")
+
+		if buf, err = decodeDataURL(urlOrPath); err == nil && int64(len(buf)) > r.limit(ctx) {
+			return nil, &TooLargeError{URL: "data:", Limit: r.limit(ctx)}
+		}
+
+		return buf, err
+	}
+
 	log.Printf("getting a resource: %s\n", urlOrPath)
 
 	_, parseErr := url.ParseRequestURI(urlOrPath)
@@ -52,35 +79,87 @@ func (r *Remote) Get(ctx context.Context, urlOrPath string) (buf []byte, err err
 		return
 	}
 
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, urlOrPath, nil)
+	for attempt := 0; ; attempt++ {
+		buf, err = r.fetch(ctx, urlOrPath)
+
+		if err == nil || attempt >= retriesFromContext(ctx) || !isTransient(err) || ctx.Err() != nil {
+			return
+		}
+
+		backoff := retryBackoff << attempt
+
+		log.Printf("retrying in %s: %s\n", backoff, err)
+
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+			return nil, fmt.Errorf("could not get a resource by the url: %s: %w", urlOrPath, ctx.Err())
+		}
+	}
+}
+
+// fetch makes a single attempt to get a resource by the URL.
+func (r *Remote) fetch(ctx context.Context, rawURL string) ([]byte, error) {
+	u, err := url.Parse(rawURL)
+
+	if err != nil {
+		return nil, fmt.Errorf("could not get a resource by the url: %s: %w", rawURL, err)
+	}
+
+	if err := r.checkHost(u.Hostname()); err != nil {
+		return nil, fmt.Errorf("could not get a resource by the url: %s: %w", rawURL, err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
 
 	if err != nil {
-		return nil, fmt.Errorf("could not get a resource by the url: %s: %w", urlOrPath, err)
+		return nil, fmt.Errorf("could not get a resource by the url: %s: %w", rawURL, err)
+	}
+
+	for name, values := range headersFor(ctx, rawURL) {
+		req.Header[name] = values
 	}
 
 	res, err := r.httpClient.Do(req)
 
 	if err != nil {
-		return nil, fmt.Errorf("could not get a resource by the url: %s: %w", urlOrPath, err)
+		return nil, fmt.Errorf("could not get a resource by the url: %s: %w", rawURL, err)
 	}
 
 	defer res.Body.Close()
 
-	buf, err = ioutil.ReadAll(io.LimitReader(res.Body, bodyLimit))
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return nil, &StatusError{URL: rawURL, StatusCode: res.StatusCode}
+	}
+
+	limit := r.limit(ctx)
+
+	// the declared length fails fast, the read is limited anyway as the length may be missing or false
+	if res.ContentLength > limit {
+		return nil, &TooLargeError{URL: rawURL, Limit: limit}
+	}
+
+	buf, err := ioutil.ReadAll(io.LimitReader(res.Body, limit+1))
 
 	if err != nil {
-		return nil, fmt.Errorf("could not read a resource body: %s: %w", urlOrPath, err)
+		return nil, fmt.Errorf("could not read a resource body: %s: %w", rawURL, err)
 	}
 
-	return
+	if int64(len(buf)) > limit {
+		return nil, &TooLargeError{URL: rawURL, Limit: limit}
+	}
+
+	return buf, nil
 }
 
 // GetAll fetches remote resources concurrently using Get
 func (r *Remote) GetAll(ctx context.Context, urlsOrPaths map[string]string) (map[string][]byte, error) {
 	bufs := make(map[string][]byte, len(urlsOrPaths))
-	errCh := make(chan error)
+	// buffered so that failing fetches don't block after the first error is returned
+	errCh := make(chan error, len(urlsOrPaths))
 	doneCh := make(chan bool)
 	var wg sync.WaitGroup
+	var mu sync.Mutex
 
 	wg.Add(len(urlsOrPaths))
 
@@ -92,7 +171,9 @@ func (r *Remote) GetAll(ctx context.Context, urlsOrPaths map[string]string) (map
 				errCh <- err
 			}
 
+			mu.Lock()
 			bufs[key] = buf
+			mu.Unlock()
 
 			wg.Done()
 		}(key, urlOrPath)
@@ -105,9 +186,13 @@ func (r *Remote) GetAll(ctx context.Context, urlsOrPaths map[string]string) (map
 
 	select {
 	case <-doneCh:
-		close(errCh)
-
-		return bufs, nil
+		// an error could be sent right before the last fetch is done
+		select {
+		case err := <-errCh:
+			return nil, err
+		default:
+			return bufs, nil
+		}
 	case err := <-errCh:
 		return nil, err
 	}
@@ -0,0 +1,63 @@
+package remote
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// maxRetries caps the number of retries requested via WithRetries.
+const maxRetries = 5
+
+// retryBackoff is the delay before the first retry, doubled for each subsequent one.
+var retryBackoff = 100 * time.Millisecond
+
+type retriesKey struct{}
+
+// StatusError is returned when a resource is responded with a non-2xx status.
+type StatusError struct {
+	URL        string
+	StatusCode int
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("could not get a resource by the url: %s: %d %s", e.URL, e.StatusCode, http.StatusText(e.StatusCode))
+}
+
+// WithRetries returns a context making Get retry transient failures (network errors and 5xx responses)
+// up to n times with an exponential backoff.
+func WithRetries(ctx context.Context, n int) context.Context {
+	if n > maxRetries {
+		n = maxRetries
+	}
+
+	return context.WithValue(ctx, retriesKey{}, n)
+}
+
+func retriesFromContext(ctx context.Context) int {
+	n, _ := ctx.Value(retriesKey{}).(int)
+
+	return n
+}
+
+// isTransient reports whether a failed fetch may succeed if retried.
+func isTransient(err error) bool {
+	var statusErr *StatusError
+	var tooLargeErr *TooLargeError
+
+	if errors.As(err, &statusErr) {
+		return statusErr.StatusCode >= 500
+	}
+
+	if errors.As(err, &tooLargeErr) {
+		return false
+	}
+
+	if errors.Is(err, ErrForbiddenHost) || errors.Is(err, ErrTooManyRedirects) {
+		return false
+	}
+
+	return !errors.Is(err, context.Canceled) && !errors.Is(err, context.DeadlineExceeded)
+}
@@ -0,0 +1,124 @@
+package remote
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+// flakyTransport responds with the statuses in turn, then with 200 OK.
+type flakyTransport struct {
+	statuses []int
+	calls    int
+}
+
+func (t *flakyTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	t.calls++
+	status := http.StatusOK
+
+	if t.calls <= len(t.statuses) {
+		status = t.statuses[t.calls-1]
+	}
+
+	if status == 0 {
+		return nil, errors.New("connection reset by peer")
+	}
+
+	return &http.Response{
+		StatusCode: status,
+		Body:       ioutil.NopCloser(strings.NewReader("image")),
+		Request:    req,
+	}, nil
+}
+
+func TestGetRetries(t *testing.T) {
+	retryBackoff = time.Millisecond
+
+	testCases := []struct {
+		name      string
+		statuses  []int
+		retries   int
+		wantCalls int
+		wantErr   bool
+	}{{
+		name:      "fails twice then succeeds",
+		statuses:  []int{http.StatusBadGateway, 0},
+		retries:   3,
+		wantCalls: 3,
+	}, {
+		name:      "not found is not retried",
+		statuses:  []int{http.StatusNotFound},
+		retries:   3,
+		wantCalls: 1,
+		wantErr:   true,
+	}, {
+		name:      "gives up",
+		statuses:  []int{http.StatusServiceUnavailable, http.StatusServiceUnavailable, http.StatusServiceUnavailable},
+		retries:   2,
+		wantCalls: 3,
+		wantErr:   true,
+	}, {
+		name:      "no retries by default",
+		statuses:  []int{http.StatusServiceUnavailable},
+		wantCalls: 1,
+		wantErr:   true,
+	}}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			transport := &flakyTransport{statuses: tt.statuses}
+			r := &Remote{httpClient: &http.Client{Transport: transport}}
+			ctx := context.Background()
+
+			if tt.retries > 0 {
+				ctx = WithRetries(ctx, tt.retries)
+			}
+
+			buf, err := r.Get(ctx, "https://example.com/logo.png")
+
+			if transport.calls != tt.wantCalls {
+				t.Errorf("expected %d calls, got %d", tt.wantCalls, transport.calls)
+			}
+
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("expected an error")
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if string(buf) != "image" {
+				t.Errorf("unexpected body: %q", buf)
+			}
+		})
+	}
+}
+
+func TestGetRetriesDeadline(t *testing.T) {
+	retryBackoff = time.Second
+
+	transport := &flakyTransport{statuses: []int{http.StatusInternalServerError, http.StatusInternalServerError}}
+	r := &Remote{httpClient: &http.Client{Transport: transport}}
+	ctx, cancel := context.WithTimeout(WithRetries(context.Background(), 3), 20*time.Millisecond)
+
+	defer cancel()
+
+	_, err := r.Get(ctx, "https://example.com/logo.png")
+
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected a deadline error, got %v", err)
+	}
+
+	if transport.calls != 1 {
+		t.Errorf("expected no retries past the deadline, got %d calls", transport.calls)
+	}
+}
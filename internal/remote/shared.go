@@ -0,0 +1,126 @@
+package remote
+
+import (
+	"context"
+	"sync"
+)
+
+// Shared is a Getter fetching every resource once for all its callers, including the concurrent ones.
+// It keeps all the fetched resources for its lifetime, so it's meant for a batch of related fetches.
+type Shared struct {
+	inner Getter
+
+	mu    sync.Mutex
+	calls map[string]*sharedCall
+}
+
+type sharedCall struct {
+	done chan struct{}
+	buf  []byte
+	err  error
+}
+
+// NewShared wraps the inner Getter sharing the resources by URL.
+func NewShared(inner Getter) *Shared {
+	return &Shared{
+		inner: inner,
+		calls: make(map[string]*sharedCall),
+	}
+}
+
+// GetAll returns the resources fetched by the earlier or concurrent callers and fetches the rest
+// using the inner Getter.
+func (s *Shared) GetAll(ctx context.Context, urlsOrPaths map[string]string) (map[string][]byte, error) {
+	bufs := make(map[string][]byte, len(urlsOrPaths))
+	// buffered so that all the failing fetches can report their errors
+	errCh := make(chan error, len(urlsOrPaths))
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+
+	wg.Add(len(urlsOrPaths))
+
+	for key, urlOrPath := range urlsOrPaths {
+		go func(key string, urlOrPath string) {
+			defer wg.Done()
+
+			buf, err := s.get(ctx, key, urlOrPath)
+
+			if err != nil {
+				errCh <- err
+				return
+			}
+
+			mu.Lock()
+			bufs[key] = buf
+			mu.Unlock()
+		}(key, urlOrPath)
+	}
+
+	wg.Wait()
+
+	select {
+	case err := <-errCh:
+		return nil, err
+	default:
+		return bufs, nil
+	}
+}
+
+// get returns the resource fetched by the first caller or fetches it if the caller is the first one.
+func (s *Shared) get(ctx context.Context, key string, urlOrPath string) ([]byte, error) {
+	// the resources behind auth are not shared with the callers without it
+	if headersFor(ctx, urlOrPath) != nil {
+		return s.fetch(ctx, key, urlOrPath)
+	}
+
+	s.mu.Lock()
+	call, exists := s.calls[urlOrPath]
+
+	if !exists {
+		call = &sharedCall{done: make(chan struct{})}
+		s.calls[urlOrPath] = call
+	}
+
+	s.mu.Unlock()
+
+	if !exists {
+		call.buf, call.err = s.fetch(ctx, key, urlOrPath)
+
+		// the next callers fetch a failed resource again
+		if call.err != nil {
+			s.mu.Lock()
+			delete(s.calls, urlOrPath)
+			s.mu.Unlock()
+		}
+
+		close(call.done)
+
+		return call.buf, call.err
+	}
+
+	select {
+	case <-call.done:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+
+	maxBytes := maxBytesFromContext(ctx)
+
+	// the fetch could fail on the context of the first caller, and a resource fetched under a greater limit
+	// is fetched again to fail on the limit of the context
+	if call.err != nil || (maxBytes > 0 && int64(len(call.buf)) > maxBytes) {
+		return s.fetch(ctx, key, urlOrPath)
+	}
+
+	return call.buf, nil
+}
+
+func (s *Shared) fetch(ctx context.Context, key string, urlOrPath string) ([]byte, error) {
+	bufs, err := s.inner.GetAll(ctx, map[string]string{key: urlOrPath})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return bufs[key], nil
+}
@@ -0,0 +1,81 @@
+package remote
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+)
+
+// flakyGetter fails the first fetch of every resource and returns the URL as the resource afterwards.
+type flakyGetter struct {
+	mu      sync.Mutex
+	fetches map[string]int
+}
+
+func (g *flakyGetter) GetAll(_ context.Context, urlsOrPaths map[string]string) (map[string][]byte, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	bufs := make(map[string][]byte, len(urlsOrPaths))
+
+	for key, urlOrPath := range urlsOrPaths {
+		if g.fetches[urlOrPath]++; g.fetches[urlOrPath] == 1 {
+			return nil, errors.New("connection reset")
+		}
+
+		bufs[key] = []byte(urlOrPath)
+	}
+
+	return bufs, nil
+}
+
+func TestShared(t *testing.T) {
+	inner := &countingGetter{fetches: map[string]int{}}
+	s := NewShared(inner)
+	var wg sync.WaitGroup
+
+	wg.Add(10)
+
+	for i := 0; i < 10; i++ {
+		go func() {
+			defer wg.Done()
+
+			bufs, err := s.GetAll(context.Background(), map[string]string{"bg": "a", "logo": "b"})
+
+			if err != nil {
+				t.Error(err)
+				return
+			}
+
+			if string(bufs["bg"]) != "a" || string(bufs["logo"]) != "b" {
+				t.Errorf("expected a and b, got %q and %q", bufs["bg"], bufs["logo"])
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	if inner.fetches["a"] != 1 || inner.fetches["b"] != 1 {
+		t.Errorf("expected a single fetch of each resource, got %v", inner.fetches)
+	}
+}
+
+func TestShared_Failed(t *testing.T) {
+	inner := &flakyGetter{fetches: map[string]int{}}
+	s := NewShared(inner)
+
+	if _, err := s.GetAll(context.Background(), map[string]string{"bg": "a"}); err == nil {
+		t.Fatal("expected the first fetch to fail")
+	}
+
+	bufs, err := s.GetAll(context.Background(), map[string]string{"bg": "a"})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(bufs["bg"]) != "a" || inner.fetches["a"] != 2 {
+		t.Errorf("expected a failed resource to be fetched again, got %q after %d fetches", bufs["bg"], inner.fetches["a"])
+	}
+}
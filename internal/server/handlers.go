@@ -4,21 +4,48 @@ import (
 	"bytes"
 	"context"
 	"errors"
+	"fmt"
 	"image"
 	"image/jpeg"
 	"net/http"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/nDmitry/ogimgd/internal/preview"
 )
 
-const timeout = 30 * time.Second
+const (
+	timeout = 30 * time.Second
+	// previews of the same parameters change only when the images they point to do,
+	// so they are cached for an hour to pick up the new images in a bounded time
+	cacheControl = "public, max-age=3600"
+)
+
+// knownParams are the query parameters the preview handler accepts.
+var knownParams = map[string]bool{
+	"title":  true,
+	"author": true,
+	"bg":     true,
+	"ava":    true,
+	"logo":   true,
+	"op":     true,
+	"w":      true,
+	"h":      true,
+	"q":      true,
+	sigParam: true,
+	expParam: true,
+}
 
 type drawer interface {
 	Draw(ctx context.Context, opts preview.Options) (image.Image, error)
 }
 
+// PreviewHandler returns the handler drawing the previews from the query parameters, Run serves it on /preview.
+func PreviewHandler(p *preview.Preview) http.Handler {
+	return getPreview(p)
+}
+
 func getPreview(d drawer) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		defer r.Body.Close()
@@ -32,6 +59,7 @@ func getPreview(d drawer) http.HandlerFunc {
 			CanvasH:    630,
 			Opacity:    0.6,
 			AvaD:       64,
+			AvaBorderW: 4,
 			LogoH:      48,
 			TitleSize:  76,
 			AuthorSize: 36,
@@ -39,6 +67,13 @@ func getPreview(d drawer) http.HandlerFunc {
 			Quality:    84,
 		}
 
+		for param := range r.URL.Query() {
+			if !knownParams[param] {
+				handleBadRequest(w, fmt.Errorf("Unknown parameter %s", param))
+				return
+			}
+		}
+
 		titleParam := r.URL.Query().Get("title")
 
 		if titleParam == "" {
@@ -67,7 +102,8 @@ func getPreview(d drawer) http.HandlerFunc {
 		if avaParam != "" {
 			opts.AvaURL = avaParam
 		} else {
-			opts.AuthorSize = 0
+			// the author is drawn next to the avatar only
+			opts.Author = ""
 		}
 
 		logoParam := r.URL.Query().Get("logo")
@@ -88,25 +124,110 @@ func getPreview(d drawer) http.HandlerFunc {
 				handleBadRequest(w, errors.New("Could not parse op parameter"))
 				return
 			}
+
+			// zero would fall back to the default opacity over a background image
+			if opts.Opacity == 0 {
+				opts.Opacity = preview.NoOverlay
+			}
+		}
+
+		intParams := []struct {
+			name  string
+			value *int
+		}{{"w", &opts.CanvasW}, {"h", &opts.CanvasH}, {"q", &opts.Quality}}
+
+		for _, param := range intParams {
+			valueParam := r.URL.Query().Get(param.name)
+
+			if valueParam == "" {
+				continue
+			}
+
+			var err error
+
+			if *param.value, err = strconv.Atoi(valueParam); err != nil {
+				handleBadRequest(w, fmt.Errorf("Could not parse %s parameter", param.name))
+				return
+			}
+		}
+
+		// zero stands for the default quality like for the formats encoded by preview.Encode
+		if opts.Quality == 0 {
+			opts.Quality = preview.DefaultQuality
+		}
+
+		// an invalid request is rejected before a cached preview could be answered with 304
+		if err := opts.Validate(); err != nil {
+			handleBadRequest(w, err)
+			return
+		}
+
+		format := negotiateFormat(r.Header.Get("Accept"))
+		// the same options encoded to the same format make the same preview as long as the images they point to
+		// stay the same, the ETag is weak as the images aren't fetched to answer If-None-Match
+		etag := fmt.Sprintf("W/%q", opts.CacheKey()+"-"+string(format))
+
+		if etagMatches(r.Header.Get("If-None-Match"), etag) {
+			setCacheHeaders(w, etag)
+			w.WriteHeader(http.StatusNotModified)
+			return
 		}
 
 		img, err := d.Draw(ctx, opts)
 
-		if err != nil {
+		switch {
+		// the parameters or the images they point to are at fault
+		case errors.Is(err, preview.ErrInvalidOptions), errors.Is(err, preview.ErrFetch), errors.Is(err, preview.ErrDecode):
+			handleBadRequest(w, err)
+			return
+		case err != nil:
 			panic(err)
 		}
 
 		buf := new(bytes.Buffer)
 
-		if err = jpeg.Encode(buf, img, &jpeg.Options{Quality: opts.Quality}); err != nil {
+		if format == preview.FormatJPEG {
+			err = jpeg.Encode(buf, img, &jpeg.Options{Quality: opts.Quality})
+		} else {
+			var encoded []byte
+
+			encoded, err = preview.Encode(img, format, opts.Quality)
+			buf = bytes.NewBuffer(encoded)
+		}
+
+		if err != nil {
 			panic(err)
 		}
 
-		w.Header().Set("Content-Type", "image/jpeg")
+		w.Header().Set("Content-Type", "image/"+string(format))
 		w.Header().Set("Content-Length", strconv.Itoa(len(buf.Bytes())))
+		setCacheHeaders(w, etag)
 
 		if _, err := w.Write(buf.Bytes()); err != nil {
 			panic(err)
 		}
 	}
 }
+
+// setCacheHeaders sets the headers letting the clients and the proxies cache a preview.
+func setCacheHeaders(w http.ResponseWriter, etag string) {
+	w.Header().Set("Cache-Control", cacheControl)
+	w.Header().Set("Vary", "Accept")
+	w.Header().Set("ETag", etag)
+}
+
+// etagMatches reports whether the If-None-Match header lists the ETag or is a wildcard,
+// the tags are compared weakly as If-None-Match requires.
+func etagMatches(header, etag string) bool {
+	etag = strings.TrimPrefix(etag, "W/")
+
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimPrefix(strings.TrimSpace(tag), "W/")
+
+		if tag == etag || tag == "*" {
+			return true
+		}
+	}
+
+	return false
+}
@@ -3,17 +3,35 @@ package server
 import (
 	"bytes"
 	"encoding/json"
+	"flag"
 	"fmt"
+	"image/jpeg"
 	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
 	"os"
+	"strconv"
+	"strings"
 	"testing"
 
+	"github.com/davidbyttow/govips/v2/vips"
 	"github.com/nDmitry/ogimgd/internal/preview"
 )
 
+// update rewrites the expected previews with the drawn ones, e.g. after a change of the layout
+var update = flag.Bool("update", false, "rewrite the expected previews in testdata/expected")
+
+func TestMain(m *testing.M) {
+	vips.LoggingSettings(nil, vips.LogLevelError)
+	preview.Startup(preview.Config{})
+
+	code := m.Run()
+
+	preview.Shutdown()
+	os.Exit(code)
+}
+
 func TestGetPreviewHandler_Success(t *testing.T) {
 	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		file, err := os.ReadFile("./testdata/bg.jpg")
@@ -71,19 +89,82 @@ func TestGetPreviewHandler_Success(t *testing.T) {
 
 			res := w.Result()
 			body, err := ioutil.ReadAll(res.Body)
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if *update {
+				if err := os.WriteFile(tt.expected, body, 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
 			expected, err := os.ReadFile(tt.expected)
 
 			if err != nil {
 				t.Error(err)
 			}
 
-			if bytes.Compare(body, expected) != 0 {
-				t.Error("images are not equal")
+			if err := compareImages(body, expected); err != nil {
+				t.Errorf("images are not equal: %s", err)
 			}
 		})
 	}
 }
 
+// compareImages decodes the JPEGs and compares them pixel by pixel. The resized avatars and logos differ slightly
+// between the libvips builds, so a few pixels may differ by a few levels, any change of the layout differs more.
+func compareImages(got, want []byte) error {
+	gotImg, err := jpeg.Decode(bytes.NewReader(got))
+
+	if err != nil {
+		return err
+	}
+
+	wantImg, err := jpeg.Decode(bytes.NewReader(want))
+
+	if err != nil {
+		return err
+	}
+
+	if gotImg.Bounds() != wantImg.Bounds() {
+		return fmt.Errorf("got the size %v, want %v", gotImg.Bounds(), wantImg.Bounds())
+	}
+
+	bounds := gotImg.Bounds()
+	differ := 0
+
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			r1, g1, b1, _ := gotImg.At(x, y).RGBA()
+			r2, g2, b2, _ := wantImg.At(x, y).RGBA()
+
+			if channelDiff(r1, r2) > maxChannelDiff || channelDiff(g1, g2) > maxChannelDiff || channelDiff(b1, b2) > maxChannelDiff {
+				differ++
+			}
+		}
+	}
+
+	if max := bounds.Dx() * bounds.Dy() / 1000; differ > max {
+		return fmt.Errorf("%d pixels differ, at most %d may", differ, max)
+	}
+
+	return nil
+}
+
+// maxChannelDiff is the 8-bit channel difference of the pixels compareImages considers the same.
+const maxChannelDiff = 24
+
+// channelDiff returns the difference of the 16-bit channels in 8 bits.
+func channelDiff(a, b uint32) uint32 {
+	if a > b {
+		return (a - b) >> 8
+	}
+
+	return (b - a) >> 8
+}
+
 func TestGetPreviewHandler_Bad(t *testing.T) {
 	p := preview.New()
 	handler := getPreview(p)
@@ -104,6 +185,26 @@ func TestGetPreviewHandler_Bad(t *testing.T) {
 		name:     "opacity",
 		req:      "/preview?title=The%20quick%20brown%20fox%20jumps%20over%20the%20lazy%20dog&author=%40Tester&ava=avatar.png&logo=logo.png&op=bad",
 		expected: "Could not parse op parameter",
+	}, {
+		name:     "opacity out of range",
+		req:      "/preview?title=The%20quick%20brown%20fox%20jumps%20over%20the%20lazy%20dog&author=%40Tester&ava=avatar.png&logo=logo.png&op=2",
+		expected: "invalid options: opacity must be within 0-1, got 2",
+	}, {
+		name:     "width",
+		req:      "/preview?title=The%20quick%20brown%20fox%20jumps%20over%20the%20lazy%20dog&logo=logo.png&w=wide",
+		expected: "Could not parse w parameter",
+	}, {
+		name:     "canvas too large",
+		req:      "/preview?title=The%20quick%20brown%20fox%20jumps%20over%20the%20lazy%20dog&logo=logo.png&w=100000&h=100000",
+		expected: "invalid options: canvas size must be within 4096x4096 px and 8294400 px in total, got 100000x100000",
+	}, {
+		name:     "quality out of range",
+		req:      "/preview?title=The%20quick%20brown%20fox%20jumps%20over%20the%20lazy%20dog&logo=logo.png&q=101",
+		expected: "invalid options: quality must be within 0-100, got 101",
+	}, {
+		name:     "unknown parameter",
+		req:      "/preview?title=The%20quick%20brown%20fox%20jumps%20over%20the%20lazy%20dog&logo=logo.png&size=2",
+		expected: "Unknown parameter size",
 	}}
 
 	for _, tt := range testCases {
@@ -139,6 +240,226 @@ func TestGetPreviewHandler_Bad(t *testing.T) {
 	}
 }
 
+func TestGetPreviewHandler_Headers(t *testing.T) {
+	handler := getPreview(preview.New())
+	req := httptest.NewRequest("GET", "/preview?title=Test&logo=logo.png&w=600&h=315&q=90", nil)
+	w := httptest.NewRecorder()
+
+	handler(w, req)
+
+	res := w.Result()
+
+	if res.StatusCode != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", res.StatusCode)
+	}
+
+	if ct := res.Header.Get("Content-Type"); ct != "image/jpeg" {
+		t.Errorf("expected image/jpeg, got %s", ct)
+	}
+
+	if cc := res.Header.Get("Cache-Control"); cc != cacheControl {
+		t.Errorf("expected %s, got %s", cacheControl, cc)
+	}
+
+	img, err := jpeg.Decode(res.Body)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if size := img.Bounds().Size(); size.X != 600 || size.Y != 315 {
+		t.Errorf("expected a 600x315 image, got %v", size)
+	}
+}
+
+func TestGetPreviewHandler_ETag(t *testing.T) {
+	handler := getPreview(preview.New())
+	w := httptest.NewRecorder()
+
+	handler(w, httptest.NewRequest("GET", "/preview?title=Test&logo=logo.png", nil))
+
+	etag := w.Result().Header.Get("ETag")
+
+	if !strings.HasPrefix(etag, `W/"`) {
+		t.Fatalf("expected a weak ETag, got %q", etag)
+	}
+
+	req := httptest.NewRequest("GET", "/preview?title=Test&logo=logo.png", nil)
+	req.Header.Set("If-None-Match", etag)
+	w = httptest.NewRecorder()
+
+	handler(w, req)
+
+	if res := w.Result(); res.StatusCode != http.StatusNotModified {
+		t.Errorf("expected status 304, got %d", res.StatusCode)
+	}
+
+	// the strong form of the tag matches too
+	req = httptest.NewRequest("GET", "/preview?title=Test&logo=logo.png", nil)
+	req.Header.Set("If-None-Match", strings.TrimPrefix(etag, "W/"))
+	w = httptest.NewRecorder()
+
+	handler(w, req)
+
+	if res := w.Result(); res.StatusCode != http.StatusNotModified {
+		t.Errorf("expected status 304 for the strong tag, got %d", res.StatusCode)
+	}
+
+	req = httptest.NewRequest("GET", "/preview?title=Changed&logo=logo.png", nil)
+	req.Header.Set("If-None-Match", etag)
+	w = httptest.NewRecorder()
+
+	handler(w, req)
+
+	if res := w.Result(); res.StatusCode != http.StatusOK || res.Header.Get("ETag") == etag {
+		t.Errorf("expected a new preview with another ETag, got %d %s", res.StatusCode, res.Header.Get("ETag"))
+	}
+}
+
+func TestGetPreviewHandler_DefaultQuality(t *testing.T) {
+	handler := getPreview(preview.New())
+
+	draw := func(q string) []byte {
+		w := httptest.NewRecorder()
+		handler(w, httptest.NewRequest("GET", "/preview?title=Test&logo=logo.png&q="+q, nil))
+
+		if w.Code != http.StatusOK {
+			t.Fatalf("expected status 200, got %d", w.Code)
+		}
+
+		return w.Body.Bytes()
+	}
+
+	if !bytes.Equal(draw("0"), draw(strconv.Itoa(preview.DefaultQuality))) {
+		t.Errorf("expected q=0 to encode with the default quality %d", preview.DefaultQuality)
+	}
+}
+
+func TestGetPreviewHandler_InvalidNotModified(t *testing.T) {
+	req := httptest.NewRequest("GET", "/preview?title=Test&logo=logo.png&w=100000&h=100000", nil)
+	req.Header.Set("If-None-Match", "*")
+	w := httptest.NewRecorder()
+
+	getPreview(preview.New())(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("expected status 400 for invalid options with a matching If-None-Match, got %d", w.Code)
+	}
+}
+
+func TestGetPreviewHandler_AuthorWithoutAvatar(t *testing.T) {
+	handler := getPreview(preview.New())
+
+	draw := func(req string) []byte {
+		w := httptest.NewRecorder()
+		handler(w, httptest.NewRequest("GET", req, nil))
+
+		if w.Code != http.StatusOK {
+			t.Fatalf("expected status 200, got %d", w.Code)
+		}
+
+		return w.Body.Bytes()
+	}
+
+	// the author isn't drawn without the avatar
+	if !bytes.Equal(draw("/preview?title=Test&author=%40Tester&logo=logo.png"), draw("/preview?title=Test&logo=logo.png")) {
+		t.Error("expected the author without the avatar to be skipped")
+	}
+}
+
+func TestGetPreviewHandler_NoOverlay(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.ServeFile(w, r, "./testdata/bg.jpg")
+	}))
+
+	defer ts.Close()
+
+	handler := getPreview(preview.New())
+
+	// brightness returns the sum of the channels of a point of the background inside the overlay
+	brightness := func(op string) uint32 {
+		w := httptest.NewRecorder()
+		handler(w, httptest.NewRequest("GET", "/preview?title=Test&logo=logo.png&bg="+url.QueryEscape(ts.URL)+op, nil))
+
+		img, err := jpeg.Decode(w.Result().Body)
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		r, g, b, _ := img.At(600, 400).RGBA()
+
+		return r>>8 + g>>8 + b>>8
+	}
+
+	if dimmed, clear := brightness(""), brightness("&op=0"); clear <= dimmed {
+		t.Errorf("expected op=0 to draw no overlay, got brightness %d, with the default overlay %d", clear, dimmed)
+	}
+}
+
+func TestGetPreviewHandler_Accept(t *testing.T) {
+	handler := getPreview(preview.New())
+
+	// the magic of each format and its offset in the body, the AVIF one follows the size of the ftyp box
+	type served struct {
+		contentType string
+		magic       string
+		at          int
+	}
+
+	jpegBody := served{"image/jpeg", "\xff\xd8", 0}
+	webpBody := served{"image/webp", "RIFF", 0}
+	avifOrWebP, avifOrJPEG := served{"image/avif", "ftypavif", 4}, served{"image/avif", "ftypavif", 4}
+
+	// AVIF is served only if the linked libvips can encode it
+	if !preview.Supported(preview.FormatAVIF) {
+		avifOrWebP, avifOrJPEG = webpBody, jpegBody
+	}
+
+	testCases := []struct {
+		accept   string
+		expected served
+	}{
+		{accept: "", expected: jpegBody},
+		{accept: "*/*", expected: jpegBody},
+		{accept: "image/avif,image/webp,image/apng,image/*,*/*;q=0.8", expected: avifOrWebP},
+		{accept: "image/avif", expected: avifOrJPEG},
+		{accept: "image/avif;q=0, image/webp", expected: webpBody},
+		{accept: "image/webp;q=0, image/jpeg", expected: jpegBody},
+		{accept: "IMAGE/WEBP", expected: webpBody},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.accept, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/preview?title=Test&logo=logo.png", nil)
+			req.Header.Set("Accept", tt.accept)
+
+			w := httptest.NewRecorder()
+
+			handler(w, req)
+
+			res := w.Result()
+			body, err := ioutil.ReadAll(res.Body)
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if ct := res.Header.Get("Content-Type"); ct != tt.expected.contentType {
+				t.Errorf("expected %s, got %s", tt.expected.contentType, ct)
+			}
+
+			if vary := res.Header.Get("Vary"); vary != "Accept" {
+				t.Errorf("expected Vary: Accept, got %s", vary)
+			}
+
+			if magic := tt.expected.magic; len(body) < tt.expected.at || !bytes.HasPrefix(body[tt.expected.at:], []byte(magic)) {
+				t.Errorf("expected the body to have %q at %d", magic, tt.expected.at)
+			}
+		})
+	}
+}
+
 func BenchmarkGetPreviewHandler(b *testing.B) {
 	p := preview.New()
 	handler := getPreview(p)
@@ -0,0 +1,44 @@
+package server
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/nDmitry/ogimgd/internal/preview"
+)
+
+// preferredFormats are the formats served instead of JPEG by preference when the client accepts them.
+var preferredFormats = []preview.Format{preview.FormatAVIF, preview.FormatWebP}
+
+// negotiateFormat picks the output format from the Accept header, JPEG if none of the preferred formats is accepted.
+// Wildcards don't count, browsers send */* for the formats they can't decode too.
+// The formats the linked libvips can't encode are skipped.
+func negotiateFormat(accept string) preview.Format {
+	accepted := map[string]bool{}
+
+	for _, part := range strings.Split(accept, ",") {
+		fields := strings.Split(part, ";")
+		mediaType := strings.ToLower(strings.TrimSpace(fields[0]))
+		accepted[mediaType] = true
+
+		for _, param := range fields[1:] {
+			kv := strings.SplitN(strings.TrimSpace(param), "=", 2)
+
+			if len(kv) < 2 || kv[0] != "q" {
+				continue
+			}
+
+			if q, err := strconv.ParseFloat(kv[1], 64); err == nil && q == 0 {
+				accepted[mediaType] = false
+			}
+		}
+	}
+
+	for _, format := range preferredFormats {
+		if accepted["image/"+string(format)] && preview.Supported(format) {
+			return format
+		}
+	}
+
+	return preview.FormatJPEG
+}
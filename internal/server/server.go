@@ -15,8 +15,8 @@ import (
 	"github.com/go-chi/chi/v5/middleware"
 )
 
-// Run starts the HTTP server
-func Run(port int, d drawer) {
+// Run starts the HTTP server. If the signer is not nil, only the signed preview requests are served.
+func Run(port int, d drawer, s *Signer) {
 	ctx, cancel := context.WithCancel(context.Background())
 	startedAt := time.Now().UTC()
 
@@ -27,7 +27,11 @@ func Run(port int, d drawer) {
 	r.Use(middleware.Logger)
 	r.Use(middleware.Recoverer)
 
-	r.Get("/preview", getPreview(d))
+	if s != nil {
+		r.With(verifySignature(s)).Get("/preview", getPreview(d))
+	} else {
+		r.Get("/preview", getPreview(d))
+	}
 
 	r.Get("/", func(w http.ResponseWriter, r *http.Request) {
 		fmt.Fprintf(
@@ -0,0 +1,130 @@
+package server
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// The query parameters of a signed request
+const (
+	sigParam = "sig"
+	expParam = "exp"
+)
+
+// Signature verification errors
+var (
+	ErrMissingSignature = errors.New("missing signature")
+	ErrInvalidSignature = errors.New("invalid signature")
+	ErrExpiredSignature = errors.New("expired signature")
+)
+
+// Signer signs the preview query parameters with HMAC-SHA256 using a shared secret and verifies the signatures,
+// so only the URLs produced by the secret holders are served.
+type Signer struct {
+	secret []byte
+	now    func() time.Time
+}
+
+// NewSigner returns a Signer using the secret.
+func NewSigner(secret []byte) *Signer {
+	return &Signer{secret: secret, now: time.Now}
+}
+
+// Sign returns a copy of the query with the signature added. A positive ttl adds the expiration time
+// the signature is valid until.
+func (s *Signer) Sign(query url.Values, ttl time.Duration) url.Values {
+	signed := url.Values{}
+
+	for key, values := range query {
+		if key != sigParam {
+			signed[key] = append([]string(nil), values...)
+		}
+	}
+
+	if ttl > 0 {
+		signed.Set(expParam, strconv.FormatInt(s.now().Add(ttl).Unix(), 10))
+	} else {
+		signed.Del(expParam)
+	}
+
+	signed.Set(sigParam, s.signature(signed))
+
+	return signed
+}
+
+// SignURL returns the URL with its query signed.
+func (s *Signer) SignURL(rawURL string, ttl time.Duration) (string, error) {
+	u, err := url.Parse(rawURL)
+
+	if err != nil {
+		return "", fmt.Errorf("could not parse the URL: %w", err)
+	}
+
+	u.RawQuery = s.Sign(u.Query(), ttl).Encode()
+
+	return u.String(), nil
+}
+
+// Verify checks the signature of the query and its expiration time if there is one.
+func (s *Signer) Verify(query url.Values) error {
+	sig := query.Get(sigParam)
+
+	if sig == "" {
+		return ErrMissingSignature
+	}
+
+	if !hmac.Equal([]byte(sig), []byte(s.signature(query))) {
+		return ErrInvalidSignature
+	}
+
+	if exp := query.Get(expParam); exp != "" {
+		expiresAt, err := strconv.ParseInt(exp, 10, 64)
+
+		if err != nil {
+			return ErrInvalidSignature
+		}
+
+		if s.now().Unix() > expiresAt {
+			return ErrExpiredSignature
+		}
+	}
+
+	return nil
+}
+
+// signature returns the URL-safe base64 HMAC of all the query parameters but the signature in the sorted order.
+func (s *Signer) signature(query url.Values) string {
+	unsigned := url.Values{}
+
+	for key, values := range query {
+		if key != sigParam {
+			unsigned[key] = values
+		}
+	}
+
+	mac := hmac.New(sha256.New, s.secret)
+	mac.Write([]byte(unsigned.Encode()))
+
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// verifySignature rejects the requests without a valid signature with 403.
+func verifySignature(s *Signer) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if err := s.Verify(r.URL.Query()); err != nil {
+				handleForbidden(w, err)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,144 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+)
+
+func TestSigner(t *testing.T) {
+	now := time.Date(2021, 6, 1, 12, 0, 0, 0, time.UTC)
+	s := NewSigner([]byte("secret"))
+	s.now = func() time.Time { return now }
+
+	query := url.Values{"title": {"Test"}, "logo": {"logo.png"}}
+
+	testCases := []struct {
+		name     string
+		query    func() url.Values
+		expected error
+	}{{
+		name:  "valid",
+		query: func() url.Values { return s.Sign(query, 0) },
+	}, {
+		name:  "valid until expiration",
+		query: func() url.Values { return s.Sign(query, time.Hour) },
+	}, {
+		name:     "missing",
+		query:    func() url.Values { return query },
+		expected: ErrMissingSignature,
+	}, {
+		name: "tampered",
+		query: func() url.Values {
+			signed := s.Sign(query, 0)
+			signed.Set("bg", "http://169.254.169.254/")
+
+			return signed
+		},
+		expected: ErrInvalidSignature,
+	}, {
+		name: "extended expiration",
+		query: func() url.Values {
+			signed := s.Sign(query, time.Hour)
+			signed.Set(expParam, "4102444800")
+
+			return signed
+		},
+		expected: ErrInvalidSignature,
+	}, {
+		name:     "another secret",
+		query:    func() url.Values { return NewSigner([]byte("guess")).Sign(query, 0) },
+		expected: ErrInvalidSignature,
+	}, {
+		name: "expired",
+		query: func() url.Values {
+			signed := s.Sign(query, time.Minute)
+			now = now.Add(time.Hour)
+
+			return signed
+		},
+		expected: ErrExpiredSignature,
+	}}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := s.Verify(tt.query()); !errors.Is(err, tt.expected) {
+				t.Errorf("expected %v, got %v", tt.expected, err)
+			}
+		})
+	}
+}
+
+func TestSignURL(t *testing.T) {
+	s := NewSigner([]byte("secret"))
+	signed, err := s.SignURL("https://example.com/preview?title=Test&logo=logo.png", time.Hour)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	u, err := url.Parse(signed)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if u.Host != "example.com" || u.Path != "/preview" {
+		t.Errorf("expected the URL kept, got %s", signed)
+	}
+
+	if err := s.Verify(u.Query()); err != nil {
+		t.Errorf("expected a valid signature, got %v", err)
+	}
+}
+
+func TestVerifySignature(t *testing.T) {
+	s := NewSigner([]byte("secret"))
+	handler := verifySignature(s)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	signed, err := s.SignURL("/preview?title=Test&logo=logo.png", 0)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testCases := []struct {
+		name     string
+		req      string
+		expected int
+	}{
+		{name: "signed", req: signed, expected: http.StatusOK},
+		{name: "unsigned", req: "/preview?title=Test&logo=logo.png", expected: http.StatusForbidden},
+		{name: "tampered", req: signed + "&ava=avatar.png", expected: http.StatusForbidden},
+	}
+
+	for _, tt := range testCases {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+
+			handler.ServeHTTP(w, httptest.NewRequest("GET", tt.req, nil))
+
+			res := w.Result()
+
+			if res.StatusCode != tt.expected {
+				t.Fatalf("expected status %d, got %d", tt.expected, res.StatusCode)
+			}
+
+			if tt.expected == http.StatusOK {
+				return
+			}
+
+			mes := errorResponse{}
+
+			if err := json.NewDecoder(res.Body).Decode(&mes); err != nil || mes.Status != statusError {
+				t.Errorf("expected an error response, got %+v, %v", mes, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,148 @@
+// Package templates renders previews from declarative JSON templates: the preview Options as a JSON object
+// (keyed by the field names, case-insensitively) with {{name}} placeholders in its string values.
+package templates
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"image"
+	"io/fs"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/nDmitry/ogimgd/internal/preview"
+)
+
+var placeholderRe = regexp.MustCompile(`\{\{\s*([A-Za-z0-9_]+)\s*\}\}`)
+
+// Template is a parsed preview template.
+type Template struct {
+	// the template decoded generically, so the placeholders are only replaced in the string values
+	tree interface{}
+}
+
+// Parse parses a JSON template. It fails on malformed JSON and on the fields preview.Options doesn't have.
+func Parse(data []byte) (*Template, error) {
+	var tree interface{}
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+	// the numbers are kept as they are written, e.g. the int64 seeds are not rounded to float64
+	dec.UseNumber()
+
+	if err := dec.Decode(&tree); err != nil {
+		return nil, fmt.Errorf("could not parse a template: %w", err)
+	}
+
+	if _, ok := tree.(map[string]interface{}); !ok {
+		return nil, fmt.Errorf("could not parse a template: expected a JSON object")
+	}
+
+	// the placeholders are in the string values only, so the template decodes as is
+	if _, err := decode(tree); err != nil {
+		return nil, err
+	}
+
+	return &Template{tree: tree}, nil
+}
+
+// Load reads and parses a JSON template from the file system.
+func Load(fsys fs.FS, name string) (*Template, error) {
+	data, err := fs.ReadFile(fsys, name)
+
+	if err != nil {
+		return nil, fmt.Errorf("could not read a template: %w", err)
+	}
+
+	return Parse(data)
+}
+
+// Options returns the Options of the template with the placeholders replaced by the vars.
+// A placeholder without a var is an error, the vars without a placeholder are ignored.
+func (t *Template) Options(vars map[string]string) (preview.Options, error) {
+	unknown := map[string]bool{}
+	tree := substitute(t.tree, vars, unknown)
+
+	if len(unknown) > 0 {
+		names := make([]string, 0, len(unknown))
+
+		for name := range unknown {
+			names = append(names, name)
+		}
+
+		sort.Strings(names)
+
+		return preview.Options{}, fmt.Errorf("unknown template placeholders: %s", strings.Join(names, ", "))
+	}
+
+	return decode(tree)
+}
+
+// Render draws the preview of the template with the placeholders replaced by the vars.
+func (t *Template) Render(ctx context.Context, p *preview.Preview, vars map[string]string) (image.Image, error) {
+	opts, err := t.Options(vars)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return p.Draw(ctx, opts)
+}
+
+// substitute returns a copy of the tree with the placeholders in the strings replaced by the vars
+// and collects the placeholders without a var.
+func substitute(node interface{}, vars map[string]string, unknown map[string]bool) interface{} {
+	switch node := node.(type) {
+	case string:
+		return placeholderRe.ReplaceAllStringFunc(node, func(placeholder string) string {
+			name := placeholderRe.FindStringSubmatch(placeholder)[1]
+			value, exists := vars[name]
+
+			if !exists {
+				unknown[name] = true
+			}
+
+			return value
+		})
+	case map[string]interface{}:
+		copied := make(map[string]interface{}, len(node))
+
+		for key, value := range node {
+			copied[key] = substitute(value, vars, unknown)
+		}
+
+		return copied
+	case []interface{}:
+		copied := make([]interface{}, len(node))
+
+		for i, value := range node {
+			copied[i] = substitute(value, vars, unknown)
+		}
+
+		return copied
+	}
+
+	return node
+}
+
+// decode decodes the tree into Options failing on the unknown fields.
+func decode(tree interface{}) (preview.Options, error) {
+	var opts preview.Options
+
+	buf, err := json.Marshal(tree)
+
+	if err != nil {
+		return preview.Options{}, fmt.Errorf("could not encode a template: %w", err)
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(buf))
+	dec.DisallowUnknownFields()
+
+	if err := dec.Decode(&opts); err != nil {
+		return preview.Options{}, fmt.Errorf("could not decode a template into the options: %w", err)
+	}
+
+	return opts, nil
+}
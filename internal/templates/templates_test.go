@@ -0,0 +1,84 @@
+package templates
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/nDmitry/ogimgd/internal/preview"
+)
+
+const card = `{
+	"canvasW": 1200,
+	"canvasH": 630,
+	"bg": "#1E1B4B",
+	"title": "{{title}}",
+	"author": "by {{ author }}",
+	"tags": ["{{tag}}", "Go"],
+	"seed": 9007199254740993
+}`
+
+func TestTemplateOptions(t *testing.T) {
+	tmpl, err := Load(fstest.MapFS{"card.json": {Data: []byte(card)}}, "card.json")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	opts, err := tmpl.Options(map[string]string{"title": `A "quoted" title`, "author": "Jane", "tag": "News"})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if opts.Title != `A "quoted" title` || opts.Author != "by Jane" || opts.Tags[0] != "News" || opts.Bg != "#1E1B4B" {
+		t.Errorf("unexpected options: %+v", opts)
+	}
+
+	if opts.Seed != 9007199254740993 {
+		t.Errorf("expected the seed kept exactly, got %d", opts.Seed)
+	}
+
+	// the template is not changed by the substitution
+	if opts, err = tmpl.Options(map[string]string{"title": "Another", "author": "John", "tag": "Go"}); err != nil || opts.Title != "Another" {
+		t.Errorf("expected the template reusable, got %q, %v", opts.Title, err)
+	}
+
+	_, err = tmpl.Options(map[string]string{"title": "Only the title"})
+
+	if err == nil || !strings.Contains(err.Error(), "author, tag") {
+		t.Errorf("expected the unknown placeholders listed, got %v", err)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	for name, data := range map[string]string{
+		"malformed":     `{"title": `,
+		"not an object": `["title"]`,
+		"unknown field": `{"titel": "{{title}}"}`,
+		"wrong type":    `{"canvasW": "{{width}}"}`,
+	} {
+		if _, err := Parse([]byte(data)); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+func TestRender(t *testing.T) {
+	tmpl, err := Parse([]byte(card))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	img, err := tmpl.Render(context.Background(), preview.New(), map[string]string{"title": "Hello", "author": "Jane", "tag": "Go"})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if b := img.Bounds(); b.Dx() != 1200 || b.Dy() != 630 {
+		t.Errorf("expected a 1200x630 preview, got %v", b)
+	}
+}